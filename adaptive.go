@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+//Adaptive is a Pacer whose rate adapts to feedback about the operations it paces
+//using additive-increase/multiplicative-decrease (AIMD).
+//Each reported success increases the rate by a fixed amount, and each reported
+//failure multiplies the rate by a fraction, so the rate of an Adaptive converges
+//on the capacity of the downstream service being paced.
+//
+//Releases are permitted one at a time at the current rate, just like a
+//TokenBucket with a burst of one.
+type Adaptive struct {
+	lock   *sync.Mutex
+	bucket *TokenBucket
+
+	//rate, minRate, and maxRate are in releases per second.
+	rate    float64
+	minRate float64
+	maxRate float64
+
+	increase float64
+	decrease float64
+}
+
+//NewAdaptive creates an Adaptive that starts by permitting one release every d.
+//Each success increases the rate by increase releases per second, and each
+//failure multiplies the rate by decrease, which should be between 0 and 1.
+//The rate never permits releases more often than once every min or less often
+//than once every max.
+func NewAdaptive(d, min, max time.Duration, increase, decrease float64) *Adaptive {
+	a := &Adaptive{
+		lock:     &sync.Mutex{},
+		bucket:   NewTokenBucket(d, 1),
+		rate:     perSecond(d),
+		minRate:  perSecond(max),
+		maxRate:  perSecond(min),
+		increase: increase,
+		decrease: decrease,
+	}
+	a.setRateLocked(a.rate)
+	return a
+}
+
+//AllowN reports whether n releases may happen at time t at the current rate of
+//a, counting them if they may.
+func (a *Adaptive) AllowN(t time.Time, n int) bool {
+	return a.bucket.AllowN(t, n)
+}
+
+//DelayN returns how long after t it will be before n releases may happen at the
+//current rate of a.
+func (a *Adaptive) DelayN(t time.Time, n int) time.Duration {
+	return a.bucket.DelayN(t, n)
+}
+
+//ReportSuccess reports that an operation paced by a succeeded, increasing the
+//rate of a additively.
+func (a *Adaptive) ReportSuccess() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.setRateLocked(a.rate + a.increase)
+}
+
+//ReportFailure reports that an operation paced by a failed, for example because
+//the downstream service was overloaded, decreasing the rate of a
+//multiplicatively.
+func (a *Adaptive) ReportFailure() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.setRateLocked(a.rate * a.decrease)
+}
+
+//Rate returns the current rate of a in releases per second.
+func (a *Adaptive) Rate() float64 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.rate
+}
+
+//Duration returns the current time between releases permitted by a.
+func (a *Adaptive) Duration() time.Duration {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return interval(a.rate)
+}
+
+//Reset forgets all releases counted by a without changing its rate.
+func (a *Adaptive) Reset() {
+	a.bucket.Reset()
+}
+
+func (a *Adaptive) setRateLocked(rate float64) {
+	if rate < a.minRate {
+		rate = a.minRate
+	}
+	if rate > a.maxRate {
+		rate = a.maxRate
+	}
+
+	a.rate = rate
+	a.bucket.SetDuration(interval(rate))
+}
+
+//perSecond returns the rate in releases per second of one release every d.
+func perSecond(d time.Duration) float64 {
+	return float64(time.Second) / float64(d)
+}
+
+//interval returns the time between releases at rate releases per second.
+func interval(rate float64) time.Duration {
+	return time.Duration(float64(time.Second) / rate)
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptive_ReportSuccess_increasesTheRateAdditively(t *testing.T) {
+	a := NewAdaptive(time.Second, time.Millisecond, time.Minute, 1, 0.5)
+
+	a.ReportSuccess()
+	a.ReportSuccess()
+
+	if a.Rate() != 3 {
+		t.Fail()
+	}
+}
+
+func TestAdaptive_ReportFailure_decreasesTheRateMultiplicatively(t *testing.T) {
+	a := NewAdaptive(time.Second/8, time.Millisecond, time.Minute, 1, 0.5)
+
+	a.ReportFailure()
+
+	if a.Rate() != 4 || a.Duration() != time.Second/4 {
+		t.Fail()
+	}
+}
+
+func TestAdaptive_rateStaysWithinBounds(t *testing.T) {
+	a := NewAdaptive(time.Second, time.Second/2, 2*time.Second, 1, 0.1)
+
+	for i := 0; i < 10; i++ {
+		a.ReportSuccess()
+	}
+	if a.Rate() != 2 {
+		t.Fail()
+	}
+
+	for i := 0; i < 10; i++ {
+		a.ReportFailure()
+	}
+	if a.Rate() != 0.5 {
+		t.Fail()
+	}
+}
+
+func TestAdaptive_AllowN_pacesAtTheCurrentRate(t *testing.T) {
+	a := NewAdaptive(time.Hour, time.Millisecond, time.Hour, 1, 0.5)
+	now := time.Now()
+
+	if !a.AllowN(now, 1) {
+		t.Fail()
+	}
+
+	if a.AllowN(now, 1) {
+		t.Fail()
+	}
+
+	for i := 0; i < 9; i++ {
+		a.ReportSuccess()
+	}
+
+	if delay := a.DelayN(now, 1); delay > 200*time.Millisecond {
+		t.Fatal(delay)
+	}
+}
@@ -0,0 +1,223 @@
+package ratelimit
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+//benchmarkCapacities are the capacities that the queueing benchmarks run with,
+//from a Limiter that holds one value to one whose queue never fills.
+var benchmarkCapacities = []int{1, 16, 1024}
+
+func BenchmarkLimiter_PushPop(b *testing.B) {
+	for _, capacity := range benchmarkCapacities {
+		b.Run(strconv.Itoa(capacity), func(b *testing.B) {
+			rl := New(0, WithCapacity(capacity))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				rl.Push(i)
+				rl.Pop()
+			}
+		})
+	}
+}
+
+func BenchmarkLimiter_TryPushTryPop(b *testing.B) {
+	rl := New(0, WithCapacity(1024))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rl.TryPush(i)
+		rl.TryPop()
+	}
+}
+
+func BenchmarkLimiter_PushPopAvailable(b *testing.B) {
+	for _, capacity := range benchmarkCapacities {
+		b.Run(strconv.Itoa(capacity), func(b *testing.B) {
+			rl := New(0, WithCapacity(capacity))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i += capacity {
+				for j := 0; j < capacity; j++ {
+					rl.Push(j)
+				}
+				rl.PopAvailable()
+			}
+		})
+	}
+}
+
+func BenchmarkLimiter_PushPriority(b *testing.B) {
+	rl := NewUnbounded(0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rl.PushPriority(i, i%4)
+		rl.Pop()
+	}
+}
+
+//benchmarkProducers measures pushing b.N values to a Limiter from producers
+//goroutines while a single consumer pops them.
+func benchmarkProducers(b *testing.B, producers, capacity int) {
+	rl := New(0, WithCapacity(capacity))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, ok := rl.PopOk(); ok; _, ok = rl.PopOk() {
+		}
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	wg := &sync.WaitGroup{}
+	for p := 0; p < producers; p++ {
+		n := b.N / producers
+		if p < b.N%producers {
+			n++
+		}
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				rl.Push(i)
+			}
+		}(n)
+	}
+	wg.Wait()
+	rl.Close()
+	<-done
+}
+
+func BenchmarkLimiter_Push_producers(b *testing.B) {
+	for _, capacity := range benchmarkCapacities {
+		for _, producers := range []int{1, 8, 64, 256} {
+			b.Run(strconv.Itoa(capacity)+"/"+strconv.Itoa(producers), func(b *testing.B) {
+				benchmarkProducers(b, producers, capacity)
+			})
+		}
+	}
+}
+
+func BenchmarkLimiter_PushPop_parallel(b *testing.B) {
+	rl := New(0, WithCapacity(1024))
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			rl.Push(0)
+			rl.Pop()
+		}
+	})
+}
+
+func BenchmarkLimiter_Allow(b *testing.B) {
+	rl := New(time.Nanosecond)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rl.Allow()
+	}
+}
+
+func BenchmarkLimiter_Allow_parallel(b *testing.B) {
+	rl := New(time.Nanosecond)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			rl.Allow()
+		}
+	})
+}
+
+func BenchmarkLimiter_Wait(b *testing.B) {
+	rl := New(0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rl.Wait()
+	}
+}
+
+func BenchmarkTokenBucket_AllowN(b *testing.B) {
+	tb := NewTokenBucket(time.Nanosecond, 16)
+	now := time.Now()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tb.AllowN(now.Add(time.Duration(i)), 1)
+	}
+}
+
+func BenchmarkKeyedLimiter_PushKeyPopKey(b *testing.B) {
+	k := NewKeyed(0, WithCapacity(16))
+	keys := make([]string, 64)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := keys[i%len(keys)]
+		k.PushKey(key, i)
+		k.PopKey(key)
+	}
+}
+
+func BenchmarkLimiter_Wait_paced(b *testing.B) {
+	rl := New(time.Microsecond)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rl.Wait()
+	}
+}
+
+//benchmarkKeyed measures pushing and popping values of 1<<20 keys from 32
+//goroutines.
+func benchmarkKeyed(b *testing.B, push func(key string, i int), pop func(key string)) {
+	const goroutines = 32
+	keys := make([]string, 1<<20)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	wg := &sync.WaitGroup{}
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := g; i < b.N; i += goroutines {
+				key := keys[(i*7919)%len(keys)]
+				push(key, i)
+				pop(key)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func BenchmarkKeyedLimiter_keys(b *testing.B) {
+	k := NewKeyed(0)
+	benchmarkKeyed(b, func(key string, i int) { k.PushKey(key, i) }, func(key string) { k.PopKey(key) })
+}
+
+func BenchmarkShardedKeyedLimiter_keys(b *testing.B) {
+	s := NewShardedKeyed(DefaultShards, 0)
+	benchmarkKeyed(b, func(key string, i int) { s.PushKey(key, i) }, func(key string) { s.PopKey(key) })
+}
+
+func BenchmarkLimiter_PushPopTimeout(b *testing.B) {
+	rl := New(0, WithCapacity(1024))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rl.PushTimeout(0, time.Second)
+		rl.PopTimeout(time.Second)
+	}
+}
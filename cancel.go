@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+//Item is a handle to a value pushed to a Limiter with PushItem, which can be
+//used to retract the value before it is released.
+type Item struct {
+	l        *Limiter
+	value    interface{}
+	priority int
+}
+
+//Value returns the value that was pushed.
+func (i *Item) Value() interface{} {
+	return i.value
+}
+
+//Cancel removes the value of i from its Limiter if it has not been popped or
+//dropped yet, reporting whether it did.
+//A canceled value is reported to Observers and Hooks as dropped and counted in
+//Stats.Dropped, but it is not passed to the function set with WithDeadLetter
+//since the caller already knows about it.
+//
+//Cancel takes time proportional to the number of values queued in the Limiter
+//with the same priority as the value of i.
+func (i *Item) Cancel() bool {
+	l := i.l
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	removed := l.values.removeIfPriority(i.priority, func(it item) bool { return it.handle == i })
+	if len(removed) == 0 {
+		return false
+	}
+	l.observeDroppedLocked(removed)
+	l.broadcastLocked()
+	return true
+}
+
+//PushItem places value in l to be popped later and returns a handle to it that
+//can be used to cancel it.
+//handle is nil if err is not nil.
+//
+//PushItem otherwise works just like Push.
+func (l *Limiter) PushItem(value interface{}) (handle *Item, err error) {
+	h := &Item{l: l, value: value}
+	if err := l.push(context.Background(), time.Time{}, item{value: value, handle: h}); err != nil {
+		return nil, l.opError("push", err)
+	}
+	return h, nil
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestItem_Cancel_removesAQueuedValue(t *testing.T) {
+	var dropped []interface{}
+	l := New(time.Duration(1), WithCapacity(3), WithHooks(Hooks{
+		OnDrop: func(e Event) {
+			dropped = append(dropped, e.Value)
+		},
+	}))
+	l.Push(1)
+	item, err := l.PushItem(2)
+	if err != nil || item.Value() != 2 {
+		t.Fatal(item, err)
+	}
+	l.Push(3)
+
+	if !item.Cancel() {
+		t.Fatal("should cancel a queued value")
+	}
+	if item.Cancel() {
+		t.Fatal("should not cancel a value twice")
+	}
+
+	if got := l.PopN(2); !reflect.DeepEqual(got, []interface{}{1, 3}) {
+		t.Fatal(got)
+	}
+	if !reflect.DeepEqual(dropped, []interface{}{2}) {
+		t.Fatal(dropped)
+	}
+	if stats := l.Stats(); stats.Dropped != 1 {
+		t.Fatal(stats.Dropped)
+	}
+}
+
+func TestItem_Cancel_isFalseOnceTheValueIsPopped(t *testing.T) {
+	l := New(time.Duration(1))
+	item, _ := l.PushItem(1)
+
+	l.Pop()
+
+	if item.Cancel() {
+		t.Fatal("should not cancel a popped value")
+	}
+}
+
+func TestItem_Cancel_makesSpaceForABlockedPush(t *testing.T) {
+	l := New(time.Duration(1) * time.Hour)
+	item, _ := l.PushItem(1)
+
+	pushed := make(chan error)
+	go func() {
+		pushed <- l.Push(2)
+	}()
+	item.Cancel()
+
+	if err := <-pushed; err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Drain(); !reflect.DeepEqual(got, []interface{}{2}) {
+		t.Fatal(got)
+	}
+}
+
+func TestLimiter_PushItem_returnsNilIfClosed(t *testing.T) {
+	l := New(time.Duration(1))
+	l.Close()
+
+	if item, err := l.PushItem(1); item != nil || err != ErrClosed {
+		t.Fatal(item, err)
+	}
+}
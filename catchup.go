@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+//catchUpPacer is the Pacer of a Limiter configured WithCatchUp.
+//Releases are scheduled one every d, and releases whose scheduled time passed
+//within the last window without being used may still happen back-to-back.
+type catchUpPacer struct {
+	lock *sync.Mutex
+
+	d      time.Duration
+	window time.Duration
+
+	//next is the scheduled time of the next release.
+	next time.Time
+}
+
+func newCatchUpPacer(d, window time.Duration) *catchUpPacer {
+	return &catchUpPacer{
+		lock:   &sync.Mutex{},
+		d:      d,
+		window: window,
+	}
+}
+
+func (p *catchUpPacer) AllowN(t time.Time, n int) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	next := p.nextLocked(t)
+	if t.Before(next) {
+		return false
+	}
+
+	p.next = next.Add(time.Duration(n) * p.d)
+	return true
+}
+
+func (p *catchUpPacer) DelayN(t time.Time, n int) time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if delay := p.nextLocked(t).Sub(t); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+func (p *catchUpPacer) SetDuration(d time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.d = d
+}
+
+func (p *catchUpPacer) Reset() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.next = time.Time{}
+}
+
+//nextLocked returns the scheduled time of the next release, not counting any
+//releases scheduled more than window before t.
+func (p *catchUpPacer) nextLocked(t time.Time) time.Time {
+	if oldest := t.Add(-p.window); p.next.Before(oldest) {
+		return oldest
+	}
+	return p.next
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCatchUpPacer_AllowN_catchesUpOnReleasesWithinTheWindow(t *testing.T) {
+	p := newCatchUpPacer(time.Second, 3*time.Second)
+	now := time.Now()
+	p.AllowN(now, 1)
+
+	later := now.Add(time.Hour)
+	for i := 0; i < 4; i++ {
+		if !p.AllowN(later, 1) {
+			t.Fatal(i)
+		}
+	}
+	if p.AllowN(later, 1) || p.DelayN(later, 1) != time.Second {
+		t.Fail()
+	}
+}
+
+func TestCatchUpPacer_AllowN_releasesAtMostTheWindowMoreThanTheRateOverAPeriod(t *testing.T) {
+	p := newCatchUpPacer(time.Second, 3*time.Second)
+	now := time.Now()
+	p.AllowN(now, 1)
+
+	later := now.Add(time.Hour)
+	released := 0
+	for elapsed := time.Duration(0); elapsed <= 10*time.Second; elapsed += 100 * time.Millisecond {
+		for p.AllowN(later.Add(elapsed), 1) {
+			released++
+		}
+	}
+	//(10s + 3s)/1s + 1
+	if released != 14 {
+		t.Fatal(released)
+	}
+}
+
+func TestCatchUpPacer_AllowN_isStrictWithAZeroWindow(t *testing.T) {
+	p := newCatchUpPacer(time.Second, 0)
+	now := time.Now()
+
+	if !p.AllowN(now, 1) || !p.AllowN(now.Add(time.Hour), 1) {
+		t.Fail()
+	}
+	if p.AllowN(now.Add(time.Hour), 1) {
+		t.Fail()
+	}
+}
+
+func TestCatchUpPacer_DelayN_isZeroAfterAnIdlePeriod(t *testing.T) {
+	p := newCatchUpPacer(time.Second, 3*time.Second)
+	now := time.Now()
+	p.AllowN(now, 1)
+
+	if delay := p.DelayN(now.Add(time.Hour), 1); delay != 0 {
+		t.Fatal(delay)
+	}
+}
+
+func TestCatchUpPacer_Reset_forgetsTheSchedule(t *testing.T) {
+	p := newCatchUpPacer(time.Hour, 0)
+	now := time.Now()
+	p.AllowN(now, 1)
+
+	p.Reset()
+
+	if !p.AllowN(now, 1) {
+		t.Fail()
+	}
+}
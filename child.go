@@ -0,0 +1,62 @@
+package ratelimit
+
+import "time"
+
+//NewChild creates a Limiter with throughput duration d configured by opts that
+//shares the budget of l.
+//A value is only released from the child once both the pacer of the child and l
+//permit it, and each release from the child counts as a release from l.
+//
+//This allows, for example, each tenant of a service to be limited to 10 values
+//per second while the service as a whole is limited to 100 values per second.
+//Children do not share values with l, only its budget, and may themselves have
+//children.
+func (l *Limiter) NewChild(d time.Duration, opts ...Option) *Limiter {
+	child := New(d, append([]Option{WithClock(l.clock)}, opts...)...)
+	child.pacer = &childPacer{
+		own:    child.pacer,
+		parent: l,
+	}
+	return child
+}
+
+//childPacer is the Pacer of a child Limiter.
+//SetDuration and Reset only affect its own pacer and never its parent.
+type childPacer struct {
+	own    Pacer
+	parent *Limiter
+}
+
+//AllowN counts n releases with both the own pacer of the child and its parent,
+//or with neither, so that children sharing a parent cannot overdraw it.
+//The own pacer is only used under the lock of the child, so it cannot change
+//between being checked and counted, while the parent checks and counts the
+//releases at once under its own lock.
+func (p *childPacer) AllowN(t time.Time, n int) bool {
+	if p.own.DelayN(t, n) > 0 {
+		return false
+	}
+	if !p.parent.AllowN(t, n) {
+		return false
+	}
+	p.own.AllowN(t, n)
+	return true
+}
+
+func (p *childPacer) DelayN(t time.Time, n int) time.Duration {
+	delay := p.own.DelayN(t, n)
+	if d := p.parent.DelayN(t, n); d > delay {
+		delay = d
+	}
+	return delay
+}
+
+func (p *childPacer) setDurationAt(t time.Time, d time.Duration) {
+	setPacerDuration(p.own, t, d)
+}
+
+func (p *childPacer) Reset() {
+	if resetter, ok := p.own.(resetter); ok {
+		resetter.Reset()
+	}
+}
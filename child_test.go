@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiter_NewChild_releasesWithinTheParentBudget(t *testing.T) {
+	parent := New(time.Duration(1) * time.Hour)
+	a := parent.NewChild(time.Duration(1), WithCapacity(2))
+	b := parent.NewChild(time.Duration(1), WithCapacity(2))
+	a.PushAll(0, 1)
+	b.PushAll(0, 1)
+
+	if values := a.PopAvailable(); len(values) != 1 {
+		t.Fail()
+	}
+
+	if values := b.PopAvailable(); len(values) != 0 {
+		t.Fail()
+	}
+
+	if parent.Allow() {
+		t.Fail()
+	}
+}
+
+func TestLimiter_NewChild_releasesWithinItsOwnBudget(t *testing.T) {
+	parent := New(time.Duration(1))
+	child := parent.NewChild(time.Duration(1)*time.Hour, WithCapacity(2))
+	child.PushAll(0, 1)
+
+	if values := child.PopAvailable(); len(values) != 1 {
+		t.Fail()
+	}
+
+	if !parent.Allow() {
+		t.Fail()
+	}
+}
+
+func TestLimiter_NewChild_supportsGrandchildren(t *testing.T) {
+	root := New(time.Duration(1) * time.Hour)
+	grandchild := root.NewChild(time.Duration(1)).NewChild(time.Duration(1))
+
+	if !grandchild.Allow() {
+		t.Fail()
+	}
+
+	if root.Allow() || grandchild.Allow() {
+		t.Fail()
+	}
+}
+
+func TestLimiter_NewChild_resetDoesNotAffectTheParent(t *testing.T) {
+	parent := New(time.Duration(1)*time.Hour, WithCapacity(1))
+	parent.Push(0)
+	child := parent.NewChild(time.Duration(1) * time.Hour)
+	child.Allow()
+
+	child.Reset()
+
+	if parent.Len() != 1 || parent.Allow() {
+		t.Fail()
+	}
+}
+
+func TestLimiter_NewChild_setDurationDoesNotAffectTheParent(t *testing.T) {
+	parent := New(time.Duration(1) * time.Hour)
+	child := parent.NewChild(time.Duration(1) * time.Hour)
+
+	child.SetDuration(time.Duration(1))
+
+	if parent.Duration() != time.Duration(1)*time.Hour {
+		t.Fail()
+	}
+}
+
+func TestLimiter_NewChild_childrenCannotOverdrawTheParentConcurrently(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		parent := New(time.Duration(1) * time.Hour)
+		children := []*Limiter{parent.NewChild(0), parent.NewChild(0)}
+
+		start := make(chan struct{})
+		allowed := make(chan bool, 2*8)
+		group := &sync.WaitGroup{}
+		for _, child := range children {
+			for j := 0; j < 8; j++ {
+				group.Add(1)
+				go func(child *Limiter) {
+					defer group.Done()
+					<-start
+					allowed <- child.Allow()
+				}(child)
+			}
+		}
+		close(start)
+		group.Wait()
+		close(allowed)
+
+		n := 0
+		for ok := range allowed {
+			if ok {
+				n++
+			}
+		}
+		if n != 1 {
+			t.Fatal(i, n)
+		}
+	}
+}
+
+//takenPacer models a Pacer whose last release is taken by another Limiter
+//between DelayN and AllowN.
+type takenPacer struct{}
+
+func (takenPacer) AllowN(t time.Time, n int) bool {
+	return false
+}
+
+func (takenPacer) DelayN(t time.Time, n int) time.Duration {
+	return 0
+}
+
+func TestLimiter_NewChild_refusesAReleaseTakenFromTheParentAfterItWasChecked(t *testing.T) {
+	parent := New(time.Duration(1), WithPacer(takenPacer{}))
+	child := parent.NewChild(time.Duration(1) * time.Hour)
+
+	if child.Allow() {
+		t.Fatal("should not release what the parent refused")
+	}
+	if delay := child.Delay(); delay != 0 {
+		t.Fatal("should not count a refused release with the child", delay)
+	}
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import "time"
+
+//Clock is the source of time of a Limiter, set with WithClock.
+//Replacing the Clock allows code that uses a Limiter to be tested without
+//waiting in real time.
+type Clock interface {
+	//Now returns the current time.
+	Now() time.Time
+
+	//NewTimer returns a Timer whose channel receives the current time once d
+	//has passed, like time.NewTimer.
+	NewTimer(d time.Duration) Timer
+
+	//AfterFunc returns a Timer that calls f in its own goroutine once d has
+	//passed, like time.AfterFunc. The channel of the Timer is nil.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+//Timer is a single event of a Clock, like time.Timer.
+type Timer interface {
+	//C returns the channel on which the time is delivered.
+	C() <-chan time.Time
+
+	//Stop prevents the Timer from firing, reporting whether it had not fired yet.
+	Stop() bool
+
+	//Reset changes the Timer to fire once d has passed, reporting whether it
+	//had not fired yet.
+	Reset(d time.Duration) bool
+}
+
+//SystemClock is the Clock that uses the time package, which is the Clock of a
+//Limiter unless WithClock is used.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return systemTimer{time.AfterFunc(d, f)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+//WithClock sets the Clock a Limiter uses to tell the time and wait for its
+//rate.
+//The Limiter passes the times of its Clock to its pacer, so the built-in pacers
+//follow the Clock too, including when SetDuration changes the rate of a
+//TokenBucket. A KeyedLimiter given WithClock evicts idle keys and tracks
+//throttled keys by the Clock, and a ConcurrencyLimiter paced by a Limiter asks
+//it about the times of its Clock.
+//Children created with NewChild use the Clock of their parent unless given
+//their own.
+func WithClock(c Clock) Option {
+	return func(l *Limiter) {
+		l.clock = c
+	}
+}
@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+//manualClock is a Clock whose time only moves when set, and whose timers fire
+//once the time passes them.
+type manualClock struct {
+	lock   sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+}
+
+type manualTimer struct {
+	clock *manualClock
+	when  time.Time
+	c     chan time.Time
+	f     func()
+}
+
+func (c *manualClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *manualClock) NewTimer(d time.Duration) Timer {
+	return c.add(d, nil)
+}
+
+func (c *manualClock) AfterFunc(d time.Duration, f func()) Timer {
+	return c.add(d, f)
+}
+
+func (c *manualClock) add(d time.Duration, f func()) *manualTimer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	t := &manualTimer{clock: c, when: c.now.Add(d), c: make(chan time.Time, 1), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (c *manualClock) advance(d time.Duration) {
+	c.lock.Lock()
+	c.now = c.now.Add(d)
+	due := []*manualTimer{}
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.when.After(c.now) {
+			pending = append(pending, t)
+		} else {
+			due = append(due, t)
+		}
+	}
+	c.timers = pending
+	c.lock.Unlock()
+
+	for _, t := range due {
+		if t.f != nil {
+			go t.f()
+		} else {
+			t.c <- c.now
+		}
+	}
+}
+
+func (t *manualTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *manualTimer) Stop() bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	for i, other := range t.clock.timers {
+		if other == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (t *manualTimer) Reset(d time.Duration) bool {
+	active := t.Stop()
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	t.when = t.clock.now.Add(d)
+	t.clock.timers = append(t.clock.timers, t)
+	return active
+}
+
+func TestWithClock_pacesByTheClock(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	l := New(time.Duration(1)*time.Hour, WithClock(c))
+
+	if !l.Allow() || l.Allow() {
+		t.Fatal("only the first release should be allowed")
+	}
+	if delay := l.Delay(); delay != time.Duration(1)*time.Hour {
+		t.Fatal(delay)
+	}
+
+	c.advance(time.Duration(1) * time.Hour)
+	if !l.Allow() {
+		t.Fatal("release should be allowed once the clock has advanced")
+	}
+}
+
+func TestWithClock_wakesAPopWhenTheClockAdvances(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	l := New(time.Duration(1)*time.Hour, WithClock(c))
+	l.Wait()
+	l.Push(1)
+
+	popped := make(chan interface{})
+	go func() {
+		popped <- l.Pop()
+	}()
+	for {
+		c.lock.Lock()
+		waiting := len(c.timers) > 0
+		c.lock.Unlock()
+		if waiting {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.advance(time.Duration(1) * time.Hour)
+
+	select {
+	case v := <-popped:
+		if v != 1 {
+			t.Fatal(v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Pop should return once the clock has advanced")
+	}
+}
+
+func TestNewChild_usesTheClockOfItsParent(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	child := New(time.Duration(1), WithClock(c)).NewChild(time.Duration(1))
+
+	if child.clock != c {
+		t.Fail()
+	}
+}
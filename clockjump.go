@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+//WithMaxCatchUp protects a Limiter against jumps of its clock, such as when a
+//laptop sleeps or a virtual machine is paused, by never letting its pacer observe
+//more than max pass between two consecutive checks, nor observe time going
+//backwards.
+//
+//Times from SystemClock carry a monotonic clock reading, so changes to the wall
+//clock never affect pacing. Depending on the platform, however, the monotonic
+//clock may or may not advance while the system is suspended. If it does, a pacer
+//that catches up on idle time, such as with WithCatchUp or a SlidingWindow, could
+//release a large burst after resuming. A Clock without monotonic readings could
+//also move backwards and stall releases for as long as it was set back.
+//
+//max should be at least as long as it takes the pacer to fully recover from
+//being idle, for example burst times the throughput duration for a TokenBucket,
+//otherwise genuinely idle time is not fully credited after the Limiter has been
+//idle for longer than max. A wait that the pacer itself asked for is always
+//credited in full.
+func WithMaxCatchUp(max time.Duration) Option {
+	return func(l *Limiter) {
+		l.maxCatchUp = max
+	}
+}
+
+//jumpPacer hides jumps of the time passed to another Pacer.
+//The times passed to pacer are shifted by offset, which absorbs any time going
+//backwards and any time beyond max passing between two calls.
+type jumpPacer struct {
+	lock *sync.Mutex
+
+	pacer Pacer
+	max   time.Duration
+
+	//last is the most recent time seen, and delay is the most recent delay
+	//returned by pacer, which is how far ahead of last the next call is expected.
+	last   time.Time
+	delay  time.Duration
+	offset time.Duration
+}
+
+func newJumpPacer(p Pacer, max time.Duration) *jumpPacer {
+	return &jumpPacer{
+		lock:  &sync.Mutex{},
+		pacer: p,
+		max:   max,
+	}
+}
+
+func (j *jumpPacer) AllowN(t time.Time, n int) bool {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if !j.pacer.AllowN(j.adjustLocked(t), n) {
+		return false
+	}
+	j.delay = 0
+	return true
+}
+
+func (j *jumpPacer) DelayN(t time.Time, n int) time.Duration {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	j.delay = j.pacer.DelayN(j.adjustLocked(t), n)
+	return j.delay
+}
+
+func (j *jumpPacer) setDurationAt(t time.Time, d time.Duration) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	setPacerDuration(j.pacer, j.adjustLocked(t), d)
+}
+
+func (j *jumpPacer) Reset() {
+	if resetter, ok := j.pacer.(resetter); ok {
+		resetter.Reset()
+	}
+}
+
+//adjustLocked returns the time to pass to pacer for t.
+func (j *jumpPacer) adjustLocked(t time.Time) time.Time {
+	if j.last.IsZero() {
+		j.last = t
+		return t
+	}
+
+	allowed := j.max
+	if j.delay > allowed {
+		allowed = j.delay
+	}
+	switch elapsed := t.Sub(j.last); {
+	case elapsed < 0:
+		j.offset -= elapsed
+	case elapsed > allowed:
+		j.offset -= elapsed - allowed
+	}
+	j.last = t
+	return t.Add(j.offset)
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithMaxCatchUp_limitsTheBurstAfterAForwardJump(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	rl := New(time.Second, WithClock(c), WithCatchUp(time.Hour), WithMaxCatchUp(time.Duration(3)*time.Second))
+	for rl.Allow() {
+	}
+
+	c.advance(time.Hour)
+
+	n := 0
+	for rl.Allow() {
+		n++
+	}
+	if n != 3 {
+		t.Fatal(n)
+	}
+}
+
+func TestWithMaxCatchUp_doesNotStallAfterABackwardJump(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	rl := New(time.Second, WithClock(c), WithMaxCatchUp(time.Minute))
+	rl.Allow()
+
+	c.advance(-time.Hour)
+	if rl.Allow() {
+		t.Fatal("the rate should still apply")
+	}
+	c.advance(time.Second)
+
+	if !rl.Allow() {
+		t.Fatal("releases should resume one duration later")
+	}
+}
+
+func TestWithMaxCatchUp_creditsWaitsThePacerAskedFor(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	rl := New(time.Hour, WithClock(c), WithMaxCatchUp(time.Second))
+	rl.Allow()
+
+	if delay := rl.Delay(); delay != time.Hour {
+		t.Fatal(delay)
+	}
+	c.advance(time.Hour)
+
+	if !rl.Allow() {
+		t.Fatal("a release should be permitted after the delay")
+	}
+}
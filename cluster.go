@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+//SharedLimiter is a Limiter that releases its share of a throughput shared by
+//a cluster of instances, without communicating with the other instances.
+//
+//Each of the n members of a cluster releases one value every n throughput
+//durations of the cluster, so that together they release about one value every
+//throughput duration.
+//The cluster is only limited approximately: load that is unevenly spread
+//between members is released more slowly than the cluster permits, the bursts
+//of all members may coincide, and the cluster may briefly exceed its throughput
+//while members learn of a change in its size.
+//
+//The size of the cluster may be fixed, or may be reported with SetMembers as
+//instances join and leave it.
+type SharedLimiter struct {
+	*Limiter
+
+	shareLock *sync.Mutex
+	cluster   time.Duration
+	members   int
+}
+
+//NewShared creates a SharedLimiter for one of members instances sharing a
+//throughput of one value every d, and is otherwise configured by opts.
+func NewShared(d time.Duration, members int, opts ...Option) *SharedLimiter {
+	members = atLeastOne(members)
+	return &SharedLimiter{
+		Limiter:   New(d*time.Duration(members), opts...),
+		shareLock: &sync.Mutex{},
+		cluster:   d,
+		members:   members,
+	}
+}
+
+//Members returns the number of members s currently shares its throughput with,
+//including itself.
+func (s *SharedLimiter) Members() int {
+	s.shareLock.Lock()
+	defer s.shareLock.Unlock()
+
+	return s.members
+}
+
+//SetMembers changes the number of members s shares its throughput with,
+//including itself, to n.
+//A number less than one is treated as one, since s is a member itself.
+func (s *SharedLimiter) SetMembers(n int) {
+	s.shareLock.Lock()
+	defer s.shareLock.Unlock()
+
+	s.members = atLeastOne(n)
+	s.updateLocked()
+}
+
+//ClusterDuration returns the throughput duration of the whole cluster.
+//Duration returns that of s alone.
+func (s *SharedLimiter) ClusterDuration() time.Duration {
+	s.shareLock.Lock()
+	defer s.shareLock.Unlock()
+
+	return s.cluster
+}
+
+//SetDuration changes the throughput duration of the whole cluster to d, which
+//changes that of s to its share of d.
+func (s *SharedLimiter) SetDuration(d time.Duration) {
+	s.shareLock.Lock()
+	defer s.shareLock.Unlock()
+
+	s.cluster = d
+	s.updateLocked()
+}
+
+func (s *SharedLimiter) updateLocked() {
+	s.Limiter.SetDuration(s.cluster * time.Duration(s.members))
+}
+
+func atLeastOne(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewShared_releasesItsShareOfTheClusterThroughput(t *testing.T) {
+	s := NewShared(time.Duration(1)*time.Hour, 4)
+
+	if d := s.Duration(); d != time.Duration(4)*time.Hour {
+		t.Fatal(d)
+	}
+	if d := s.ClusterDuration(); d != time.Duration(1)*time.Hour {
+		t.Fatal(d)
+	}
+}
+
+func TestNewShared_treatsFewerThanOneMemberAsOne(t *testing.T) {
+	s := NewShared(time.Duration(1)*time.Hour, 0)
+
+	if s.Members() != 1 || s.Duration() != time.Duration(1)*time.Hour {
+		t.Fail()
+	}
+}
+
+func TestSharedLimiter_SetMembers_changesTheShare(t *testing.T) {
+	s := NewShared(time.Duration(1)*time.Hour, 4)
+
+	s.SetMembers(2)
+
+	if s.Members() != 2 || s.Duration() != time.Duration(2)*time.Hour {
+		t.Fail()
+	}
+}
+
+func TestSharedLimiter_SetDuration_changesTheClusterDuration(t *testing.T) {
+	s := NewShared(time.Duration(1)*time.Hour, 3)
+
+	s.SetDuration(time.Duration(2) * time.Hour)
+
+	if s.ClusterDuration() != time.Duration(2)*time.Hour || s.Duration() != time.Duration(6)*time.Hour {
+		t.Fail()
+	}
+}
@@ -0,0 +1,84 @@
+//Command throttle copies its standard input to its standard output at a limited
+//rate, for example to replay a log file or feed a load script at a steady pace.
+//
+//Usage:
+//
+//	throttle [-rate 10/s] [-burst 1]
+//	throttle -bytes 65536
+//
+//By default throttle writes lines at the given Rate, such as "100/s" or
+//"5k/m". With -bytes it instead copies bytes at the given number per second,
+//regardless of lines.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintln(os.Stderr, "throttle:", err)
+		}
+		os.Exit(2)
+	}
+}
+
+func run(args []string, in io.Reader, out, errOut io.Writer) error {
+	rate := ratelimit.Rate{N: 10, Per: time.Second}
+
+	fs := flag.NewFlagSet("throttle", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	fs.Var(&rate, "rate", "number of lines per period to write, such as 100/s")
+	burst := fs.Int("burst", 1, "number of lines that may be written at once")
+	bytesPerSec := fs.Int("bytes", 0, "if positive, copy this many bytes per second instead of lines")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	if *bytesPerSec > 0 {
+		_, err := io.Copy(out, ratelimit.NewReader(in, *bytesPerSec))
+		return err
+	}
+
+	opts := []ratelimit.Option{}
+	if *burst > 1 {
+		opts = append(opts, ratelimit.WithBurst(*burst))
+	}
+	return copyLines(out, in, ratelimit.New(rate.Duration(), opts...))
+}
+
+//copyLines writes each line read from in to out once l permits it.
+//Each line is flushed as soon as it is written so that its timing is preserved.
+func copyLines(out io.Writer, in io.Reader, l *ratelimit.Limiter) error {
+	r := bufio.NewReader(in)
+	w := bufio.NewWriter(out)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			l.Wait()
+			if _, err := w.Write(line); err != nil {
+				return err
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun_writesLinesAtTheRate(t *testing.T) {
+	out := &bytes.Buffer{}
+	start := time.Now()
+
+	err := run([]string{"-rate", "50/s"}, strings.NewReader("a\nb\nc"), out, ioutil.Discard)
+
+	if err != nil || out.String() != "a\nb\nc" {
+		t.Fatal(out.String(), err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Duration(30)*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+}
+
+func TestRun_copiesBytes(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	err := run([]string{"-bytes", "1000000"}, strings.NewReader("abc"), out, ioutil.Discard)
+
+	if err != nil || out.String() != "abc" {
+		t.Fatal(out.String(), err)
+	}
+}
+
+func TestRun_rejectsInvalidFlags(t *testing.T) {
+	for _, args := range [][]string{{"-rate", "fast"}, {"extra"}} {
+		if err := run(args, strings.NewReader(""), ioutil.Discard, ioutil.Discard); err == nil {
+			t.Error(args)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+//Combine returns a Pacer that only permits releases when all of pacers permit
+//them, and counts each release with all of pacers.
+//This allows multiple limits to be stacked, for example 10 per second and 100
+//per minute and 1000 per hour:
+//
+//	p := Combine(
+//		NewTokenBucket(time.Second/10, 10),
+//		NewSlidingWindow(100, time.Minute),
+//		NewSlidingWindow(1000, time.Hour),
+//	)
+//	l := New(0, WithPacer(p))
+//
+//Since Limiter implements Pacer, Limiters may be combined as well.
+//
+//The returned Pacer does not forward SetDuration or Reset to pacers.
+//If one of pacers is also used elsewhere, then releases counted elsewhere
+//between checking and counting a release may leave it refusing the release, in
+//which case AllowN returns false but the pacers before it have already counted
+//it. Use NewChild to share the budget of a Limiter without that.
+func Combine(pacers ...Pacer) Pacer {
+	return &combined{
+		lock:   &sync.Mutex{},
+		pacers: pacers,
+	}
+}
+
+type combined struct {
+	lock   *sync.Mutex
+	pacers []Pacer
+}
+
+func (c *combined) AllowN(t time.Time, n int) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.delayLocked(t, n) > 0 {
+		return false
+	}
+
+	for _, p := range c.pacers {
+		if !p.AllowN(t, n) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *combined) DelayN(t time.Time, n int) time.Duration {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.delayLocked(t, n)
+}
+
+func (c *combined) delayLocked(t time.Time, n int) time.Duration {
+	delay := time.Duration(0)
+	for _, p := range c.pacers {
+		if d := p.DelayN(t, n); d > delay {
+			delay = d
+		}
+	}
+	return delay
+}
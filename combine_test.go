@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCombine_permitsOnlyWhenAllPacersPermit(t *testing.T) {
+	second := NewTokenBucket(time.Second, 3)
+	minute := NewSlidingWindow(2, time.Minute)
+	p := Combine(second, minute)
+	now := time.Now()
+
+	if !p.AllowN(now, 1) || !p.AllowN(now, 1) {
+		t.Fail()
+	}
+
+	if p.AllowN(now, 1) {
+		t.Fail()
+	}
+
+	if tokens := second.Tokens(now); tokens != 1 {
+		t.Fatal(tokens)
+	}
+
+	if delay := p.DelayN(now, 1); delay != time.Minute {
+		t.Fail()
+	}
+}
+
+func TestCombine_delayIsTheLongestDelay(t *testing.T) {
+	p := Combine(NewTokenBucket(time.Second, 1), NewTokenBucket(time.Minute, 1))
+	now := time.Now()
+	p.AllowN(now, 1)
+
+	if delay := p.DelayN(now, 1); delay != time.Minute {
+		t.Fail()
+	}
+}
+
+func TestCombine_acceptsLimiters(t *testing.T) {
+	a := New(time.Hour)
+	b := New(time.Duration(1))
+	rl := New(0, WithCapacity(2), WithPacer(Combine(a, b)))
+	rl.PushAll(0, 1)
+
+	if values := rl.PopAvailable(); len(values) != 1 {
+		t.Fail()
+	}
+
+	if a.Allow() || !b.Allow() {
+		t.Fail()
+	}
+}
+
+func TestCombine_withNoPacersAlwaysPermits(t *testing.T) {
+	p := Combine()
+
+	if !p.AllowN(time.Now(), 100) || p.DelayN(time.Now(), 100) != 0 {
+		t.Fail()
+	}
+}
@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+)
+
+//ErrNotAcquired designates that ConcurrencyLimiter.Release was called more times
+//than the ConcurrencyLimiter was acquired.
+var ErrNotAcquired = errors.New("ratelimit: concurrency limiter not acquired")
+
+//ConcurrencyLimiter caps the number of operations that are in flight at once,
+//as opposed to the rate at which they start.
+//
+//A ConcurrencyLimiter may be combined with a Pacer, such as a Limiter, so that
+//operations are limited both in number and in rate (for example, at most 10
+//concurrently and at most 5 per second).
+type ConcurrencyLimiter struct {
+	slots chan struct{}
+	pacer Pacer
+}
+
+//NewConcurrencyLimiter creates a ConcurrencyLimiter that permits at most n
+//operations in flight at once.
+//If p is not nil, then operations also do not start faster than p permits.
+func NewConcurrencyLimiter(n int, p Pacer) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		slots: make(chan struct{}, n),
+		pacer: p,
+	}
+}
+
+//Acquire blocks until fewer than the maximum number of operations are in flight
+//and the pacer of c, if any, permits another to start.
+//Each successful call to Acquire must be followed by a call to Release once the
+//operation is done.
+//
+//If ctx is done first, then nothing is acquired and ctx.Err() is returned.
+func (c *ConcurrencyLimiter) Acquire(ctx context.Context) error {
+	select {
+	case c.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if c.pacer != nil {
+		if err := waitPacer(ctx, c.pacer, 1); err != nil {
+			<-c.slots
+			return err
+		}
+	}
+	return nil
+}
+
+//TryAcquire acquires c without blocking if fewer than the maximum number of
+//operations are in flight and the pacer of c, if any, permits another to start.
+//It reports whether c was acquired.
+func (c *ConcurrencyLimiter) TryAcquire() bool {
+	select {
+	case c.slots <- struct{}{}:
+	default:
+		return false
+	}
+
+	if c.pacer != nil && !c.pacer.AllowN(clockOf(c.pacer).Now(), 1) {
+		<-c.slots
+		return false
+	}
+	return true
+}
+
+//Release marks an operation started by Acquire or TryAcquire as done.
+//
+//err will be ErrNotAcquired if there is no operation in flight.
+func (c *ConcurrencyLimiter) Release() error {
+	select {
+	case <-c.slots:
+		return nil
+	default:
+		return ErrNotAcquired
+	}
+}
+
+//Do acquires c, calls fn, and then releases c, returning the error from fn.
+//
+//If ctx is done before c can be acquired, then fn is not called and ctx.Err()
+//is returned.
+func (c *ConcurrencyLimiter) Do(ctx context.Context, fn func() error) error {
+	if err := c.Acquire(ctx); err != nil {
+		return err
+	}
+	defer c.Release()
+
+	return fn()
+}
+
+//InFlight returns the number of operations currently in flight.
+func (c *ConcurrencyLimiter) InFlight() int {
+	return len(c.slots)
+}
+
+//Max returns the maximum number of operations permitted in flight at once.
+func (c *ConcurrencyLimiter) Max() int {
+	return cap(c.slots)
+}
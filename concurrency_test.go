@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimiter_Acquire_blocksAtTheMaximum(t *testing.T) {
+	c := NewConcurrencyLimiter(2, nil)
+
+	if c.Acquire(context.Background()) != nil || c.Acquire(context.Background()) != nil {
+		t.Fail()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	if err := c.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Fail()
+	}
+
+	if c.InFlight() != 2 || c.Max() != 2 {
+		t.Fail()
+	}
+}
+
+func TestConcurrencyLimiter_Release_makesRoomForAnotherOperation(t *testing.T) {
+	c := NewConcurrencyLimiter(1, nil)
+	c.Acquire(context.Background())
+
+	if c.TryAcquire() {
+		t.Fail()
+	}
+
+	if err := c.Release(); err != nil {
+		t.Fail()
+	}
+
+	if !c.TryAcquire() {
+		t.Fail()
+	}
+}
+
+func TestConcurrencyLimiter_Release_returnsErrorIfNotAcquired(t *testing.T) {
+	c := NewConcurrencyLimiter(1, nil)
+
+	if err := c.Release(); err != ErrNotAcquired {
+		t.Fail()
+	}
+}
+
+func TestConcurrencyLimiter_Acquire_honorsThePacer(t *testing.T) {
+	c := NewConcurrencyLimiter(10, New(time.Duration(1)*time.Hour))
+
+	if err := c.Acquire(context.Background()); err != nil {
+		t.Fail()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	if err := c.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Fail()
+	}
+
+	if c.InFlight() != 1 {
+		t.Fail()
+	}
+
+	if c.TryAcquire() {
+		t.Fail()
+	}
+}
+
+func TestConcurrencyLimiter_TryAcquire_usesTheClockOfThePacer(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	cl := NewConcurrencyLimiter(10, New(time.Duration(1)*time.Hour, WithClock(c)))
+
+	if !cl.TryAcquire() || cl.TryAcquire() {
+		t.Fail()
+	}
+
+	c.advance(time.Duration(1) * time.Hour)
+
+	if !cl.TryAcquire() {
+		t.Fail()
+	}
+}
+
+func TestConcurrencyLimiter_Do_limitsOperationsInFlight(t *testing.T) {
+	c := NewConcurrencyLimiter(3, nil)
+
+	lock := &sync.Mutex{}
+	inFlight, maxInFlight := 0, 0
+
+	group := &sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			c.Do(context.Background(), func() error {
+				lock.Lock()
+				inFlight++
+				if inFlight > maxInFlight {
+					maxInFlight = inFlight
+				}
+				lock.Unlock()
+
+				time.Sleep(time.Millisecond)
+
+				lock.Lock()
+				inFlight--
+				lock.Unlock()
+				return nil
+			})
+		}()
+	}
+	group.Wait()
+
+	if maxInFlight > 3 || c.InFlight() != 0 {
+		t.Fail()
+	}
+}
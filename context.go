@@ -0,0 +1,21 @@
+package ratelimit
+
+import "context"
+
+//contextKey is the type of the key a Limiter is stored under in a
+//context.Context, so that it cannot collide with keys of other packages.
+type contextKey struct{}
+
+//NewContext returns a copy of ctx that carries l.
+//This allows, for example, middleware to attach the Limiter that applies to a
+//request so that code handling the request can pace follow-up calls with the
+//same budget.
+func NewContext(ctx context.Context, l *Limiter) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+//FromContext returns the Limiter carried by ctx, if any.
+func FromContext(ctx context.Context) (l *Limiter, ok bool) {
+	l, ok = ctx.Value(contextKey{}).(*Limiter)
+	return l, ok
+}
@@ -0,0 +1,19 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFromContext_returnsTheLimiterOfNewContext(t *testing.T) {
+	rl := New(time.Duration(1))
+	ctx := NewContext(context.Background(), rl)
+
+	if l, ok := FromContext(ctx); l != rl || !ok {
+		t.Fail()
+	}
+	if l, ok := FromContext(context.Background()); l != nil || ok {
+		t.Fail()
+	}
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import "time"
+
+//DropReason is why a Limiter dropped a value without releasing it.
+type DropReason int
+
+const (
+	//Overflowed means the value was evicted or refused by the Overflow policy of
+	//the Limiter.
+	Overflowed DropReason = iota + 1
+
+	//Expired means the value outlived its time to live from PushTTL or WithTTL.
+	Expired
+
+	//Discarded means the value was still queued when the Limiter was closed with
+	//CloseDiscard, when CloseContext gave up waiting for it, or when the Limiter
+	//was Reset.
+	Discarded
+)
+
+func (r DropReason) String() string {
+	switch r {
+	case Overflowed:
+		return "overflowed"
+	case Expired:
+		return "expired"
+	case Discarded:
+		return "discarded"
+	}
+	return "unknown"
+}
+
+//DroppedValue is a value that a Limiter dropped without releasing it.
+type DroppedValue struct {
+	//Value is the value that was dropped.
+	Value interface{}
+
+	//Reason is why Value was dropped.
+	Reason DropReason
+
+	//Pushed is when Value was pushed, and Time is when it was dropped.
+	Pushed time.Time
+	Time   time.Time
+}
+
+//WithDeadLetter sets fn to be called with every value that a Limiter throws
+//away, because of its Overflow policy, because it expired, or because it was
+//discarded, so that it can be accounted for or persisted.
+//Values returned by Drain or retracted with Item.Cancel are not passed to fn,
+//since the caller already has them.
+//
+//Unlike Observers and Hooks, fn is called without the lock of the Limiter held,
+//by the goroutine whose call dropped the value, before that call returns. It may
+//therefore be slow or use the Limiter, at the cost of slowing that call down.
+func WithDeadLetter(fn func(d DroppedValue)) Option {
+	return func(l *Limiter) {
+		l.deadLetter = fn
+	}
+}
+
+//DeadLetterBuffer is the number of dead letters buffered by the channel returned
+//by DeadLetters.
+const DeadLetterBuffer = 256
+
+//DeadLetters returns a channel on which every value that l throws away is
+//delivered, along with the reason, just like to the function set with
+//WithDeadLetter.
+//Subsequent calls to DeadLetters return the same channel.
+//
+//The channel buffers DeadLetterBuffer values. Values are sent without blocking,
+//so a dead letter that does not fit because the channel is not received from
+//fast enough is lost and counted in Stats.LostDeadLetters instead. The channel is
+//never closed, since a Limiter that is Reset may drop values again.
+func (l *Limiter) DeadLetters() <-chan DroppedValue {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.deadLetters == nil {
+		l.deadLetters = make(chan DroppedValue, DeadLetterBuffer)
+	}
+	return l.deadLetters
+}
+
+//dropLocked reports that items were dropped from the queue of l for reason and
+//delivers them as dead letters with deadLetterLocked.
+func (l *Limiter) dropLocked(reason DropReason, items ...item) {
+	if len(items) == 0 {
+		return
+	}
+	l.observeDroppedLocked(items)
+	l.deadLetterLocked(reason, items...)
+}
+
+//deadLetterLocked sends items as dead letters for reason on the channel returned
+//by DeadLetters and queues them to be passed to the dead-letter function once l
+//is unlocked with unlockAndDeliver, without reporting them as dropped from the
+//queue of l.
+func (l *Limiter) deadLetterLocked(reason DropReason, items ...item) {
+	if l.deadLetter == nil && l.deadLetters == nil {
+		return
+	}
+	now := l.clock.Now()
+	for _, it := range items {
+		d := DroppedValue{
+			Value:  it.value,
+			Reason: reason,
+			Pushed: it.pushed,
+			Time:   now,
+		}
+		if l.deadLetter != nil {
+			l.dead = append(l.dead, d)
+		}
+		if l.deadLetters != nil {
+			select {
+			case l.deadLetters <- d:
+			default:
+				l.stats.lostDeadLetters++
+			}
+		}
+	}
+}
+
+//unlockAndDeliver unlocks l and then passes the values dropped while it was
+//locked to its dead-letter function.
+func (l *Limiter) unlockAndDeliver() {
+	dead, fn := l.dead, l.deadLetter
+	l.dead = nil
+	l.lock.Unlock()
+
+	for _, d := range dead {
+		fn(d)
+	}
+}
@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLimiter_DeadLetters_receivesDroppedValuesWithTheirReasons(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	l := New(time.Duration(1)*time.Hour, WithClock(c), WithCapacity(3), WithOverflow(DropOldest))
+	dead := l.DeadLetters()
+	if l.DeadLetters() != dead {
+		t.Fatal("should return the same channel")
+	}
+
+	received := make(chan []DroppedValue)
+	go func() {
+		got := []DroppedValue{}
+		for len(got) < 5 {
+			got = append(got, <-dead)
+		}
+		received <- got
+	}()
+	l.PushTTL(1, time.Duration(1)*time.Second)
+	l.PushAll(2, 3)
+	c.advance(time.Duration(1) * time.Second)
+	l.Push(4)
+	l.Push(5)
+	l.CloseDiscard()
+
+	got := <-received
+	want := []DroppedValue{
+		{1, Expired, time.Unix(0, 0), time.Unix(1, 0)},
+		{2, Overflowed, time.Unix(0, 0), time.Unix(1, 0)},
+		{3, Discarded, time.Unix(0, 0), time.Unix(1, 0)},
+		{4, Discarded, time.Unix(1, 0), time.Unix(1, 0)},
+		{5, Discarded, time.Unix(1, 0), time.Unix(1, 0)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+}
+
+func TestLimiter_DeadLetters_neverBlocksTheLimiter(t *testing.T) {
+	l := New(time.Duration(1), WithCapacity(Unbounded))
+	l.DeadLetters()
+	for i := 0; i < DeadLetterBuffer+10; i++ {
+		l.Push(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		l.CloseDiscard()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("CloseDiscard should not wait for dead letters to be received")
+	}
+
+	if stats := l.Stats(); stats.LostDeadLetters != 10 {
+		t.Fatal(stats.LostDeadLetters)
+	}
+	if n := len(l.DeadLetters()); n != DeadLetterBuffer {
+		t.Fatal(n)
+	}
+}
+
+func TestWithDeadLetter_receivesValuesDiscardedByCloseAndReset(t *testing.T) {
+	var dropped []interface{}
+	var reasons []DropReason
+	l := New(time.Duration(1)*time.Hour, WithCapacity(Unbounded), WithDeadLetter(func(d DroppedValue) {
+		dropped = append(dropped, d.Value)
+		reasons = append(reasons, d.Reason)
+	}))
+	l.Wait()
+	l.PushAll(1, 2)
+	l.Reset()
+
+	l.Wait()
+	l.Push(3)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(10)*time.Millisecond)
+	defer cancel()
+	l.CloseContext(ctx)
+
+	if !reflect.DeepEqual(dropped, []interface{}{1, 2, 3}) {
+		t.Fatal(dropped)
+	}
+	for _, r := range reasons {
+		if r != Discarded {
+			t.Fatal(reasons)
+		}
+	}
+}
+
+func TestWithDeadLetter_doesNotReceiveDrainedOrCanceledValues(t *testing.T) {
+	calls := 0
+	l := New(time.Duration(1), WithCapacity(Unbounded), WithDeadLetter(func(d DroppedValue) {
+		calls++
+	}))
+	item, _ := l.PushItem(1)
+	l.Push(2)
+
+	item.Cancel()
+	l.Drain()
+
+	if calls != 0 {
+		t.Fatal(calls)
+	}
+}
+
+func TestDropReason_String(t *testing.T) {
+	for r, want := range map[DropReason]string{
+		Overflowed:    "overflowed",
+		Expired:       "expired",
+		Discarded:     "discarded",
+		DropReason(0): "unknown",
+	} {
+		if s := r.String(); s != want {
+			t.Fatal(r, s)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+)
+
+//ErrClosed designates that a Limiter is already closed in calls to Push, TryPush,
+//and Close.
+var ErrClosed = errors.New("ratelimit: limiter already closed")
+
+//ErrTimeout designates that a call to PushTimeout or PopTimeout did not complete
+//within the provided duration.
+var ErrTimeout = errors.New("ratelimit: timeout")
+
+//ErrQueueFull designates that a value could not be pushed because there was no
+//space left to store it.
+var ErrQueueFull = errors.New("ratelimit: queue full")
+
+//LimiterError records an error and the operation of the named Limiter that
+//caused it.
+//The errors returned by a Limiter created with WithName are LimiterErrors, so
+//they should be compared with errors.Is, as in errors.Is(err, ErrClosed), rather
+//than with ==.
+type LimiterError struct {
+	//Name is the name of the Limiter given to WithName.
+	Name string
+
+	//Op is the operation that failed: "push", "pop", "wait", or "close".
+	Op string
+
+	Err error
+}
+
+func (e *LimiterError) Error() string {
+	return e.Op + " " + e.Name + ": " + e.Err.Error()
+}
+
+//Unwrap returns the underlying error of e.
+func (e *LimiterError) Unwrap() error {
+	return e.Err
+}
+
+//WithName names a Limiter so that the errors it returns are LimiterErrors
+//identifying it.
+//Without WithName, a Limiter returns the error values of this package directly.
+func WithName(name string) Option {
+	return func(l *Limiter) {
+		l.name = name
+	}
+}
+
+//Name returns the name given to l with WithName.
+func (l *Limiter) Name() string {
+	return l.name
+}
+
+//opError wraps a non-nil err in a LimiterError for op if l has a name.
+func (l *Limiter) opError(op string, err error) error {
+	if err == nil || l.name == "" {
+		return err
+	}
+	return &LimiterError{Name: l.name, Op: op, Err: err}
+}
+
+func timeoutError(err error) error {
+	if err == context.DeadlineExceeded {
+		return ErrTimeout
+	}
+	return err
+}
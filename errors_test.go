@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithName_wrapsErrorsInALimiterError(t *testing.T) {
+	rl := New(time.Duration(1)*time.Hour, WithName("orders"))
+	rl.Close()
+
+	err := rl.Push(0)
+
+	le, ok := err.(*LimiterError)
+	if !ok || le.Name != "orders" || le.Op != "push" || !errors.Is(err, ErrClosed) {
+		t.Fatal(err)
+	}
+	if err.Error() != "push orders: "+ErrClosed.Error() {
+		t.Fatal(err.Error())
+	}
+	if err := rl.Close(); !errors.Is(err, ErrClosed) || err.(*LimiterError).Op != "close" {
+		t.Fatal(err)
+	}
+}
+
+func TestWithName_wrapsTimeouts(t *testing.T) {
+	rl := New(time.Duration(1)*time.Hour, WithName("orders"))
+
+	_, err := rl.PopTimeout(time.Millisecond)
+
+	var le *LimiterError
+	if !errors.As(err, &le) || le.Op != "pop" || !errors.Is(err, ErrTimeout) {
+		t.Fatal(err)
+	}
+}
+
+func TestLimiter_returnsErrorsDirectlyWithoutAName(t *testing.T) {
+	rl := New(time.Duration(1))
+	rl.Close()
+
+	if err := rl.Push(0); err != ErrClosed {
+		t.Fatal(err)
+	}
+	if rl.Name() != "" {
+		t.Fatal(rl.Name())
+	}
+}
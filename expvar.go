@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"expvar"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+//WithExpvar publishes the counters of a Limiter with expvar under name, for
+//services that do not collect metrics otherwise.
+//The published variable is a JSON object holding the number of values queued
+//("len"), the total number of values pushed and popped ("pushed" and "popped"),
+//and the current rate in releases per second ("rate", omitted if the throughput
+//duration is not positive).
+//
+//Just like expvar.Publish, WithExpvar panics if name is already published.
+//For the same reason NewKeyed and NewShardedKeyed panic if WithExpvar is among
+//their options, since they would apply it to the Limiter of every key.
+func WithExpvar(name string) Option {
+	return func(l *Limiter) {
+		if l.keyed {
+			panic("ratelimit: WithExpvar(" + strconv.Quote(name) + ") cannot be used for the Limiters of a KeyedLimiter")
+		}
+		c := &expvarCounters{}
+		l.observers = append(l.observers, c)
+		expvar.Publish(name, expvar.Func(func() interface{} {
+			return c.vars(l)
+		}))
+	}
+}
+
+//expvarCounters is the Observer that counts the values published by WithExpvar.
+type expvarCounters struct {
+	pushed uint64
+	popped uint64
+}
+
+func (c *expvarCounters) Pushed(value interface{}) {
+	atomic.AddUint64(&c.pushed, 1)
+}
+
+func (c *expvarCounters) Popped(value interface{}, wait time.Duration) {
+	atomic.AddUint64(&c.popped, 1)
+}
+
+func (c *expvarCounters) Dropped(value interface{}) {}
+
+func (c *expvarCounters) Closed() {}
+
+func (c *expvarCounters) vars(l *Limiter) map[string]interface{} {
+	vars := map[string]interface{}{
+		"len":    l.Len(),
+		"pushed": atomic.LoadUint64(&c.pushed),
+		"popped": atomic.LoadUint64(&c.popped),
+	}
+	if d := l.Duration(); d > 0 {
+		vars["rate"] = perSecond(d)
+	}
+	return vars
+}
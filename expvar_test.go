@@ -0,0 +1,33 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+)
+
+func TestWithExpvar_publishesCounters(t *testing.T) {
+	rl := New(time.Second/4, WithCapacity(Unbounded), WithExpvar("TestWithExpvar_publishesCounters"))
+	rl.PushAll(1, 2, 3)
+	rl.Pop()
+
+	vars := map[string]float64{}
+	if err := json.Unmarshal([]byte(expvar.Get("TestWithExpvar_publishesCounters").String()), &vars); err != nil {
+		t.Fatal(err)
+	}
+
+	if vars["len"] != 2 || vars["pushed"] != 3 || vars["popped"] != 1 || vars["rate"] != 4 {
+		t.Fatal(vars)
+	}
+}
+
+func TestWithExpvar_panicsForKeyedLimiters(t *testing.T) {
+	const name = "TestWithExpvar_panicsForKeyedLimiters"
+	expectPanic(t, func() { NewKeyed(time.Second, WithExpvar(name)) })
+	expectPanic(t, func() { NewShardedKeyed(2, time.Second, WithExpvar(name)) })
+
+	if expvar.Get(name) != nil {
+		t.Fatal("a KeyedLimiter should not publish anything")
+	}
+}
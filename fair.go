@@ -0,0 +1,212 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+//FairLimiter rate limits values pushed to it by multiple producers, releasing
+//them fairly across producers instead of in the order they were pushed.
+//
+//Each producer pushes values to its own Stream. Releases are interleaved across
+//streams using weighted round-robin: a stream with weight w has up to w values
+//released in a row before the next stream with values is served. A single busy
+//stream therefore cannot starve the others.
+//Values within a single stream are released in the order they were pushed.
+type FairLimiter struct {
+	monitor
+
+	pacer Pacer
+
+	streams []*Stream
+	n       int
+	closed  bool
+
+	//current is the index of the stream being served and served is the number
+	//of values released from it during its turn.
+	current int
+	served  int
+}
+
+//Stream is a producer of values for a FairLimiter.
+type Stream struct {
+	f *FairLimiter
+
+	weight   int
+	capacity int
+	values   *queue
+}
+
+//NewFairLimiter creates a FairLimiter whose releases are permitted by p.
+func NewFairLimiter(p Pacer) *FairLimiter {
+	return &FairLimiter{
+		monitor: newMonitor(),
+		pacer:   p,
+	}
+}
+
+//NewStream registers and returns a new Stream of f with weight and capacity.
+//capacity may be Unbounded.
+func (f *FairLimiter) NewStream(weight, capacity int) *Stream {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	s := &Stream{
+		f:        f,
+		weight:   weight,
+		capacity: capacity,
+		values:   newQueue(initialQueueSize(capacity)),
+	}
+	f.streams = append(f.streams, s)
+	return s
+}
+
+//Push places value in s to be popped from its FairLimiter later.
+//Push does not return until there is space in s to store value.
+//
+//err will be ErrClosed if the FairLimiter of s is closed.
+func (s *Stream) Push(value interface{}) error {
+	f := s.f
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.pollLocked(context.Background(), func(now time.Time) (bool, time.Duration, error) {
+		accepted, err := s.tryPushLocked(value)
+		return accepted, 0, err
+	})
+}
+
+//TryPush attempts to place value in s without blocking.
+//accepted is false if there is no space in s to store value.
+//
+//err will be ErrClosed if the FairLimiter of s is closed.
+func (s *Stream) TryPush(value interface{}) (accepted bool, err error) {
+	s.f.lock.Lock()
+	defer s.f.lock.Unlock()
+
+	return s.tryPushLocked(value)
+}
+
+func (s *Stream) tryPushLocked(value interface{}) (accepted bool, err error) {
+	if s.f.closed {
+		return false, ErrClosed
+	}
+	if s.capacity != Unbounded && s.values.len() >= s.capacity {
+		return false, nil
+	}
+
+	s.values.push(item{value: value})
+	s.f.n++
+	s.f.broadcastLocked()
+	return true, nil
+}
+
+//Len returns the number of values currently queued in s.
+func (s *Stream) Len() int {
+	s.f.lock.Lock()
+	defer s.f.lock.Unlock()
+
+	return s.values.len()
+}
+
+//Pop releases a value from f.
+//It will not return a value until there is a value in one of the streams of f
+//and the pacer of f permits its release.
+//
+//If f is closed and there are no more values to pop, then the returned value
+//will be nil.
+func (f *FairLimiter) Pop() interface{} {
+	v, _ := f.PopOk()
+	return v
+}
+
+//PopOk releases a value from f.
+//It works just like Pop, but has an extra return value ok that designates if f
+//is not closed and value is therefore legitimate.
+func (f *FairLimiter) PopOk() (value interface{}, ok bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	err := f.pollLocked(context.Background(), func(now time.Time) (bool, time.Duration, error) {
+		v, ok, wait := f.tryPopLocked(now)
+		if ok {
+			value = v
+			return true, 0, nil
+		}
+		if f.closed && f.n == 0 {
+			return false, 0, ErrClosed
+		}
+		return false, wait, nil
+	})
+	return value, err == nil
+}
+
+//TryPop attempts to release a value from f without blocking.
+//ok is false if there is no value in f to pop or if the pacer of f does not
+//permit a release now.
+func (f *FairLimiter) TryPop() (value interface{}, ok bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	v, ok, _ := f.tryPopLocked(f.clock.Now())
+	return v, ok
+}
+
+func (f *FairLimiter) tryPopLocked(now time.Time) (value interface{}, ok bool, wait time.Duration) {
+	s := f.nextStreamLocked()
+	if s == nil {
+		return nil, false, 0
+	}
+	if !f.pacer.AllowN(now, 1) {
+		return nil, false, f.pacer.DelayN(now, 1)
+	}
+
+	v := s.values.pop().value
+	f.n--
+	f.served++
+	f.broadcastLocked()
+	return v, true, 0
+}
+
+//nextStreamLocked returns the stream whose value should be released next, or nil
+//if there are no values in f.
+func (f *FairLimiter) nextStreamLocked() *Stream {
+	if len(f.streams) == 0 {
+		return nil
+	}
+
+	for i := 0; i <= len(f.streams); i++ {
+		s := f.streams[f.current]
+		if s.values.len() > 0 && f.served < s.weight {
+			return s
+		}
+		f.current = (f.current + 1) % len(f.streams)
+		f.served = 0
+	}
+	return nil
+}
+
+//Len returns the number of values currently queued in all streams of f.
+func (f *FairLimiter) Len() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.n
+}
+
+//Close closes f and prevents any more values from being pushed to its streams.
+//Note that values not yet popped are still available to receive.
+//
+//If f is already closed, then ErrClosed is returned, otherwise err is nil.
+func (f *FairLimiter) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return ErrClosed
+	}
+
+	f.closed = true
+	f.broadcastLocked()
+	return nil
+}
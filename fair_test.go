@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFairLimiter_interleavesStreamsByWeight(t *testing.T) {
+	f := NewFairLimiter(NewTokenBucket(0, 1))
+	a := f.NewStream(2, Unbounded)
+	b := f.NewStream(1, Unbounded)
+
+	for i := 0; i < 6; i++ {
+		a.Push("a")
+	}
+	b.Push("b")
+	b.Push("b")
+
+	want := []string{"a", "a", "b", "a", "a", "b", "a", "a"}
+	for _, w := range want {
+		if v := f.Pop(); v != w {
+			t.Fatal(v, w)
+		}
+	}
+}
+
+func TestFairLimiter_busyStreamDoesNotStarveOthers(t *testing.T) {
+	f := NewFairLimiter(NewTokenBucket(0, 1))
+	noisy := f.NewStream(1, Unbounded)
+	quiet := f.NewStream(1, Unbounded)
+
+	for i := 0; i < 100; i++ {
+		noisy.Push(i)
+	}
+	quiet.Push("quiet")
+
+	f.Pop()
+	if v := f.Pop(); v != "quiet" {
+		t.Fail()
+	}
+}
+
+func TestFairLimiter_keepsOrderWithinAStream(t *testing.T) {
+	f := NewFairLimiter(NewTokenBucket(0, 1))
+	s := f.NewStream(1, 3)
+	s.Push(0)
+	s.Push(1)
+	s.Push(2)
+
+	for i := 0; i < 3; i++ {
+		if v := f.Pop(); v != i {
+			t.Fail()
+		}
+	}
+}
+
+func TestFairLimiter_TryPop_honorsThePacer(t *testing.T) {
+	f := NewFairLimiter(NewTokenBucket(time.Duration(1)*time.Hour, 1))
+	s := f.NewStream(1, 2)
+	s.Push(0)
+	s.Push(1)
+
+	if v, ok := f.TryPop(); v != 0 || !ok {
+		t.Fail()
+	}
+
+	if _, ok := f.TryPop(); ok {
+		t.Fail()
+	}
+
+	if f.Len() != 1 || s.Len() != 1 {
+		t.Fail()
+	}
+}
+
+func TestStream_TryPush_rejectsWhenFull(t *testing.T) {
+	f := NewFairLimiter(NewTokenBucket(0, 1))
+	s := f.NewStream(1, 1)
+
+	if accepted, err := s.TryPush(0); !accepted || err != nil {
+		t.Fail()
+	}
+
+	if accepted, err := s.TryPush(1); accepted || err != nil {
+		t.Fail()
+	}
+}
+
+func TestFairLimiter_Close_stopsPushesAndDrainsPops(t *testing.T) {
+	f := NewFairLimiter(NewTokenBucket(0, 1))
+	s := f.NewStream(1, 2)
+	s.Push(0)
+
+	if err := f.Close(); err != nil {
+		t.Fail()
+	}
+
+	if err := f.Close(); err != ErrClosed {
+		t.Fail()
+	}
+
+	if err := s.Push(1); err != ErrClosed {
+		t.Fail()
+	}
+
+	if v, ok := f.PopOk(); v != 0 || !ok {
+		t.Fail()
+	}
+
+	if v, ok := f.PopOk(); v != nil || ok {
+		t.Fail()
+	}
+}
+
+func TestFairLimiter_TryPop_withoutStreams(t *testing.T) {
+	f := NewFairLimiter(NewTokenBucket(0, 1))
+
+	if _, ok := f.TryPop(); ok {
+		t.Fail()
+	}
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+//FixedWindow is a Pacer that permits at most n releases within each window of
+//time, where windows are aligned to multiples of their duration since the zero
+//time (for example, on the minute or on the hour in UTC).
+//The count of releases resets at the start of each window.
+//
+//FixedWindow matches upstream quotas that reset at calendar boundaries, but
+//permits up to 2n releases in a short period that spans a boundary.
+type FixedWindow struct {
+	lock *sync.Mutex
+
+	n      int
+	window time.Duration
+
+	start time.Time
+	count int
+}
+
+//NewFixedWindow creates a FixedWindow that permits at most n releases within each
+//aligned window of duration window.
+func NewFixedWindow(n int, window time.Duration) *FixedWindow {
+	return &FixedWindow{
+		lock:   &sync.Mutex{},
+		n:      n,
+		window: window,
+	}
+}
+
+//AllowN reports whether k releases may happen at time t within the limit of the
+//window containing t, and counts them if they may.
+//
+//If k is greater than the limit of w, then the releases are permitted at the
+//start of a window.
+func (w *FixedWindow) AllowN(t time.Time, k int) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.delayLocked(t, k) > 0 {
+		return false
+	}
+
+	w.count += k
+	return true
+}
+
+//DelayN returns how long after t it will be before k releases may happen within
+//the limit of w.
+func (w *FixedWindow) DelayN(t time.Time, k int) time.Duration {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.delayLocked(t, k)
+}
+
+//Remaining returns the number of releases still permitted in the current window.
+func (w *FixedWindow) Remaining() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.advanceLocked(time.Now())
+	if remaining := w.n - w.count; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+//ResetsAt returns the time at which the current window ends and the count of
+//releases resets.
+func (w *FixedWindow) ResetsAt() time.Time {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.advanceLocked(time.Now())
+	return w.start.Add(w.window)
+}
+
+//Reset forgets all releases counted in the current window.
+func (w *FixedWindow) Reset() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.count = 0
+}
+
+func (w *FixedWindow) delayLocked(t time.Time, k int) time.Duration {
+	w.advanceLocked(t)
+
+	if k > w.n {
+		k = w.n
+	}
+	if w.count+k <= w.n {
+		return 0
+	}
+	return w.start.Add(w.window).Sub(t)
+}
+
+//advanceLocked moves w to the window containing t if it is a later window.
+func (w *FixedWindow) advanceLocked(t time.Time) {
+	if start := t.Truncate(w.window); start.After(w.start) {
+		w.start = start
+		w.count = 0
+	}
+}
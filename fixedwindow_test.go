@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixedWindow_AllowN_permitsNPerAlignedWindow(t *testing.T) {
+	window := time.Duration(1) * time.Minute
+	w := NewFixedWindow(2, window)
+	start := time.Now().Truncate(window)
+
+	if !w.AllowN(start.Add(time.Second), 1) || !w.AllowN(start.Add(2*time.Second), 1) {
+		t.Fail()
+	}
+
+	if w.AllowN(start.Add(window-1), 1) {
+		t.Fail()
+	}
+
+	if !w.AllowN(start.Add(window), 2) {
+		t.Fail()
+	}
+}
+
+func TestFixedWindow_DelayN_returnsTimeUntilTheWindowResets(t *testing.T) {
+	window := time.Duration(1) * time.Minute
+	w := NewFixedWindow(1, window)
+	start := time.Now().Truncate(window)
+	at := start.Add(10 * time.Second)
+	w.AllowN(at, 1)
+
+	if delay := w.DelayN(at, 1); delay != window-10*time.Second {
+		t.Fail()
+	}
+
+	if delay := w.DelayN(start.Add(window), 1); delay != 0 {
+		t.Fail()
+	}
+}
+
+func TestFixedWindow_AllowN_moreThanTheLimitWaitsForANewWindow(t *testing.T) {
+	window := time.Duration(1) * time.Minute
+	w := NewFixedWindow(2, window)
+	start := time.Now().Truncate(window)
+	w.AllowN(start, 1)
+
+	if w.AllowN(start, 3) {
+		t.Fail()
+	}
+
+	if !w.AllowN(start.Add(window), 3) {
+		t.Fail()
+	}
+}
+
+func TestFixedWindow_RemainingAndResetsAt(t *testing.T) {
+	window := time.Duration(1) * time.Hour
+	w := NewFixedWindow(3, window)
+	w.AllowN(time.Now(), 1)
+
+	if w.Remaining() != 2 {
+		t.Fail()
+	}
+
+	if resetsAt := w.ResetsAt(); !resetsAt.Equal(time.Now().Truncate(window).Add(window)) {
+		t.Fail()
+	}
+
+	w.Reset()
+
+	if w.Remaining() != 3 {
+		t.Fail()
+	}
+}
@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//GCRA is a Pacer that implements the generic cell rate algorithm.
+//It permits one release every interval on average and tolerates releases that
+//arrive up to tolerance early, which allows a burst of about
+//1 + tolerance/interval releases.
+//
+//GCRA keeps a single theoretical arrival time as its state, so both its
+//decisions and its memory use are O(1).
+//Releasing n values at once requires only the first to be permitted and moves
+//the theoretical arrival time n intervals ahead.
+type GCRA struct {
+	lock *sync.Mutex
+
+	interval  time.Duration
+	tolerance time.Duration
+
+	//tat is the theoretical arrival time of the next release.
+	tat time.Time
+}
+
+//NewGCRA creates a GCRA with emission interval interval and burst tolerance
+//tolerance.
+func NewGCRA(interval, tolerance time.Duration) *GCRA {
+	return &GCRA{
+		lock:      &sync.Mutex{},
+		interval:  interval,
+		tolerance: tolerance,
+	}
+}
+
+//Allow reports whether a release may happen now, counting it if it may.
+//It is shorthand for g.AllowN(time.Now(), 1).
+func (g *GCRA) Allow() bool {
+	return g.AllowN(time.Now(), 1)
+}
+
+//AllowN reports whether n releases may happen at time t, counting them if they
+//may.
+func (g *GCRA) AllowN(t time.Time, n int) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.delayLocked(t) > 0 {
+		return false
+	}
+
+	tat := g.tat
+	if tat.Before(t) {
+		tat = t
+	}
+	g.tat = tat.Add(time.Duration(n) * g.interval)
+	return true
+}
+
+//DelayN returns how long after t it will be before n releases may happen.
+func (g *GCRA) DelayN(t time.Time, n int) time.Duration {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if delay := g.delayLocked(t); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+//Wait blocks until a release may happen, counting it, or until ctx is done, in
+//which case ctx.Err() is returned.
+func (g *GCRA) Wait(ctx context.Context) error {
+	return waitPacer(ctx, g, 1)
+}
+
+//SetDuration changes the emission interval of g to d.
+func (g *GCRA) SetDuration(d time.Duration) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.interval = d
+}
+
+//Reset forgets all releases counted by g.
+func (g *GCRA) Reset() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.tat = time.Time{}
+}
+
+func (g *GCRA) delayLocked(t time.Time) time.Duration {
+	return g.tat.Add(-g.tolerance).Sub(t)
+}
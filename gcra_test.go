@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGCRA_AllowN_permitsBurstWithinTolerance(t *testing.T) {
+	interval := time.Duration(1) * time.Second
+	g := NewGCRA(interval, 2*interval)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if !g.AllowN(now, 1) {
+			t.Fail()
+		}
+	}
+
+	if g.AllowN(now, 1) {
+		t.Fail()
+	}
+
+	if !g.AllowN(now.Add(interval), 1) {
+		t.Fail()
+	}
+}
+
+func TestGCRA_AllowN_withoutToleranceIsStrict(t *testing.T) {
+	interval := time.Duration(1) * time.Second
+	g := NewGCRA(interval, 0)
+	now := time.Now()
+
+	if !g.AllowN(now, 1) {
+		t.Fail()
+	}
+
+	if g.AllowN(now.Add(interval-1), 1) {
+		t.Fail()
+	}
+
+	if !g.AllowN(now.Add(interval), 1) {
+		t.Fail()
+	}
+}
+
+func TestGCRA_DelayN_returnsTimeUntilConforming(t *testing.T) {
+	interval := time.Duration(1) * time.Second
+	g := NewGCRA(interval, interval)
+	now := time.Now()
+	g.AllowN(now, 3)
+
+	if delay := g.DelayN(now, 1); delay != 2*interval {
+		t.Fail()
+	}
+
+	if delay := g.DelayN(now.Add(5*interval), 1); delay != 0 {
+		t.Fail()
+	}
+}
+
+func TestGCRA_Allow_andWait(t *testing.T) {
+	interval := time.Duration(10) * time.Millisecond
+	g := NewGCRA(interval, 0)
+
+	if !g.Allow() {
+		t.Fail()
+	}
+
+	if g.Allow() {
+		t.Fail()
+	}
+
+	if err := g.Wait(context.Background()); err != nil {
+		t.Fail()
+	}
+}
+
+func TestGCRA_Wait_returnsErrorIfContextIsDone(t *testing.T) {
+	g := NewGCRA(time.Duration(1)*time.Hour, 0)
+	g.Allow()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	if err := g.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fail()
+	}
+}
+
+func TestGCRA_Reset_forgetsReleases(t *testing.T) {
+	g := NewGCRA(time.Duration(1)*time.Hour, 0)
+	g.Allow()
+
+	g.Reset()
+
+	if !g.Allow() {
+		t.Fail()
+	}
+}
@@ -0,0 +1,23 @@
+package ratelimit
+
+import "time"
+
+//WithHighResolution makes a Limiter release values closer to their exact release
+//times, for uses such as driving hardware or replaying market data where the
+//granularity of timers causes drift at sub-millisecond rates.
+//
+//A goroutine waiting for the rate of the Limiter sleeps until spin before the
+//release time, as usual, and then busy-waits for the remainder, checking the
+//time in a loop that yields the processor on every iteration. A spin of a few
+//hundred microseconds to a millisecond covers the timer granularity of most
+//systems.
+//
+//The accuracy costs CPU: every goroutine waiting for the rate keeps a processor
+//busy for up to spin before each release, so at a rate of one release every 2*spin
+//a waiting consumer uses about half a CPU. Spinning only applies to waits for the
+//rate, not to waits for values to be pushed or for space to push them.
+func WithHighResolution(spin time.Duration) Option {
+	return func(l *Limiter) {
+		l.spin = spin
+	}
+}
@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithHighResolution_busyWaitsInsteadOfSleepingNearTheRelease(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	rl := New(time.Minute, WithClock(c), WithHighResolution(time.Hour))
+	rl.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		rl.Wait()
+		close(done)
+	}()
+	time.Sleep(time.Duration(10) * time.Millisecond)
+
+	c.lock.Lock()
+	timers := len(c.timers)
+	c.lock.Unlock()
+	if timers != 0 {
+		t.Fatal("the wait should spin rather than use a timer", timers)
+	}
+	select {
+	case <-done:
+		t.Fatal("the rate should still apply")
+	default:
+	}
+
+	c.advance(time.Minute)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the wait should end once its time is reached")
+	}
+}
+
+func TestWithHighResolution_sleepsUntilTheSpin(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	rl := New(time.Minute, WithClock(c), WithHighResolution(time.Second))
+	rl.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		rl.Wait()
+		close(done)
+	}()
+	due := time.Duration(0)
+	for due == 0 {
+		time.Sleep(time.Millisecond)
+		c.lock.Lock()
+		if len(c.timers) == 1 {
+			due = c.timers[0].when.Sub(time.Unix(0, 0))
+		}
+		c.lock.Unlock()
+	}
+	if due != time.Minute-time.Second {
+		t.Fatal(due)
+	}
+
+	c.advance(time.Minute)
+	<-done
+}
@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+//waitBuckets is the number of buckets of a WaitHistogram. Bucket zero holds
+//waits under a microsecond and bucket i holds waits of at least 2^(i-1) and
+//under 2^i microseconds, with the last bucket holding everything longer.
+const waitBuckets = 40
+
+//WaitHistogram is the distribution of how long the values released by a
+//Limiter waited, from being pushed to being popped, which includes both the
+//time they were queued behind other values and the time they were held back by
+//the rate.
+//
+//Waits are counted in buckets whose bounds double, so percentiles are estimated
+//by interpolating within a bucket and are accurate to within a factor of two.
+type WaitHistogram struct {
+	counts [waitBuckets]uint64
+	count  uint64
+	sum    time.Duration
+	max    time.Duration
+}
+
+func (h *WaitHistogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.counts[waitBucket(d)]++
+	h.count++
+	h.sum += d
+	if d > h.max {
+		h.max = d
+	}
+}
+
+func waitBucket(d time.Duration) int {
+	i := bits.Len64(uint64(d / time.Microsecond))
+	if i >= waitBuckets {
+		return waitBuckets - 1
+	}
+	return i
+}
+
+//waitBucketBounds returns the lower and upper bounds of bucket i.
+func waitBucketBounds(i int) (time.Duration, time.Duration) {
+	if i == 0 {
+		return 0, time.Microsecond
+	}
+	return time.Duration(1<<uint(i-1)) * time.Microsecond, time.Duration(1<<uint(i)) * time.Microsecond
+}
+
+//Count returns the number of waits in h.
+func (h WaitHistogram) Count() uint64 {
+	return h.count
+}
+
+//Mean returns the average wait in h, or zero if h is empty.
+func (h WaitHistogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.count)
+}
+
+//Max returns the longest wait in h.
+func (h WaitHistogram) Max() time.Duration {
+	return h.max
+}
+
+//Percentile returns an estimate of the wait that p percent of the waits in h
+//are no longer than, such as h.Percentile(99) for the 99th percentile.
+//It returns zero if h is empty.
+func (h WaitHistogram) Percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := math.Ceil(p / 100 * float64(h.count))
+	if rank < 1 {
+		rank = 1
+	}
+
+	var before float64
+	for i, count := range h.counts {
+		if count == 0 || before+float64(count) < rank {
+			before += float64(count)
+			continue
+		}
+
+		lower, upper := waitBucketBounds(i)
+		if upper > h.max || i == waitBuckets-1 {
+			upper = h.max
+		}
+		if lower > upper {
+			lower = upper
+		}
+		fraction := (rank - before) / float64(count)
+		return lower + time.Duration(fraction*float64(upper-lower))
+	}
+	return h.max
+}
+
+//Histogram receives how long each value released by a Limiter waited, so that
+//the waits can be recorded by a metrics system of the caller's choosing.
+type Histogram interface {
+	Observe(wait time.Duration)
+}
+
+//WithHistogram makes a Limiter report how long each value it releases waited to
+//h, in addition to counting it in the WaitHistogram of its Stats.
+//The methods of h are called while the Limiter is locked, as with an Observer.
+func WithHistogram(h Histogram) Option {
+	return WithObserver(histogramObserver{h})
+}
+
+type histogramObserver struct {
+	h Histogram
+}
+
+func (o histogramObserver) Pushed(value interface{}) {}
+
+func (o histogramObserver) Popped(value interface{}, wait time.Duration) {
+	o.h.Observe(wait)
+}
+
+func (o histogramObserver) Dropped(value interface{}) {}
+
+func (o histogramObserver) Closed() {}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaitHistogram_Percentile_estimatesWithinABucket(t *testing.T) {
+	h := WaitHistogram{}
+	for i := 0; i < 99; i++ {
+		h.record(time.Millisecond)
+	}
+	h.record(time.Second)
+
+	if p := h.Percentile(50); p < time.Millisecond/2 || p > 2*time.Millisecond {
+		t.Error(p)
+	}
+	if p := h.Percentile(100); p != time.Second {
+		t.Error(p)
+	}
+	if h.Count() != 100 || h.Max() != time.Second {
+		t.Error(h.Count(), h.Max())
+	}
+	if mean := h.Mean(); mean != (99*time.Millisecond+time.Second)/100 {
+		t.Error(mean)
+	}
+}
+
+func TestWaitHistogram_isZeroWhenEmpty(t *testing.T) {
+	h := WaitHistogram{}
+
+	if h.Percentile(99) != 0 || h.Mean() != 0 || h.Max() != 0 {
+		t.Fail()
+	}
+}
+
+func TestWaitHistogram_Percentile_neverExceedsTheMax(t *testing.T) {
+	h := WaitHistogram{}
+	h.record(time.Duration(1) * time.Hour)
+
+	if p := h.Percentile(99); p > time.Duration(1)*time.Hour {
+		t.Fatal(p)
+	}
+	h.record(time.Duration(1000) * time.Hour)
+	if p := h.Percentile(100); p != time.Duration(1000)*time.Hour {
+		t.Fatal(p)
+	}
+}
+
+type waitsHistogram []time.Duration
+
+func (h *waitsHistogram) Observe(wait time.Duration) {
+	*h = append(*h, wait)
+}
+
+func TestWithHistogram_reportsWaits(t *testing.T) {
+	d := time.Duration(10) * time.Millisecond
+	h := &waitsHistogram{}
+	l := New(d, WithCapacity(Unbounded), WithHistogram(h))
+	l.PushAll(1, 2)
+	l.Pop()
+	l.Pop()
+
+	if len(*h) != 2 || (*h)[1] < d/2 {
+		t.Fatal(*h)
+	}
+	if waits := l.Stats().Waits; waits.Count() != 2 || waits.Max() < d/2 {
+		t.Fatal(waits)
+	}
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import "time"
+
+//Event describes a value at one of the events of a Limiter passed to Hooks.
+type Event struct {
+	//Value is the value of the event. It is nil for OnClose.
+	Value interface{}
+
+	//Pushed is when Value was pushed. It is the zero time for OnFull and
+	//OnClose.
+	Pushed time.Time
+
+	//Time is when the event happened.
+	Time time.Time
+
+	//Wait is how long Value was queued before the event, which is zero for
+	//OnPush, OnFull, and OnClose.
+	Wait time.Duration
+}
+
+func newEvent(it item, now time.Time) Event {
+	return Event{
+		Value:  it.value,
+		Pushed: it.pushed,
+		Time:   now,
+		Wait:   now.Sub(it.pushed),
+	}
+}
+
+//Hooks are callbacks for the events of a Limiter, registered with WithHooks.
+//Any of them may be nil.
+//
+//Like the methods of an Observer, the hooks are called while the Limiter is
+//locked, so they must be fast and must not use the Limiter.
+type Hooks struct {
+	//OnPush is called when a value is pushed.
+	OnPush func(e Event)
+
+	//OnPop is called when a value is released.
+	OnPop func(e Event)
+
+	//OnDrop is called when a value is removed without being released by
+	//CloseDiscard, Drain, Reset, Item.Cancel, the Overflow policy of the
+	//Limiter, or because it expired.
+	OnDrop func(e Event)
+
+	//OnFull is called when a value is pushed while the Limiter is at capacity,
+	//once for each push.
+	OnFull func(e Event)
+
+	//OnClose is called when the Limiter is closed.
+	OnClose func(e Event)
+}
+
+//WithHooks adds h to the Hooks called for the events of a Limiter.
+func WithHooks(h Hooks) Option {
+	return func(l *Limiter) {
+		l.hooks = append(l.hooks, h)
+	}
+}
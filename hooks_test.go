@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithHooks_callsTheHooksWithEvents(t *testing.T) {
+	d := time.Duration(10) * time.Millisecond
+	pushes, pops, drops, closes := []Event{}, []Event{}, []Event{}, []Event{}
+	l := New(d, WithCapacity(Unbounded), WithHooks(Hooks{
+		OnPush:  func(e Event) { pushes = append(pushes, e) },
+		OnPop:   func(e Event) { pops = append(pops, e) },
+		OnDrop:  func(e Event) { drops = append(drops, e) },
+		OnClose: func(e Event) { closes = append(closes, e) },
+	}))
+
+	l.PushAll(1, 2, 3)
+	l.Pop()
+	l.Pop()
+	l.CloseDiscard()
+
+	if len(pushes) != 3 || len(pops) != 2 || len(drops) != 1 || len(closes) != 1 {
+		t.Fatal(pushes, pops, drops, closes)
+	}
+	if pushes[0].Value != 1 || pushes[0].Pushed.IsZero() || pushes[0].Wait != 0 {
+		t.Fatal(pushes[0])
+	}
+	if pops[1].Value != 2 || pops[1].Wait < d/2 || !pops[1].Time.Equal(pops[1].Pushed.Add(pops[1].Wait)) {
+		t.Fatal(pops[1])
+	}
+	if drops[0].Value != 3 || closes[0].Value != nil || closes[0].Time.IsZero() {
+		t.Fatal(drops[0], closes[0])
+	}
+}
+
+func TestWithHooks_allowsNilHooks(t *testing.T) {
+	l := New(time.Duration(1), WithCapacity(Unbounded), WithHooks(Hooks{}))
+	l.PushAll(1, 2)
+	l.Pop()
+
+	if values := l.Drain(); len(values) != 1 || values[0] != 2 {
+		t.Fatal(values)
+	}
+}
+
+func TestWithHooks_callsOnFullOncePerPush(t *testing.T) {
+	fulls := []Event{}
+	l := New(time.Duration(10)*time.Millisecond, WithHooks(Hooks{
+		OnFull: func(e Event) { fulls = append(fulls, e) },
+	}))
+	l.Push(1)
+
+	l.TryPush(2)
+	l.TryPushAll(3, 4)
+	l.PushTimeout(5, time.Duration(30)*time.Millisecond)
+
+	if len(fulls) != 3 || fulls[0].Value != 2 || fulls[1].Value != 3 || fulls[2].Value != 5 {
+		t.Fatal(fulls)
+	}
+}
@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+//DefaultThrottleWindow is the sliding window over which a KeyedLimiter tracks
+//throttled keys for TopThrottled unless SetThrottleWindow is used.
+const DefaultThrottleWindow = time.Minute
+
+//throttleSlots is the number of slots a throttleTracker divides its window into.
+const throttleSlots = 10
+
+//ThrottledKey is a key of a KeyedLimiter and the number of times it was
+//throttled within the throttle window.
+type ThrottledKey struct {
+	Key   string
+	Count uint64
+}
+
+//throttleTracker counts the throttled pops of keys over a sliding window.
+//The window is divided into slots so that each key uses a fixed amount of
+//memory no matter how often it is throttled.
+type throttleTracker struct {
+	lock *sync.Mutex
+
+	window time.Duration
+	keys   map[string]*throttleCounts
+
+	pruned time.Time
+}
+
+//throttleCounts holds the count of throttled pops of a key in each slot, along
+//with the slot number each count belongs to.
+type throttleCounts struct {
+	counts [throttleSlots]uint64
+	slots  [throttleSlots]int64
+}
+
+func newThrottleTracker(window time.Duration) *throttleTracker {
+	return &throttleTracker{
+		lock:   &sync.Mutex{},
+		window: window,
+		keys:   map[string]*throttleCounts{},
+	}
+}
+
+//setWindow changes the window of t, forgetting everything counted so far.
+func (t *throttleTracker) setWindow(window time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.window = window
+	t.keys = map[string]*throttleCounts{}
+}
+
+//record counts a throttled pop of key at now.
+func (t *throttleTracker) record(key string, now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if now.Sub(t.pruned) >= t.window {
+		t.pruneLocked(now)
+		t.pruned = now
+	}
+
+	c, ok := t.keys[key]
+	if !ok {
+		c = &throttleCounts{}
+		t.keys[key] = c
+	}
+
+	slot := t.slotLocked(now)
+	i := slot % throttleSlots
+	if c.slots[i] != slot {
+		c.slots[i] = slot
+		c.counts[i] = 0
+	}
+	c.counts[i]++
+}
+
+//top returns up to n keys with the most throttled pops within the window ending
+//at now, from most to least throttled.
+func (t *throttleTracker) top(n int, now time.Time) []ThrottledKey {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.pruneLocked(now)
+
+	top := make([]ThrottledKey, 0, len(t.keys))
+	for key, c := range t.keys {
+		top = append(top, ThrottledKey{Key: key, Count: t.totalLocked(c, now)})
+	}
+	sort.Sort(byThrottles(top))
+
+	if n < len(top) {
+		top = top[:n]
+	}
+	return top
+}
+
+//pruneLocked forgets the keys of t that were not throttled within the window
+//ending at now.
+func (t *throttleTracker) pruneLocked(now time.Time) {
+	for key, c := range t.keys {
+		if t.totalLocked(c, now) == 0 {
+			delete(t.keys, key)
+		}
+	}
+}
+
+func (t *throttleTracker) totalLocked(c *throttleCounts, now time.Time) uint64 {
+	oldest := t.slotLocked(now) - throttleSlots
+	total := uint64(0)
+	for i, slot := range c.slots {
+		if slot > oldest {
+			total += c.counts[i]
+		}
+	}
+	return total
+}
+
+//slotLocked returns the number of the slot containing now.
+func (t *throttleTracker) slotLocked(now time.Time) int64 {
+	width := int64(t.window) / throttleSlots
+	if width <= 0 {
+		width = 1
+	}
+	return now.UnixNano() / width
+}
+
+//byThrottles sorts ThrottledKeys from most to least throttled, and then by key.
+type byThrottles []ThrottledKey
+
+func (b byThrottles) Len() int {
+	return len(b)
+}
+
+func (b byThrottles) Less(i, j int) bool {
+	if b[i].Count != b[j].Count {
+		return b[i].Count > b[j].Count
+	}
+	return b[i].Key < b[j].Key
+}
+
+func (b byThrottles) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottleTracker_top_ordersKeysByCount(t *testing.T) {
+	tr := newThrottleTracker(time.Minute)
+	now := time.Now()
+	for _, key := range []string{"a", "b", "b", "c", "c", "c", "d"} {
+		tr.record(key, now)
+	}
+
+	top := tr.top(3, now)
+
+	if len(top) != 3 || top[0] != (ThrottledKey{"c", 3}) || top[1] != (ThrottledKey{"b", 2}) || top[2] != (ThrottledKey{"a", 1}) {
+		t.Fatal(top)
+	}
+}
+
+func TestThrottleTracker_top_forgetsCountsOutsideTheWindow(t *testing.T) {
+	tr := newThrottleTracker(time.Minute)
+	now := time.Now()
+	tr.record("a", now)
+	tr.record("a", now.Add(30*time.Second))
+
+	if top := tr.top(1, now.Add(time.Minute)); len(top) != 1 || top[0].Count != 1 {
+		t.Fatal(top)
+	}
+	if top := tr.top(1, now.Add(2*time.Minute)); len(top) != 0 || len(tr.keys) != 0 {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_TopThrottled_reportsThrottledPops(t *testing.T) {
+	k := NewKeyed(time.Duration(1)*time.Hour, WithCapacity(2))
+	for _, key := range []string{"a", "b"} {
+		k.PushKey(key, 1)
+		k.PushKey(key, 2)
+		k.PopKey(key)
+	}
+	k.TryPopKey("a")
+	k.TryPopKey("a")
+	k.TryPopKey("b")
+
+	top := k.TopThrottled(10)
+
+	if len(top) != 2 || top[0] != (ThrottledKey{"a", 2}) || top[1] != (ThrottledKey{"b", 1}) {
+		t.Fatal(top)
+	}
+}
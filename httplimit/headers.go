@@ -0,0 +1,62 @@
+package httplimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//setHeaders sets the rate limit headers of a response from the state of t.
+//
+//The quota is counted over a window of the throughput duration of t rounded up
+//to whole seconds. X-RateLimit-Limit and RateLimit-Limit are the number of
+//requests released per window, X-RateLimit-Remaining and RateLimit-Remaining are
+//the number of further requests that may still be released within the next
+//window, X-RateLimit-Reset is the Unix time in seconds at which the requests
+//queued now have all been released, and RateLimit-Reset is the number of
+//seconds until then.
+func setHeaders(w http.ResponseWriter, t target) {
+	limit, remaining, reset, ok := t.state()
+	if !ok {
+		return
+	}
+
+	secs := ceilSeconds(reset)
+	h := w.Header()
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		h.Set(prefix+"Limit", strconv.Itoa(limit))
+		h.Set(prefix+"Remaining", strconv.Itoa(remaining))
+	}
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Duration(secs)*time.Second).Unix(), 10))
+	h.Set("RateLimit-Reset", strconv.Itoa(secs))
+}
+
+//quotaState returns the quota of a queue that holds n requests and releases one
+//request every d after an initial delay.
+//ok is false if d is not positive.
+func quotaState(n int, delay, d time.Duration) (limit, remaining int, reset time.Duration, ok bool) {
+	if d <= 0 {
+		return 0, 0, 0, false
+	}
+	window := time.Duration(ceilSeconds(d)) * time.Second
+	limit = int(window / d)
+
+	//Releases happen at delay, delay+d, ..., and the first n of those within
+	//the window are taken by the queued requests.
+	if delay < window {
+		remaining = int((window-delay+d-1)/d) - n
+	}
+	if remaining < 0 {
+		remaining = 0
+	}
+	if n > 0 {
+		reset = delay + time.Duration(n-1)*d
+	}
+	return limit, remaining, reset, true
+}
+
+//ceilSeconds returns d rounded up to whole seconds.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
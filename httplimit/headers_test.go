@@ -0,0 +1,70 @@
+package httplimit
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+func TestMiddleware_setsRateLimitHeaders(t *testing.T) {
+	l := ratelimit.New(time.Duration(30)*time.Second, ratelimit.WithCapacity(2))
+	l.Wait()
+	h := Middleware(l)(okHandler())
+	for i := 0; i < 2; i++ {
+		go h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	time.Sleep(time.Duration(10) * time.Millisecond)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	header := w.Header()
+	if header.Get("X-RateLimit-Limit") != "1" || header.Get("RateLimit-Limit") != "1" {
+		t.Fail()
+	}
+	if header.Get("X-RateLimit-Remaining") != "0" || header.Get("RateLimit-Remaining") != "0" {
+		t.Fail()
+	}
+	if header.Get("RateLimit-Reset") != "60" {
+		t.Fatal(header.Get("RateLimit-Reset"))
+	}
+	reset, _ := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if until := time.Unix(reset, 0).Sub(time.Now()); until < 58*time.Second || until > 61*time.Second {
+		t.Fatal(until)
+	}
+}
+
+func TestMiddleware_reportsTheQuotaOfTheRate(t *testing.T) {
+	l := ratelimit.New(time.Duration(100)*time.Millisecond, ratelimit.WithCapacity(ratelimit.Unbounded))
+	h := Middleware(l)(okHandler())
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	header := w.Header()
+	if header.Get("RateLimit-Limit") != "10" || header.Get("RateLimit-Remaining") != "9" {
+		t.Fatal(header.Get("RateLimit-Limit"), header.Get("RateLimit-Remaining"))
+	}
+}
+
+func TestQuotaState_countsTheReleasesLeftWithinTheWindow(t *testing.T) {
+	d := time.Duration(100) * time.Millisecond
+	if limit, remaining, reset, ok := quotaState(0, 0, d); limit != 10 || remaining != 10 || reset != 0 || !ok {
+		t.Fatal(limit, remaining, reset, ok)
+	}
+	if _, remaining, reset, _ := quotaState(3, 5*d, d); remaining != 2 || reset != 7*d {
+		t.Fatal(remaining, reset)
+	}
+	if _, remaining, _, _ := quotaState(0, time.Second, d); remaining != 0 {
+		t.Fatal(remaining)
+	}
+	if limit, remaining, _, _ := quotaState(1, 0, time.Duration(90)*time.Second); limit != 1 || remaining != 0 {
+		t.Fatal(limit, remaining)
+	}
+	if _, _, _, ok := quotaState(0, 0, 0); ok {
+		t.Fail()
+	}
+}
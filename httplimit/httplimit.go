@@ -0,0 +1,124 @@
+//Package httplimit provides net/http middleware that rate limits requests with
+//a ratelimit.Limiter.
+package httplimit
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+//Middleware returns middleware that rate limits the requests to the handler it
+//wraps with l.
+//
+//Each request is queued in l and is served once l releases it, so requests are
+//served no faster than the rate of l. The capacity of l bounds the number of
+//requests waiting to be served. A request that arrives while l is full is
+//rejected with 429 Too Many Requests and a Retry-After header giving the number
+//of seconds until l is expected to have space. A request that arrives after l is
+//closed is rejected with 503 Service Unavailable.
+//
+//A request whose client goes away while it is queued is not served, but still
+//uses its release when it comes up.
+//l should only be used by the returned middleware to queue requests, but is
+//attached to the context of each request served with ratelimit.NewContext so that
+//handlers may pace follow-up calls with the same budget.
+func Middleware(l *ratelimit.Limiter) func(http.Handler) http.Handler {
+	go func() {
+		for v, ok := l.PopOk(); ok; v, ok = l.PopOk() {
+			close(v.(chan struct{}))
+		}
+	}()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			serve(w, r.WithContext(ratelimit.NewContext(r.Context(), l)), next, limiterTarget{l})
+		})
+	}
+}
+
+//target is what requests are queued in by the middleware of this package.
+type target interface {
+	//push queues value without blocking.
+	push(value interface{}) (accepted bool, err error)
+
+	//retryAfter returns how long a rejected client should wait before trying
+	//again.
+	retryAfter() time.Duration
+
+	//state returns the number of requests released per window, the number
+	//that may still be released within the next window, and the time until
+	//the queue is empty. See setHeaders.
+	//ok is false if there is no such state.
+	state() (limit, remaining int, reset time.Duration, ok bool)
+}
+
+//serve queues r in t and serves it with next once it is released.
+//If t does not accept r, then the request is rejected.
+func serve(w http.ResponseWriter, r *http.Request, next http.Handler, t target) {
+	ticket := make(chan struct{})
+	accepted, err := t.push(ticket)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	setHeaders(w, t)
+	if !accepted {
+		tooManyRequests(w, t.retryAfter())
+		return
+	}
+
+	select {
+	case <-ticket:
+		next.ServeHTTP(w, r)
+	case <-r.Context().Done():
+	}
+}
+
+//limiterTarget is the target of Middleware.
+type limiterTarget struct {
+	l *ratelimit.Limiter
+}
+
+func (t limiterTarget) push(value interface{}) (bool, error) {
+	return t.l.TryPush(value)
+}
+
+func (t limiterTarget) retryAfter() time.Duration {
+	return RetryAfter(t.l)
+}
+
+func (t limiterTarget) state() (limit, remaining int, reset time.Duration, ok bool) {
+	return quotaState(t.l.Len(), t.l.Delay(), t.l.Duration())
+}
+
+//RetryAfter returns how long a client rejected by l should wait before trying
+//again, which is the time until l next releases a value and has space for
+//another.
+func RetryAfter(l *ratelimit.Limiter) time.Duration {
+	if delay := l.Delay(); delay > 0 {
+		return delay
+	}
+	return l.Duration()
+}
+
+//tooManyRequests responds to a rejected request with 429 Too Many Requests and a
+//Retry-After header of retryAfter rounded up to whole seconds.
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(seconds(retryAfter)))
+	http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+}
+
+//seconds returns d rounded up to whole seconds, and at least one.
+//
+//Retry-After must be at least one second, so that clients do not retry
+//immediately.
+func seconds(d time.Duration) int {
+	s := ceilSeconds(d)
+	if s < 1 {
+		return 1
+	}
+	return s
+}
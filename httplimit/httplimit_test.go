@@ -0,0 +1,77 @@
+package httplimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestMiddleware_servesRequestsReleasedByTheLimiter(t *testing.T) {
+	h := Middleware(ratelimit.New(time.Duration(1), ratelimit.WithCapacity(2)))(okHandler())
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != http.StatusOK {
+			t.Fail()
+		}
+	}
+}
+
+func TestMiddleware_rejectsRequestsWhenTheLimiterIsFull(t *testing.T) {
+	l := ratelimit.New(time.Duration(90)*time.Second, ratelimit.WithCapacity(1))
+	l.Wait()
+	h := Middleware(l)(okHandler())
+	go h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	time.Sleep(time.Duration(10) * time.Millisecond)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "90" {
+		t.Fatal(w.Code, w.Header())
+	}
+}
+
+func TestMiddleware_rejectsRequestsWhenTheLimiterIsClosed(t *testing.T) {
+	l := ratelimit.New(time.Duration(1))
+	h := Middleware(l)(okHandler())
+	l.Close()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fail()
+	}
+}
+
+func TestSeconds_roundsUpToAtLeastOne(t *testing.T) {
+	if seconds(0) != 1 || seconds(time.Duration(1500)*time.Millisecond) != 2 || seconds(time.Duration(2)*time.Second) != 2 {
+		t.Fail()
+	}
+}
+
+func TestMiddleware_attachesTheLimiterToTheRequestContext(t *testing.T) {
+	l := ratelimit.New(time.Duration(1))
+	attached := false
+	h := Middleware(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := ratelimit.FromContext(r.Context())
+		attached = ok && got == l
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if !attached {
+		t.Fail()
+	}
+}
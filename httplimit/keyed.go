@@ -0,0 +1,147 @@
+package httplimit
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+//KeyFunc extracts the key a request is rate limited by, such as the IP address
+//of its client or its API key.
+type KeyFunc func(r *http.Request) string
+
+//KeyedMiddleware returns middleware that rate limits the requests to the handler
+//it wraps with the Limiter of k for the key of each request, so that each client
+//is limited independently.
+//It otherwise works just like Middleware.
+//
+//Requests of different keys are served in round-robin order using
+//KeyedLimiter.PopAny, so k should only be used by the returned middleware.
+func KeyedMiddleware(k *ratelimit.KeyedLimiter, key KeyFunc) func(http.Handler) http.Handler {
+	go func() {
+		for _, v, ok := k.PopAny(); ok; _, v, ok = k.PopAny() {
+			close(v.(chan struct{}))
+		}
+	}()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			serve(w, r, next, keyTarget{k, key(r)})
+		})
+	}
+}
+
+//keyTarget is the target of KeyedMiddleware for a single key.
+type keyTarget struct {
+	k   *ratelimit.KeyedLimiter
+	key string
+}
+
+func (t keyTarget) push(value interface{}) (bool, error) {
+	return t.k.TryPushKey(t.key, value)
+}
+
+func (t keyTarget) retryAfter() time.Duration {
+	return KeyedRetryAfter(t.k, t.key)
+}
+
+func (t keyTarget) state() (limit, remaining int, reset time.Duration, ok bool) {
+	stats, ok := t.k.Stats(t.key)
+	if !ok {
+		return 0, 0, 0, false
+	}
+	return quotaState(stats.Len, t.k.Delay(t.key), t.k.Duration(t.key))
+}
+
+//KeyedRetryAfter returns how long a client of key rejected by k should wait
+//before trying again.
+func KeyedRetryAfter(k *ratelimit.KeyedLimiter, key string) time.Duration {
+	if delay := k.Delay(key); delay > 0 {
+		return delay
+	}
+	return k.Duration(key)
+}
+
+//RemoteAddr is a KeyFunc that returns the IP address of the client connected
+//to the server, without its port.
+//Behind a proxy or load balancer this is the address of the proxy, so use
+//ForwardedFor instead.
+func RemoteAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+//Header returns a KeyFunc that returns the value of the header name, such as an
+//API key header.
+func Header(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+//ForwardedFor returns a KeyFunc that returns the IP address of the client of a
+//request that may have passed through trusted proxies.
+//Each of trusted is an IP address or a CIDR range such as "10.0.0.0/8".
+//
+//When the request comes from a trusted proxy, the X-Forwarded-For header is
+//read from right to left, skipping addresses of trusted proxies, and the first
+//untrusted address is the client. Addresses added by untrusted clients are
+//never used, so the client cannot choose its own key.
+//err is not nil if any of trusted cannot be parsed.
+func ForwardedFor(trusted ...string) (KeyFunc, error) {
+	nets := make([]*net.IPNet, 0, len(trusted))
+	for _, t := range trusted {
+		if !strings.Contains(t, "/") {
+			if ip := net.ParseIP(t); ip != nil && ip.To4() != nil {
+				t += "/32"
+			} else {
+				t += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(t)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+
+	isTrusted := func(addr string) bool {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return false
+		}
+		for _, n := range nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(r *http.Request) string {
+		client := RemoteAddr(r)
+		if !isTrusted(client) {
+			return client
+		}
+
+		hops := []string{}
+		for _, header := range r.Header[http.CanonicalHeaderKey("X-Forwarded-For")] {
+			for _, hop := range strings.Split(header, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+		for i := len(hops) - 1; i >= 0; i-- {
+			client = hops[i]
+			if !isTrusted(client) {
+				break
+			}
+		}
+		return client
+	}, nil
+}
@@ -0,0 +1,76 @@
+package httplimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+func TestKeyedMiddleware_limitsEachKeyIndependently(t *testing.T) {
+	k := ratelimit.NewKeyed(time.Duration(1) * time.Hour)
+	h := KeyedMiddleware(k, Header("X-Api-Key"))(okHandler())
+	request := func(key string) int {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-Api-Key", key)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if request("a") != http.StatusOK || request("b") != http.StatusOK {
+		t.Fail()
+	}
+
+	go request("a")
+	time.Sleep(time.Duration(10) * time.Millisecond)
+	if request("a") != http.StatusTooManyRequests {
+		t.Fail()
+	}
+}
+
+func TestRemoteAddr_stripsThePort(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+
+	if RemoteAddr(r) != "192.0.2.1" {
+		t.Fail()
+	}
+}
+
+func TestForwardedFor_skipsTrustedProxies(t *testing.T) {
+	key, err := ForwardedFor("10.0.0.0/8", "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		remote    string
+		forwarded []string
+		want      string
+	}{
+		{"203.0.113.9:1", []string{"198.51.100.1"}, "203.0.113.9"},
+		{"192.0.2.1:1", []string{"198.51.100.1, 10.1.1.1"}, "198.51.100.1"},
+		{"10.0.0.1:1", []string{"6.6.6.6, 198.51.100.1", "10.2.2.2"}, "198.51.100.1"},
+		{"10.0.0.1:1", []string{"10.2.2.2"}, "10.2.2.2"},
+		{"10.0.0.1:1", nil, "10.0.0.1"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = c.remote
+		for _, f := range c.forwarded {
+			r.Header.Add("X-Forwarded-For", f)
+		}
+		if got := key(r); got != c.want {
+			t.Error(c.remote, c.forwarded, got)
+		}
+	}
+}
+
+func TestForwardedFor_returnsErrorForInvalidRanges(t *testing.T) {
+	if _, err := ForwardedFor("not an ip"); err == nil {
+		t.Fail()
+	}
+}
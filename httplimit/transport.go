@@ -0,0 +1,135 @@
+package httplimit
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+//Transport is an http.RoundTripper that paces the requests made through it with
+//a Limiter.
+//
+//When a server responds with 429 Too Many Requests, or 503 Service Unavailable
+//with a Retry-After header, Transport pauses its Limiter until the time the
+//server asked for, so that no further requests are made until then. It can
+//optionally retry such requests itself.
+type Transport struct {
+	//Base is the RoundTripper that makes requests. http.DefaultTransport is used
+	//if Base is nil.
+	Base http.RoundTripper
+
+	//Limiter paces the requests made through the Transport.
+	Limiter *ratelimit.Limiter
+
+	//MaxRetries is the number of times a request rejected by the server is
+	//retried after the pause it asked for. Requests whose bodies cannot be
+	//replayed with GetBody are never retried.
+	MaxRetries int
+
+	//DefaultRetryAfter is the pause used for a 429 response without a valid
+	//Retry-After header. It defaults to the throughput duration of Limiter.
+	DefaultRetryAfter time.Duration
+}
+
+//NewTransport creates a Transport that paces the requests made with base by l.
+func NewTransport(base http.RoundTripper, l *ratelimit.Limiter) *Transport {
+	return &Transport{
+		Base:    base,
+		Limiter: l,
+	}
+}
+
+//RoundTrip waits for the Limiter of t to permit a release and then makes req
+//with the Base of t, pausing and retrying as described for Transport.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.Limiter.WaitContext(req.Context()); err != nil {
+			return nil, err
+		}
+
+		resp, err := base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		pause, ok := t.retryAfter(resp)
+		if !ok {
+			return resp, nil
+		}
+		t.Limiter.PauseUntil(time.Now().Add(pause))
+
+		if attempt >= t.MaxRetries || !rewindable(req) {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = cloneWithBody(req, body)
+		}
+	}
+}
+
+//retryAfter returns how long the server of resp asked its clients to pause.
+//ok is false if resp does not ask for a pause.
+func (t *Transport) retryAfter(resp *http.Response) (pause time.Duration, ok bool) {
+	header := resp.Header.Get("Retry-After")
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+	case resp.StatusCode == http.StatusServiceUnavailable && header != "":
+	default:
+		return 0, false
+	}
+
+	if pause, ok := parseRetryAfter(header, time.Now()); ok {
+		return pause, true
+	}
+	if t.DefaultRetryAfter > 0 {
+		return t.DefaultRetryAfter, true
+	}
+	return t.Limiter.Duration(), true
+}
+
+//parseRetryAfter parses the value of a Retry-After header, which is either a
+//number of seconds or an HTTP date, into the pause after now it asks for.
+func parseRetryAfter(header string, now time.Time) (pause time.Duration, ok bool) {
+	if header == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		if pause = date.Sub(now); pause < 0 {
+			pause = 0
+		}
+		return pause, true
+	}
+	return 0, false
+}
+
+//rewindable reports whether req can be sent again.
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+//cloneWithBody returns a shallow copy of req with body.
+func cloneWithBody(req *http.Request, body io.ReadCloser) *http.Request {
+	clone := req.WithContext(req.Context())
+	clone.Body = body
+	return clone
+}
@@ -0,0 +1,108 @@
+package httplimit
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+//fakeTransport is an http.RoundTripper that responds with the next of its
+//statuses.
+type fakeTransport struct {
+	statuses []int
+	header   string
+	bodies   []string
+}
+
+func (f *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		b, _ := ioutil.ReadAll(req.Body)
+		f.bodies = append(f.bodies, string(b))
+	}
+
+	status := f.statuses[0]
+	f.statuses = f.statuses[1:]
+	resp := &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}
+	if f.header != "" {
+		resp.Header.Set("Retry-After", f.header)
+	}
+	return resp, nil
+}
+
+func TestTransport_RoundTrip_pausesTheLimiterOnTooManyRequests(t *testing.T) {
+	l := ratelimit.New(time.Duration(1))
+	tr := NewTransport(&fakeTransport{statuses: []int{429}, header: "120"}, l)
+
+	resp, err := tr.RoundTrip(httptest.NewRequest("GET", "http://example.com/", nil))
+
+	if err != nil || resp.StatusCode != 429 {
+		t.Fatal(resp, err)
+	}
+	if d := l.Delay(); d <= 119*time.Second || d > 120*time.Second {
+		t.Fatal(d)
+	}
+}
+
+func TestTransport_RoundTrip_ignoresServiceUnavailableWithoutRetryAfter(t *testing.T) {
+	l := ratelimit.New(time.Duration(1))
+	tr := NewTransport(&fakeTransport{statuses: []int{503}}, l)
+
+	tr.RoundTrip(httptest.NewRequest("GET", "http://example.com/", nil))
+
+	if l.Delay() > 0 {
+		t.Fail()
+	}
+}
+
+func TestTransport_RoundTrip_retriesReplayableRequests(t *testing.T) {
+	f := &fakeTransport{statuses: []int{503, 429, 200}, header: "0"}
+	tr := NewTransport(f, ratelimit.New(time.Duration(1)))
+	tr.MaxRetries = 2
+	req, _ := http.NewRequest("POST", "http://example.com/", strings.NewReader("body"))
+
+	resp, err := tr.RoundTrip(req)
+
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatal(resp, err)
+	}
+	if len(f.bodies) != 3 || f.bodies[2] != "body" {
+		t.Fatal(f.bodies)
+	}
+}
+
+func TestTransport_RoundTrip_stopsAfterMaxRetries(t *testing.T) {
+	f := &fakeTransport{statuses: []int{429, 429, 200}, header: "0"}
+	tr := NewTransport(f, ratelimit.New(time.Duration(1)))
+	tr.MaxRetries = 1
+
+	resp, _ := tr.RoundTrip(httptest.NewRequest("GET", "http://example.com/", nil))
+
+	if resp.StatusCode != 429 || len(f.statuses) != 1 {
+		t.Fail()
+	}
+}
+
+func TestParseRetryAfter_parsesSecondsAndDates(t *testing.T) {
+	now := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	if pause, ok := parseRetryAfter("30", now); pause != 30*time.Second || !ok {
+		t.Fail()
+	}
+	if pause, ok := parseRetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now); pause != time.Minute || !ok {
+		t.Fail()
+	}
+	for _, header := range []string{"", "-1", "soon"} {
+		if _, ok := parseRetryAfter(header, now); ok {
+			t.Error(header)
+		}
+	}
+}
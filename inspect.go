@@ -0,0 +1,27 @@
+package ratelimit
+
+//Snapshot returns a copy of the values currently queued in l, in the order they
+//would be released.
+//It does not remove any values or count as any releases, so it is safe to use
+//for debugging and admin endpoints while l is in use.
+func (l *Limiter) Snapshot() []interface{} {
+	l.lock.Lock()
+	items := l.values.items()
+	l.lock.Unlock()
+
+	values := make([]interface{}, len(items))
+	for i, it := range items {
+		values[i] = it.value
+	}
+	return values
+}
+
+//Inspect calls fn with each value currently queued in l, in the order they
+//would be released, just like ranging over Snapshot.
+//fn is called without the lock of l held, so it may use l, but values pushed or
+//popped while Inspect runs are not reflected.
+func (l *Limiter) Inspect(fn func(value interface{})) {
+	for _, v := range l.Snapshot() {
+		fn(v)
+	}
+}
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Snapshot_returnsValuesInReleaseOrder(t *testing.T) {
+	l := New(time.Duration(1)*time.Hour, WithCapacity(Unbounded))
+	l.Push(1)
+	l.Push(2)
+	l.PushPriority(3, 1)
+	l.PushPriority(4, -1)
+
+	if values := l.Snapshot(); !reflect.DeepEqual(values, []interface{}{3, 1, 2, 4}) {
+		t.Fatal(values)
+	}
+	if l.Len() != 4 {
+		t.Fatal("snapshot should not remove values")
+	}
+}
+
+func TestLimiter_Snapshot_followsTheRingBuffer(t *testing.T) {
+	l := New(time.Duration(1), WithCapacity(3))
+	l.PushAll(1, 2, 3)
+	l.Pop()
+	l.Pop()
+	l.PushAll(4, 5)
+
+	if values := l.Snapshot(); !reflect.DeepEqual(values, []interface{}{3, 4, 5}) {
+		t.Fatal(values)
+	}
+}
+
+func TestLimiter_Inspect_callsFnWithEachValue(t *testing.T) {
+	l := New(time.Duration(1), WithCapacity(Unbounded))
+	l.PushAll(1, 2)
+
+	values := []interface{}{}
+	l.Inspect(func(v interface{}) {
+		values = append(values, v, l.Len())
+	})
+
+	if !reflect.DeepEqual(values, []interface{}{1, 2, 2, 2}) {
+		t.Fatal(values)
+	}
+}
@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//bandwidthChunks is the number of chunks a second of bandwidth is divided into
+//so that reads and writes are paced smoothly rather than in bursts of a
+//second's worth of bytes.
+const bandwidthChunks = 10
+
+//bandwidth paces bytes to a rate in bytes per second.
+//Bytes are counted in a bucket that holds up to a chunk of bytes and refills
+//continuously at the rate, so that any rate is paced exactly.
+type bandwidth struct {
+	lock *sync.Mutex
+
+	bytesPerSec int
+	chunk       int
+
+	//available is the number of bytes permitted as of last. It is negative
+	//while bytes permitted ahead of time are being paid back.
+	available float64
+	last      time.Time
+}
+
+//newBandwidth panics if bytesPerSec is less than one.
+func newBandwidth(bytesPerSec int) *bandwidth {
+	if bytesPerSec < 1 {
+		panic("ratelimit: invalid bandwidth of " + strconv.Itoa(bytesPerSec) + " bytes per second, must be at least 1")
+	}
+	chunk := bytesPerSec / bandwidthChunks
+	if chunk < 1 {
+		chunk = 1
+	}
+	return &bandwidth{
+		lock:        &sync.Mutex{},
+		bytesPerSec: bytesPerSec,
+		chunk:       chunk,
+		available:   float64(chunk),
+	}
+}
+
+//wait blocks until b permits n bytes.
+func (b *bandwidth) wait(n int) {
+	if delay := b.reserve(time.Now(), n); delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+//reserve counts n bytes at now and returns how long it will be before they are
+//permitted.
+func (b *bandwidth) reserve(now time.Time, n int) time.Duration {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if !b.last.IsZero() && now.After(b.last) {
+		b.available += now.Sub(b.last).Seconds() * float64(b.bytesPerSec)
+		if max := float64(b.chunk); b.available > max {
+			b.available = max
+		}
+	}
+	b.last = now
+
+	b.available -= float64(n)
+	if b.available >= 0 {
+		return 0
+	}
+	return time.Duration(-b.available / float64(b.bytesPerSec) * float64(time.Second))
+}
+
+//limit returns p cut down to the chunk size of b.
+func (b *bandwidth) limit(p []byte) []byte {
+	if len(p) > b.chunk {
+		return p[:b.chunk]
+	}
+	return p
+}
+
+type reader struct {
+	r io.Reader
+	b *bandwidth
+}
+
+//NewReader returns an io.Reader that reads from r no faster than bytesPerSec
+//bytes per second on average.
+//NewReader panics if bytesPerSec is less than one.
+//Each read is limited to a fraction of a second's worth of bytes so that the
+//throughput stays smooth, and returns only once the bytes read are permitted.
+func NewReader(r io.Reader, bytesPerSec int) io.Reader {
+	return &reader{
+		r: r,
+		b: newBandwidth(bytesPerSec),
+	}
+}
+
+func (r *reader) Read(p []byte) (n int, err error) {
+	n, err = r.r.Read(r.b.limit(p))
+	if n > 0 {
+		r.b.wait(n)
+	}
+	return n, err
+}
+
+type writer struct {
+	w io.Writer
+	b *bandwidth
+}
+
+//NewWriter returns an io.Writer that writes to w no faster than bytesPerSec
+//bytes per second on average.
+//NewWriter panics if bytesPerSec is less than one.
+//Large writes are split into chunks of a fraction of a second's worth of bytes
+//that are each written once they are permitted, so that the throughput stays
+//smooth.
+func NewWriter(w io.Writer, bytesPerSec int) io.Writer {
+	return &writer{
+		w: w,
+		b: newBandwidth(bytesPerSec),
+	}
+}
+
+func (w *writer) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		chunk := w.b.limit(p)
+		w.b.wait(len(chunk))
+
+		written, err := w.w.Write(chunk)
+		n += written
+		if err != nil {
+			return n, err
+		}
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestNewReader_limitsTheReadRate(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	r := NewReader(bytes.NewReader(make([]byte, 3000)), 10000)
+	start := time.Now()
+
+	b, err := ioutil.ReadAll(r)
+
+	elapsed := time.Since(start)
+	if len(b) != 3000 || err != nil {
+		t.Fail()
+	}
+	if elapsed < 150*time.Millisecond || elapsed > 500*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+}
+
+func TestNewReader_readsAtMostAChunk(t *testing.T) {
+	r := NewReader(bytes.NewReader(make([]byte, 100)), 200)
+
+	n, _ := r.Read(make([]byte, 100))
+
+	if n != 20 {
+		t.Fatal(n)
+	}
+}
+
+func TestNewWriter_writesEverythingInChunks(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	buffer := &bytes.Buffer{}
+	w := NewWriter(buffer, 10000)
+	start := time.Now()
+
+	n, err := w.Write(make([]byte, 3000))
+
+	elapsed := time.Since(start)
+	if n != 3000 || err != nil || buffer.Len() != 3000 {
+		t.Fail()
+	}
+	if elapsed < 150*time.Millisecond || elapsed > 500*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+}
+
+func TestNewBandwidth_usesAtLeastOneByteChunks(t *testing.T) {
+	b := newBandwidth(5)
+
+	if b.chunk != 1 || len(b.limit(make([]byte, 10))) != 1 {
+		t.Fail()
+	}
+}
+
+func TestBandwidth_reserve_pacesExactlyAtAnyRate(t *testing.T) {
+	for _, bytesPerSec := range []int{3, 1000003, 3000000000} {
+		b := newBandwidth(bytesPerSec)
+		now := time.Unix(0, 0)
+
+		if delay := b.reserve(now, b.chunk); delay != 0 {
+			t.Fatal(bytesPerSec, delay)
+		}
+		want := time.Duration(float64(b.chunk) / float64(bytesPerSec) * float64(time.Second))
+		if delay := b.reserve(now, b.chunk); delay != want {
+			t.Fatal(bytesPerSec, delay, want)
+		}
+		if delay := b.reserve(now.Add(2*want), b.chunk); delay != 0 {
+			t.Fatal(bytesPerSec, delay)
+		}
+	}
+}
+
+func TestNewReader_panicsForAnInvalidRate(t *testing.T) {
+	for _, bytesPerSec := range []int{0, -1} {
+		expectPanic(t, func() { NewReader(bytes.NewReader(nil), bytesPerSec) })
+		expectPanic(t, func() { NewWriter(&bytes.Buffer{}, bytesPerSec) })
+	}
+}
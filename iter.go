@@ -0,0 +1,20 @@
+//go:build go1.23
+
+package ratelimit
+
+import "iter"
+
+//All returns an iterator over the values of l as they are released.
+//Each value is popped just like with PopOk, and iteration ends once l is closed
+//and there are no more values to pop.
+//
+//Breaking out of the iteration does not pop any more values from l.
+func (l *Limiter) All() iter.Seq[interface{}] {
+	return func(yield func(interface{}) bool) {
+		for v, ok := l.PopOk(); ok; v, ok = l.PopOk() {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+//go:build go1.23
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_All_yieldsValuesUntilClosed(t *testing.T) {
+	rl := New(time.Duration(1))
+
+	go func() {
+		for i := 0; i < 10; i++ {
+			rl.Push(i)
+		}
+		rl.Close()
+	}()
+
+	want := 0
+	for v := range rl.All() {
+		if v != want {
+			t.Fail()
+		}
+		want++
+	}
+
+	if want != 10 {
+		t.Fail()
+	}
+}
+
+func TestLimiter_All_stopsPoppingOnBreak(t *testing.T) {
+	rl := NewCapacity(time.Duration(1), 3)
+	rl.PushAll(0, 1, 2)
+
+	for v := range rl.All() {
+		if v != 0 {
+			t.Fail()
+		}
+		break
+	}
+
+	if rl.Len() != 2 {
+		t.Fail()
+	}
+}
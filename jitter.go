@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//jitterPacer randomizes the release times permitted by another Pacer.
+//After each release, the next release time of pacer is shifted by a random
+//offset within ±fraction of d.
+type jitterPacer struct {
+	lock *sync.Mutex
+
+	pacer    Pacer
+	d        time.Duration
+	fraction float64
+	rand     *rand.Rand
+
+	offset time.Duration
+}
+
+func newJitterPacer(p Pacer, d time.Duration, fraction float64, src rand.Source) *jitterPacer {
+	if src == nil {
+		src = rand.NewSource(time.Now().UnixNano())
+	}
+	return &jitterPacer{
+		lock:     &sync.Mutex{},
+		pacer:    p,
+		d:        d,
+		fraction: fraction,
+		rand:     rand.New(src),
+	}
+}
+
+func (j *jitterPacer) AllowN(t time.Time, n int) bool {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if !j.pacer.AllowN(t.Add(-j.offset), n) {
+		return false
+	}
+
+	j.offset = time.Duration((2*j.rand.Float64() - 1) * j.fraction * float64(j.d))
+	return true
+}
+
+func (j *jitterPacer) DelayN(t time.Time, n int) time.Duration {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	return j.pacer.DelayN(t.Add(-j.offset), n)
+}
+
+func (j *jitterPacer) setDurationAt(t time.Time, d time.Duration) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	j.d = d
+	setPacerDuration(j.pacer, t.Add(-j.offset), d)
+}
+
+func (j *jitterPacer) Reset() {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	j.offset = 0
+	if resetter, ok := j.pacer.(resetter); ok {
+		resetter.Reset()
+	}
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestJitterPacer_shiftsReleaseTimesWithinFraction(t *testing.T) {
+	d := time.Duration(1) * time.Second
+	j := newJitterPacer(NewTokenBucket(d, 1), d, 0.5, rand.NewSource(1))
+	now := time.Now()
+
+	if !j.AllowN(now, 1) {
+		t.Fail()
+	}
+
+	delay := j.DelayN(now, 1)
+	if delay < d/2 || delay > 3*d/2 {
+		t.Fatal(delay)
+	}
+
+	if j.AllowN(now.Add(delay-1), 1) || !j.AllowN(now.Add(delay), 1) {
+		t.Fail()
+	}
+}
+
+func TestJitterPacer_isDeterministicWithASeededSource(t *testing.T) {
+	d := time.Duration(1) * time.Second
+	now := time.Now()
+
+	delays := func() []time.Duration {
+		j := newJitterPacer(NewTokenBucket(d, 1), d, 0.2, rand.NewSource(42))
+		result := []time.Duration{}
+		at := now
+		for i := 0; i < 5; i++ {
+			at = at.Add(j.DelayN(at, 1))
+			j.AllowN(at, 1)
+			result = append(result, j.DelayN(at, 1))
+		}
+		return result
+	}
+
+	a, b := delays(), delays()
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fail()
+		}
+	}
+}
+
+func TestJitterPacer_Reset_clearsTheOffset(t *testing.T) {
+	d := time.Duration(1) * time.Hour
+	j := newJitterPacer(NewTokenBucket(d, 1), d, 0.5, rand.NewSource(1))
+	j.AllowN(time.Now(), 1)
+
+	j.Reset()
+
+	if !j.AllowN(time.Now(), 1) {
+		t.Fail()
+	}
+}
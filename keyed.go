@@ -0,0 +1,853 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+//KeyedLimiter manages an independent Limiter for each of many string keys, such
+//as user IDs, IP addresses, or API keys.
+//The Limiter for a key is created the first time the key is used, with the
+//throughput duration and options the KeyedLimiter was created with.
+//
+//Values pushed with one key are only ever popped with the same key, and the
+//rate of one key does not affect any other key.
+//The rate and capacity of individual keys may be overridden with SetKeyRate and
+//SetKeyCapacity, for example to give premium tenants higher limits.
+type KeyedLimiter struct {
+	monitor
+
+	d    time.Duration
+	opts []Option
+
+	//rates and capacities are the overrides of the defaults for specific keys.
+	rates      map[string]time.Duration
+	capacities map[string]int
+	weights    map[string]int
+
+	//capacity is the default capacity set with SetCapacity, which replaces
+	//that of opts if hasCapacity is set.
+	capacity    int
+	hasCapacity bool
+
+	keys   map[string]*keyState
+	closed bool
+
+	//ready holds the keys of k that may have values queued, in the order in
+	//which PopAny visits them.
+	ready *list.List
+
+	ttl     time.Duration
+	swept   time.Time
+	onEvict func(key string)
+	keyFunc KeyFunc
+
+	separator string
+	observer  func(key string) Observer
+
+	//current is the key being served by PopAny and served is the number of
+	//values released from it during its turn.
+	current string
+	served  int
+
+	throttles *throttleTracker
+
+	//recent holds the keys of k from most to least recently used.
+	recent    *list.List
+	maxKeys   int
+	evictions uint64
+}
+
+//keyState is the state a KeyedLimiter keeps for a single key.
+type keyState struct {
+	//pushed, popped, and throttled are the counters reported in KeyStats. They
+	//are accessed atomically and kept first for alignment.
+	pushed    uint64
+	popped    uint64
+	throttled uint64
+
+	limiter *Limiter
+
+	//users is the number of calls currently using limiter.
+	users int
+
+	//used is the last time a call stopped using limiter.
+	used time.Time
+
+	//element is the element of the key in the recent list of its KeyedLimiter.
+	element *list.Element
+
+	//ready is the element of the key in the ready list of its KeyedLimiter, and
+	//is nil if the key is not in it.
+	ready *list.Element
+
+	//parent is the state of the parent key of a hierarchical key, children is
+	//the number of keys whose parent this is, and depth is the number of
+	//ancestors of the key.
+	parent   *keyState
+	children int
+	depth    int
+}
+
+//NewKeyed creates a KeyedLimiter whose keys each have throughput duration d and
+//are configured by opts.
+//NewKeyed panics if d or opts are invalid for a Limiter, or if opts include
+//WithExpvar.
+func NewKeyed(d time.Duration, opts ...Option) *KeyedLimiter {
+	//opts are checked once up front rather than when the first key is created,
+	//and k follows the Clock they give its keys.
+	probe := New(d, append([]Option{func(l *Limiter) { l.keyed = true }}, opts...)...)
+	m := newMonitor()
+	m.clock = probe.clock
+
+	return &KeyedLimiter{
+		monitor: m,
+		d:       d,
+		opts:    opts,
+
+		rates:      map[string]time.Duration{},
+		capacities: map[string]int{},
+		weights:    map[string]int{},
+
+		keys: map[string]*keyState{},
+
+		ready:  list.New(),
+		recent: list.New(),
+
+		throttles: newThrottleTracker(DefaultThrottleWindow),
+	}
+}
+
+//SetKeyRate overrides the throughput duration of key to be d instead of the
+//default of k.
+//It takes effect immediately if key is already in use.
+func (k *KeyedLimiter) SetKeyRate(key string, d time.Duration) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.rates[key] = d
+	if s, ok := k.keys[key]; ok {
+		s.limiter.SetDuration(d)
+	}
+	k.broadcastLocked()
+}
+
+//SetKeyCapacity overrides the capacity of key to be capacity instead of the
+//default of k.
+//capacity may be Unbounded. It takes effect immediately if key is already in
+//use.
+func (k *KeyedLimiter) SetKeyCapacity(key string, capacity int) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.capacities[key] = capacity
+	if s, ok := k.keys[key]; ok {
+		s.limiter.SetCapacity(capacity)
+	}
+}
+
+//SetDuration changes the default throughput duration of k to d.
+//It takes effect immediately for the keys in use whose rate is not overridden
+//with SetKeyRate.
+func (k *KeyedLimiter) SetDuration(d time.Duration) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.d = d
+	for key, s := range k.keys {
+		if _, ok := k.rates[key]; !ok {
+			s.limiter.SetDuration(d)
+		}
+	}
+	k.broadcastLocked()
+}
+
+//SetCapacity changes the default capacity of k to capacity, replacing any
+//given by the options of k.
+//capacity may be Unbounded. It takes effect immediately for the keys in use
+//whose capacity is not overridden with SetKeyCapacity.
+func (k *KeyedLimiter) SetCapacity(capacity int) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.capacity, k.hasCapacity = capacity, true
+	for key, s := range k.keys {
+		if _, ok := k.capacities[key]; !ok {
+			s.limiter.SetCapacity(capacity)
+		}
+	}
+	k.broadcastLocked()
+}
+
+//SetKeyWeight sets the weight of key used by PopAny, which defaults to one.
+//A key with weight w has up to w values released in a row by PopAny before the
+//next key with values is served.
+func (k *KeyedLimiter) SetKeyWeight(key string, weight int) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.weights[key] = weight
+}
+
+//SetThrottleWindow sets the sliding window over which TopThrottled counts how
+//often keys are throttled, forgetting everything counted so far.
+func (k *KeyedLimiter) SetThrottleWindow(window time.Duration) {
+	k.throttles.setWindow(window)
+}
+
+//TopThrottled returns up to n keys that have been throttled most often within
+//the throttle window, from most to least throttled, to help identify abusive
+//clients.
+//A key is throttled each time a pop with the key has to wait for its rate while
+//it has values queued.
+func (k *KeyedLimiter) TopThrottled(n int) []ThrottledKey {
+	return k.throttles.top(n, k.clock.Now())
+}
+
+//SetIdleTTL makes k evict the state of keys that have been idle for at least
+//ttl, so that keys such as client IP addresses do not use memory forever.
+//A key is idle while it has no queued values and no calls are using it. A ttl of
+//zero, the default, disables eviction.
+//
+//Idle keys are evicted as k is used, or explicitly with EvictIdle.
+//An evicted key starts over with a fresh Limiter the next time it is used, so
+//ttl should be longer than the throughput duration of the key.
+func (k *KeyedLimiter) SetIdleTTL(ttl time.Duration) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.ttl = ttl
+}
+
+//OnEvict sets a function that is called with each key whose state is evicted
+//from k.
+//fn is called without any locks of k held, so it may use k.
+func (k *KeyedLimiter) OnEvict(fn func(key string)) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.onEvict = fn
+}
+
+//SetMaxKeys limits the number of keys k keeps state for to max, so that a client
+//using many random keys cannot exhaust memory. A max of zero, the default,
+//imposes no limit.
+//
+//When a new key is used while k is at max keys, the least recently used key that
+//no calls are using is evicted, discarding any values queued for it.
+//The ancestors of a hierarchical key count towards max, so as many keys are
+//evicted as there are new states to create for the key and its ancestors.
+//Evictions are reported to the function set with OnEvict and counted by
+//Evictions.
+func (k *KeyedLimiter) SetMaxKeys(max int) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.maxKeys = max
+}
+
+//Evictions returns the number of keys whose state has been evicted from k, either
+//because they were idle or to stay within the maximum number of keys.
+func (k *KeyedLimiter) Evictions() uint64 {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	return k.evictions
+}
+
+//EvictIdle evicts the state of all keys that have been idle for at least the
+//duration set with SetIdleTTL, and returns the number of keys evicted.
+func (k *KeyedLimiter) EvictIdle() int {
+	k.lock.Lock()
+	evicted := k.evictIdleLocked(k.clock.Now())
+	onEvict := k.onEvict
+	k.lock.Unlock()
+
+	notifyEvicted(onEvict, evicted)
+	return len(evicted)
+}
+
+//SetSeparator enables hierarchical keys whose parts are separated by sep, such as
+//"org/team/user" with a sep of "/".
+//A value pushed with a hierarchical key is only released once the budgets of the
+//key and all of its ancestors permit it, and each release counts against all of
+//them, just like with Limiter.NewChild. In the example, a release must satisfy
+//the budgets of "org", "org/team", and "org/team/user" simultaneously.
+//
+//The ancestors of a key are created when the key is first used, and are not
+//evicted while they have descendants. SetSeparator should be called before k is
+//used.
+func (k *KeyedLimiter) SetSeparator(sep string) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.separator = sep
+}
+
+//SetObserver sets a function that returns the Observer of the Limiter of each
+//key, for example to collect metrics labeled with the key.
+//It applies to the Limiters of keys created after it is called.
+func (k *KeyedLimiter) SetObserver(fn func(key string) Observer) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.observer = fn
+}
+
+//KeyFunc derives the key of a value pushed to a KeyedLimiter with Push, for
+//example the host of a request.
+type KeyFunc func(value interface{}) string
+
+//SetKeyFunc sets the KeyFunc used by Push and TryPush to route values to the
+//Limiter of their key.
+func (k *KeyedLimiter) SetKeyFunc(fn KeyFunc) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.keyFunc = fn
+}
+
+//Push places value in the Limiter for the key derived from value by the KeyFunc
+//of k. It works just like PushKey.
+//Values are all pushed with the empty key if no KeyFunc has been set.
+func (k *KeyedLimiter) Push(value interface{}) error {
+	return k.PushKey(k.keyOf(value), value)
+}
+
+//TryPush attempts to place value in the Limiter for the key derived from value
+//by the KeyFunc of k without blocking. It works just like TryPushKey.
+func (k *KeyedLimiter) TryPush(value interface{}) (accepted bool, err error) {
+	return k.TryPushKey(k.keyOf(value), value)
+}
+
+//PushKey places value in the Limiter for key to be popped later with PopKey.
+//It works just like Limiter.Push.
+//
+//err will be ErrClosed if k.Close() has already been called.
+func (k *KeyedLimiter) PushKey(key string, value interface{}) error {
+	s, err := k.acquire(key)
+	if err != nil {
+		return err
+	}
+	defer k.release(s)
+
+	if err := s.limiter.Push(value); err != nil {
+		return err
+	}
+	atomic.AddUint64(&s.pushed, 1)
+	return nil
+}
+
+//TryPushKey attempts to place value in the Limiter for key without blocking.
+//It works just like Limiter.TryPush.
+func (k *KeyedLimiter) TryPushKey(key string, value interface{}) (accepted bool, err error) {
+	s, err := k.acquire(key)
+	if err != nil {
+		return false, err
+	}
+	defer k.release(s)
+
+	accepted, err = s.limiter.TryPush(value)
+	if accepted {
+		atomic.AddUint64(&s.pushed, 1)
+	}
+	return accepted, err
+}
+
+//PopKey releases the next value pushed with key.
+//It works just like Limiter.Pop, and returns nil once k is closed and key has no
+//more values.
+func (k *KeyedLimiter) PopKey(key string) interface{} {
+	value, _ := k.PopKeyOk(key)
+	return value
+}
+
+//PopKeyOk works just like PopKey, but ok is false if k is closed and key has no
+//more values.
+func (k *KeyedLimiter) PopKeyOk(key string) (value interface{}, ok bool) {
+	s, err := k.acquire(key)
+	if err != nil {
+		return nil, false
+	}
+	defer k.release(s)
+
+	k.checkThrottled(key, s)
+	value, ok = s.limiter.PopOk()
+	if ok {
+		atomic.AddUint64(&s.popped, 1)
+	}
+	return value, ok
+}
+
+//TryPopKey attempts to release the next value pushed with key without blocking.
+//It works just like Limiter.TryPop.
+func (k *KeyedLimiter) TryPopKey(key string) (value interface{}, ok bool) {
+	s, err := k.acquire(key)
+	if err != nil {
+		return nil, false
+	}
+	defer k.release(s)
+
+	k.checkThrottled(key, s)
+	value, ok = s.limiter.TryPop()
+	if ok {
+		atomic.AddUint64(&s.popped, 1)
+	}
+	return value, ok
+}
+
+func (k *KeyedLimiter) keyOf(value interface{}) string {
+	k.lock.Lock()
+	keyFunc := k.keyFunc
+	k.lock.Unlock()
+
+	if keyFunc == nil {
+		return ""
+	}
+	return keyFunc(value)
+}
+
+//PopAny releases the next value permitted by the rate of its key, choosing
+//among keys with queued values using weighted round-robin so that one busy key
+//cannot monopolize a consumer. See SetKeyWeight.
+//Keys take their turns in the order in which they came to have values queued.
+//It blocks until a value of some key may be released, and returns that key.
+//
+//ok is false if k is closed and no keys have values left.
+func (k *KeyedLimiter) PopAny() (key string, value interface{}, ok bool) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	err := k.pollLocked(context.Background(), func(now time.Time) (bool, time.Duration, error) {
+		popped, v, ok, wait := k.tryPopAnyLocked()
+		if ok {
+			key, value = popped, v
+			return true, 0, nil
+		}
+		if k.closed && !k.queuedLocked() {
+			return false, 0, ErrClosed
+		}
+		return false, wait, nil
+	})
+	return key, value, err == nil
+}
+
+//queuedLocked returns whether any key of k has values queued.
+func (k *KeyedLimiter) queuedLocked() bool {
+	for e := k.ready.Front(); e != nil; e = e.Next() {
+		if k.keys[e.Value.(string)].limiter.Len() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+//TryPopAny works just like PopAny, but returns immediately with ok false if no
+//value of any key may be released now.
+func (k *KeyedLimiter) TryPopAny() (key string, value interface{}, ok bool) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	key, value, ok, _ = k.tryPopAnyLocked()
+	return key, value, ok
+}
+
+//tryPopAnyLocked releases a value from the first key in round-robin order that
+//permits it.
+//If no key does, wait is the shortest time until a key held back by its rate
+//permits a release, and is zero if no key is, in which case nothing changes
+//before k is broadcast to, for example when a value is pushed or a PopKey
+//returns.
+func (k *KeyedLimiter) tryPopAnyLocked() (key string, value interface{}, ok bool, wait time.Duration) {
+	//The ready list is rotated so that its front is the key whose turn it is.
+	if e := k.ready.Front(); e != nil && e.Value.(string) == k.current && k.served >= k.weightLocked(k.current) {
+		k.ready.MoveToBack(e)
+	}
+
+	for n := k.ready.Len(); n > 0; n-- {
+		e := k.ready.Front()
+		key := e.Value.(string)
+		s := k.keys[key]
+		v, ok, delay := s.limiter.tryPop()
+		if ok {
+			atomic.AddUint64(&s.popped, 1)
+			if key != k.current {
+				k.current, k.served = key, 0
+			}
+			k.served++
+			k.readyLocked(s)
+			return key, v, true, 0
+		}
+		if s.limiter.Len() == 0 {
+			k.unreadyLocked(s)
+			continue
+		}
+		if delay > 0 && (wait <= 0 || delay < wait) {
+			wait = delay
+		}
+		k.ready.MoveToBack(e)
+	}
+	return "", nil, false, wait
+}
+
+func (k *KeyedLimiter) weightLocked(key string) int {
+	if weight, ok := k.weights[key]; ok {
+		return weight
+	}
+	return 1
+}
+
+//Len returns the number of values currently queued for key.
+func (k *KeyedLimiter) Len(key string) int {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if s, ok := k.keys[key]; ok {
+		return s.limiter.Len()
+	}
+	return 0
+}
+
+//KeyStats are statistics about a single key of a KeyedLimiter.
+type KeyStats struct {
+	//Len and Cap are the number of values queued for the key and its capacity.
+	Len int
+	Cap int
+
+	//Pushed and Popped are the number of values pushed and popped with the key.
+	Pushed uint64
+	Popped uint64
+
+	//Throttled is the number of pops with the key that had to wait for its rate
+	//even though it had values queued.
+	Throttled uint64
+
+	//LastUsed is the last time a call using the key returned.
+	//It is the zero time while the key has never stopped being used.
+	LastUsed time.Time
+}
+
+//Stats returns statistics about key.
+//ok is false if k does not currently keep state for key, for example because it
+//has never been used or has been evicted.
+func (k *KeyedLimiter) Stats(key string) (stats KeyStats, ok bool) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	s, ok := k.keys[key]
+	if !ok {
+		return KeyStats{}, false
+	}
+	return s.statsLocked(), true
+}
+
+//Range calls fn with the statistics of each key k currently keeps state for, in
+//sorted order of keys, until fn returns false.
+//fn is called without any locks of k held, so it may use k.
+func (k *KeyedLimiter) Range(fn func(key string, stats KeyStats) bool) {
+	k.lock.Lock()
+	all := make(map[string]KeyStats, len(k.keys))
+	for key, s := range k.keys {
+		all[key] = s.statsLocked()
+	}
+	k.lock.Unlock()
+
+	keys := make([]string, 0, len(all))
+	for key := range all {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !fn(key, all[key]) {
+			return
+		}
+	}
+}
+
+//Duration returns the throughput duration of key.
+func (k *KeyedLimiter) Duration(key string) time.Duration {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if d, ok := k.rates[key]; ok {
+		return d
+	}
+	return k.d
+}
+
+//Delay returns how long it will be before a value pushed with key may be
+//released, ignoring any values already queued for key.
+//It is zero if key has no state.
+func (k *KeyedLimiter) Delay(key string) time.Duration {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if s, ok := k.keys[key]; ok {
+		return s.limiter.Delay()
+	}
+	return 0
+}
+
+//Keys returns the keys k currently keeps state for in sorted order.
+func (k *KeyedLimiter) Keys() []string {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	keys := make([]string, 0, len(k.keys))
+	for key := range k.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//Close closes the Limiters of all keys in k.
+//Values already pushed may still be popped, but pushing any key fails with
+//ErrClosed.
+//
+//If k is already closed, then ErrClosed is returned, otherwise err is nil.
+func (k *KeyedLimiter) Close() (err error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.closed {
+		return ErrClosed
+	}
+	k.closed = true
+
+	for _, s := range k.keys {
+		s.limiter.Close()
+	}
+	k.broadcastLocked()
+	return nil
+}
+
+//acquire returns the state for key and marks it as in use until it is passed to
+//release.
+//The state is created if it does not exist, unless k is closed in which case
+//ErrClosed is returned.
+func (k *KeyedLimiter) acquire(key string) (*keyState, error) {
+	k.lock.Lock()
+
+	var evicted []string
+	if now := k.clock.Now(); k.ttl > 0 && now.Sub(k.swept) >= k.ttl {
+		evicted = k.evictIdleLocked(now)
+		k.swept = now
+	}
+	s, ok := k.keys[key]
+	if !ok && !k.closed {
+		evicted = append(evicted, k.evictRecentLocked(key)...)
+		s = k.createLocked(key)
+	}
+	if s != nil {
+		k.recent.MoveToFront(s.element)
+		s.users++
+	}
+	onEvict := k.onEvict
+	k.lock.Unlock()
+
+	notifyEvicted(onEvict, evicted)
+	if s == nil {
+		return nil, ErrClosed
+	}
+	return s, nil
+}
+
+//createLocked creates the state for key, and for any of its ancestors that do
+//not have state yet.
+func (k *KeyedLimiter) createLocked(key string) *keyState {
+	s := &keyState{}
+	if i := strings.LastIndex(key, k.separator); k.separator != "" && i >= 0 {
+		parentKey := key[:i]
+		parent, ok := k.keys[parentKey]
+		if !ok {
+			parent = k.createLocked(parentKey)
+		}
+		parent.children++
+		s.parent = parent
+		s.depth = parent.depth + 1
+	}
+
+	s.limiter = k.newLimiterLocked(key, s.parent)
+	s.element = k.recent.PushFront(key)
+	k.keys[key] = s
+	return s
+}
+
+//newLimiterLocked creates the Limiter for key using the defaults of k and any
+//overrides for key. The Limiter is a child of the Limiter of parent if parent
+//is not nil.
+func (k *KeyedLimiter) newLimiterLocked(key string, parent *keyState) *Limiter {
+	d, ok := k.rates[key]
+	if !ok {
+		d = k.d
+	}
+
+	opts := k.opts
+	if capacity, ok := k.capacities[key]; ok {
+		opts = append(opts[:len(opts):len(opts)], WithCapacity(capacity))
+	} else if k.hasCapacity {
+		opts = append(opts[:len(opts):len(opts)], WithCapacity(k.capacity))
+	}
+	if k.observer != nil {
+		opts = append(opts[:len(opts):len(opts)], WithObserver(k.observer(key)))
+	}
+	if parent != nil {
+		return parent.limiter.NewChild(d, opts...)
+	}
+	return New(d, opts...)
+}
+
+//readyLocked adds the key of s to the back of the ready list of k if it has
+//values queued and is not already in it, and removes it once it has none.
+func (k *KeyedLimiter) readyLocked(s *keyState) {
+	if s.limiter.Len() == 0 {
+		k.unreadyLocked(s)
+	} else if s.ready == nil {
+		s.ready = k.ready.PushBack(s.element.Value)
+	}
+}
+
+func (k *KeyedLimiter) unreadyLocked(s *keyState) {
+	if s.ready != nil {
+		k.ready.Remove(s.ready)
+		s.ready = nil
+	}
+}
+
+func (k *KeyedLimiter) release(s *keyState) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	s.users--
+	s.used = k.clock.Now()
+	k.readyLocked(s)
+	k.broadcastLocked()
+}
+
+//evictIdleLocked removes the state of all keys that have been idle since at
+//least the ttl of k before now, and returns the evicted keys.
+func (k *KeyedLimiter) evictIdleLocked(now time.Time) []string {
+	if k.ttl <= 0 {
+		return nil
+	}
+
+	//Keys are visited from the leaves up, so that an ancestor whose descendants
+	//are all evicted is evicted in the same pass.
+	keys := make([]string, 0, len(k.keys))
+	for key := range k.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return k.keys[keys[i]].depth > k.keys[keys[j]].depth
+	})
+
+	evicted := []string{}
+	for _, key := range keys {
+		s := k.keys[key]
+		if s.users == 0 && s.children == 0 && now.Sub(s.used) >= k.ttl && s.limiter.Len() == 0 {
+			k.removeLocked(key, s)
+			evicted = append(evicted, key)
+		}
+	}
+	return evicted
+}
+
+//evictRecentLocked removes the state of the least recently used keys that are
+//not in use until there is room within the maximum of k for key and for any of
+//its ancestors that do not have state yet, and returns the evicted keys.
+//The ancestors of key are never evicted.
+func (k *KeyedLimiter) evictRecentLocked(key string) []string {
+	if k.maxKeys <= 0 {
+		return nil
+	}
+
+	n := k.missingLocked(key)
+	evicted := []string{}
+	//Evicting a key may leave its parent without children, so the keys are
+	//visited again until there is room or nothing more can be evicted.
+	for more := true; more && len(k.keys)+n > k.maxKeys; {
+		more = false
+		for e := k.recent.Back(); e != nil && len(k.keys)+n > k.maxKeys; {
+			prev := e.Prev()
+			other := e.Value.(string)
+			if s := k.keys[other]; s.users == 0 && s.children == 0 && !k.isAncestor(other, key) {
+				s.limiter.CloseDiscard()
+				k.removeLocked(other, s)
+				evicted = append(evicted, other)
+				more = true
+			}
+			e = prev
+		}
+	}
+	return evicted
+}
+
+//missingLocked returns the number of states createLocked creates for key, which
+//is one for key and one for each of its ancestors that do not have state yet.
+func (k *KeyedLimiter) missingLocked(key string) int {
+	n := 1
+	for k.separator != "" {
+		i := strings.LastIndex(key, k.separator)
+		if i < 0 {
+			break
+		}
+		key = key[:i]
+		if _, ok := k.keys[key]; ok {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+//isAncestor returns whether ancestor is an ancestor of the hierarchical key.
+func (k *KeyedLimiter) isAncestor(ancestor, key string) bool {
+	return k.separator != "" && strings.HasPrefix(key, ancestor+k.separator)
+}
+
+func (k *KeyedLimiter) removeLocked(key string, s *keyState) {
+	delete(k.keys, key)
+	k.recent.Remove(s.element)
+	k.unreadyLocked(s)
+	if s.parent != nil {
+		s.parent.children--
+	}
+	k.evictions++
+}
+
+//checkThrottled counts a throttled pop of key if its state s has values queued
+//that may not be released yet.
+func (k *KeyedLimiter) checkThrottled(key string, s *keyState) {
+	if s.limiter.Len() > 0 && s.limiter.Delay() > 0 {
+		atomic.AddUint64(&s.throttled, 1)
+		k.throttles.record(key, k.clock.Now())
+	}
+}
+
+//statsLocked returns the KeyStats of s. The lock of the KeyedLimiter of s must be
+//held.
+func (s *keyState) statsLocked() KeyStats {
+	return KeyStats{
+		Len:       s.limiter.Len(),
+		Cap:       s.limiter.Cap(),
+		Pushed:    atomic.LoadUint64(&s.pushed),
+		Popped:    atomic.LoadUint64(&s.popped),
+		Throttled: atomic.LoadUint64(&s.throttled),
+		LastUsed:  s.used,
+	}
+}
+
+func notifyEvicted(onEvict func(key string), evicted []string) {
+	if onEvict == nil {
+		return
+	}
+	for _, key := range evicted {
+		onEvict(key)
+	}
+}
@@ -0,0 +1,571 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter_PushKey_createsIndependentLimitersPerKey(t *testing.T) {
+	k := NewKeyed(time.Duration(1)*time.Hour, WithCapacity(2))
+	k.PushKey("a", 1)
+	k.PushKey("a", 2)
+	k.PushKey("b", 3)
+
+	if k.PopKey("a") != 1 || k.PopKey("b") != 3 {
+		t.Fail()
+	}
+	if _, ok := k.TryPopKey("a"); ok {
+		t.Fail()
+	}
+	if k.Len("a") != 1 || k.Len("b") != 0 || k.Len("c") != 0 {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_TryPushKey_honorsThePerKeyCapacity(t *testing.T) {
+	k := NewKeyed(time.Duration(1))
+
+	if accepted, err := k.TryPushKey("a", 1); !accepted || err != nil {
+		t.Fail()
+	}
+	if accepted, _ := k.TryPushKey("a", 2); accepted {
+		t.Fail()
+	}
+	if accepted, _ := k.TryPushKey("b", 2); !accepted {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_PopKey_blocksUntilTheKeyIsPushed(t *testing.T) {
+	k := NewKeyed(time.Duration(1))
+	result := make(chan interface{})
+	go func() {
+		result <- k.PopKey("a")
+	}()
+
+	time.Sleep(time.Duration(10) * time.Millisecond)
+	k.PushKey("b", 1)
+	k.PushKey("a", 2)
+
+	if <-result != 2 {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_Keys_returnsSortedKeys(t *testing.T) {
+	k := NewKeyed(time.Duration(1), WithCapacity(Unbounded))
+	k.PushKey("b", 1)
+	k.PushKey("a", 1)
+
+	keys := k.Keys()
+
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_Close_closesAllKeys(t *testing.T) {
+	k := NewKeyed(time.Duration(1))
+	k.PushKey("a", 1)
+
+	if k.Close() != nil || k.Close() != ErrClosed {
+		t.Fail()
+	}
+	if k.PushKey("a", 2) != ErrClosed || k.PushKey("b", 2) != ErrClosed {
+		t.Fail()
+	}
+	if v, ok := k.PopKeyOk("a"); v != 1 || !ok {
+		t.Fail()
+	}
+	if _, ok := k.PopKeyOk("a"); ok {
+		t.Fail()
+	}
+	if _, ok := k.PopKeyOk("b"); ok {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_SetKeyRate_overridesTheDefaultRate(t *testing.T) {
+	k := NewKeyed(time.Duration(1)*time.Hour, WithCapacity(Unbounded))
+	k.PushKey("a", 0)
+	k.SetKeyRate("a", time.Duration(1))
+	k.SetKeyRate("b", time.Duration(1))
+	for _, key := range []string{"a", "b", "c"} {
+		k.PushKey(key, 1)
+		k.PushKey(key, 2)
+	}
+
+	for _, key := range []string{"a", "b"} {
+		for k.Len(key) > 0 {
+			k.PopKey(key)
+		}
+	}
+	if k.PopKey("c") != 1 {
+		t.Fail()
+	}
+	if _, ok := k.TryPopKey("c"); ok {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_SetKeyCapacity_overridesTheDefaultCapacity(t *testing.T) {
+	k := NewKeyed(time.Duration(1))
+	k.SetKeyCapacity("a", 2)
+	k.PushKey("b", 0)
+	k.SetKeyCapacity("b", 2)
+
+	k.PushKey("a", 0)
+	for _, key := range []string{"a", "b"} {
+		if accepted, _ := k.TryPushKey(key, 1); !accepted {
+			t.Fail()
+		}
+	}
+	k.PushKey("c", 1)
+	if accepted, _ := k.TryPushKey("c", 2); accepted {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_SetDuration_changesKeysWithoutOverrides(t *testing.T) {
+	k := NewKeyed(time.Duration(1) * time.Hour)
+	k.SetKeyRate("a", time.Duration(2)*time.Hour)
+	k.PushKey("a", 0)
+	k.PushKey("b", 0)
+
+	k.SetDuration(time.Duration(3) * time.Hour)
+
+	if k.Duration("a") != time.Duration(2)*time.Hour || k.Duration("b") != time.Duration(3)*time.Hour || k.Duration("c") != time.Duration(3)*time.Hour {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_SetCapacity_changesKeysWithoutOverrides(t *testing.T) {
+	k := NewKeyed(time.Duration(1) * time.Hour)
+	k.SetKeyCapacity("a", 1)
+	k.PushKey("a", 0)
+	k.PushKey("b", 0)
+
+	k.SetCapacity(2)
+	k.PushKey("c", 0)
+
+	if accepted, _ := k.TryPushKey("a", 1); accepted {
+		t.Fail()
+	}
+	for _, key := range []string{"b", "c"} {
+		if accepted, _ := k.TryPushKey(key, 1); !accepted {
+			t.Fail()
+		}
+	}
+}
+
+func TestKeyedLimiter_EvictIdle_evictsOnlyIdleKeys(t *testing.T) {
+	k := NewKeyed(time.Duration(1), WithCapacity(2))
+	evicted := []string{}
+	k.OnEvict(func(key string) {
+		evicted = append(evicted, key)
+	})
+	k.PushKey("a", 1)
+	k.PushKey("b", 1)
+	k.PopKey("b")
+
+	if k.EvictIdle() != 0 {
+		t.Fail()
+	}
+
+	k.SetIdleTTL(time.Duration(1) * time.Millisecond)
+	time.Sleep(time.Duration(5) * time.Millisecond)
+
+	if k.EvictIdle() != 1 || len(evicted) != 1 || evicted[0] != "b" {
+		t.Fail()
+	}
+	if keys := k.Keys(); len(keys) != 1 || keys[0] != "a" {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_acquire_evictsIdleKeysAsItIsUsed(t *testing.T) {
+	k := NewKeyed(time.Duration(1))
+	k.SetIdleTTL(time.Duration(1) * time.Millisecond)
+	k.PushKey("a", 1)
+	k.PopKey("a")
+
+	time.Sleep(time.Duration(5) * time.Millisecond)
+	k.PushKey("b", 1)
+
+	if keys := k.Keys(); len(keys) != 1 || keys[0] != "b" {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_EvictIdle_usesTheClockOfTheKeys(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	k := NewKeyed(time.Duration(1), WithClock(c))
+	k.SetIdleTTL(time.Duration(1) * time.Hour)
+	k.PushKey("a", 1)
+	k.PopKey("a")
+
+	if k.EvictIdle() != 0 {
+		t.Fail()
+	}
+
+	c.advance(time.Duration(1) * time.Hour)
+
+	if k.EvictIdle() != 1 || len(k.Keys()) != 0 {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_acquire_evictsIdleKeysAtTheTimeOfTheClock(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	k := NewKeyed(time.Duration(1), WithClock(c))
+	k.SetIdleTTL(time.Duration(1) * time.Hour)
+	k.PushKey("a", 1)
+	k.PopKey("a")
+
+	k.PushKey("b", 1)
+	if keys := k.Keys(); len(keys) != 2 {
+		t.Fatal(keys)
+	}
+
+	c.advance(time.Duration(1) * time.Hour)
+	k.PushKey("c", 1)
+
+	if keys := k.Keys(); len(keys) != 2 || keys[0] != "b" || keys[1] != "c" {
+		t.Fatal(keys)
+	}
+}
+
+func TestKeyedLimiter_SetMaxKeys_evictsTheLeastRecentlyUsedKey(t *testing.T) {
+	k := NewKeyed(time.Duration(1), WithCapacity(Unbounded))
+	k.SetMaxKeys(2)
+	evicted := []string{}
+	k.OnEvict(func(key string) {
+		evicted = append(evicted, key)
+	})
+	k.PushKey("a", 1)
+	k.PushKey("b", 1)
+	k.PushKey("a", 2)
+
+	k.PushKey("c", 1)
+
+	if keys := k.Keys(); len(keys) != 2 || keys[0] != "a" || keys[1] != "c" {
+		t.Fail()
+	}
+	if len(evicted) != 1 || evicted[0] != "b" || k.Evictions() != 1 {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_SetMaxKeys_doesNotEvictKeysInUse(t *testing.T) {
+	k := NewKeyed(time.Duration(1))
+	k.SetMaxKeys(1)
+	done := make(chan interface{})
+	go func() {
+		done <- k.PopKey("a")
+	}()
+	time.Sleep(time.Duration(10) * time.Millisecond)
+
+	k.PushKey("b", 1)
+	k.PushKey("a", 2)
+
+	if <-done != 2 || k.Evictions() != 0 {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_Stats_countsPushesPopsAndThrottling(t *testing.T) {
+	k := NewKeyed(time.Duration(1)*time.Hour, WithCapacity(3))
+	for i := 0; i < 3; i++ {
+		k.PushKey("a", i)
+	}
+	k.PopKey("a")
+	k.TryPopKey("a")
+
+	stats, ok := k.Stats("a")
+	if !ok || stats.Len != 2 || stats.Cap != 3 || stats.Pushed != 3 || stats.Popped != 1 {
+		t.Fatal(stats)
+	}
+	if stats.Throttled != 1 || stats.LastUsed.IsZero() {
+		t.Fail()
+	}
+	if _, ok := k.Stats("b"); ok {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_Range_visitsKeysInOrderUntilFalse(t *testing.T) {
+	k := NewKeyed(time.Duration(1))
+	for _, key := range []string{"c", "a", "b"} {
+		k.PushKey(key, 1)
+	}
+
+	visited := []string{}
+	k.Range(func(key string, stats KeyStats) bool {
+		visited = append(visited, key)
+		return stats.Len == 1 && key != "b"
+	})
+
+	if len(visited) != 2 || visited[0] != "a" || visited[1] != "b" {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_Push_routesValuesByTheKeyFunc(t *testing.T) {
+	k := NewKeyed(time.Duration(1), WithCapacity(Unbounded))
+	k.Push("none")
+	k.SetKeyFunc(func(value interface{}) string {
+		return value.(string)[:1]
+	})
+
+	k.Push("apple")
+	k.TryPush("avocado")
+	k.Push("banana")
+
+	if k.Len("") != 1 || k.Len("a") != 2 || k.Len("b") != 1 {
+		t.Fail()
+	}
+	if k.PopKey("a") != "apple" || k.PopKey("a") != "avocado" {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_SetSeparator_releasesWithinAllAncestorBudgets(t *testing.T) {
+	k := NewKeyed(time.Duration(1), WithCapacity(Unbounded))
+	k.SetSeparator("/")
+	k.SetKeyRate("org", time.Duration(1)*time.Hour)
+	k.PushKey("org/a/x", 1)
+	k.PushKey("org/b/y", 2)
+
+	if k.PopKey("org/a/x") != 1 {
+		t.Fail()
+	}
+	if _, ok := k.TryPopKey("org/b/y"); ok {
+		t.Fail()
+	}
+	if keys := k.Keys(); len(keys) != 5 {
+		t.Fatal(keys)
+	}
+}
+
+func TestKeyedLimiter_SetSeparator_doesNotEvictAncestors(t *testing.T) {
+	k := NewKeyed(time.Duration(1))
+	k.SetSeparator("/")
+	k.SetIdleTTL(time.Duration(1) * time.Millisecond)
+	k.PushKey("org/team", 1)
+	time.Sleep(time.Duration(5) * time.Millisecond)
+
+	if k.EvictIdle() != 0 {
+		t.Fail()
+	}
+	k.PopKey("org/team")
+	time.Sleep(time.Duration(5) * time.Millisecond)
+	if k.EvictIdle() != 2 || len(k.Keys()) != 0 {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_SetSeparator_evictsDeepHierarchiesInOnePass(t *testing.T) {
+	k := NewKeyed(time.Duration(1))
+	k.SetSeparator("/")
+	k.SetIdleTTL(time.Duration(1) * time.Millisecond)
+	for _, key := range []string{"a/b/c/d", "a/x", "e/f/g"} {
+		k.PushKey(key, 1)
+		k.PopKey(key)
+	}
+	time.Sleep(time.Duration(5) * time.Millisecond)
+
+	if n := k.EvictIdle(); n != 8 || len(k.Keys()) != 0 {
+		t.Fatal(n, k.Keys())
+	}
+}
+
+func TestKeyedLimiter_SetMaxKeys_countsAncestors(t *testing.T) {
+	k := NewKeyed(time.Duration(1), WithCapacity(Unbounded))
+	k.SetSeparator("/")
+	k.SetMaxKeys(3)
+	k.PushKey("a/b", 1)
+	k.PushKey("c", 1)
+
+	k.PushKey("d/e/f", 1)
+
+	if keys := k.Keys(); len(keys) != 3 || keys[0] != "d" || keys[1] != "d/e" || keys[2] != "d/e/f" {
+		t.Fatal(keys)
+	}
+	if k.Evictions() != 3 {
+		t.Fail()
+	}
+
+	k.PushKey("d/e/g", 1)
+
+	if keys := k.Keys(); len(keys) != 3 || keys[2] != "d/e/g" {
+		t.Fatal(keys)
+	}
+}
+
+func TestKeyedLimiter_PopAny_roundRobinsAcrossKeysByWeight(t *testing.T) {
+	k := NewKeyed(time.Duration(1), WithCapacity(Unbounded))
+	k.SetKeyWeight("a", 2)
+	for i := 0; i < 3; i++ {
+		k.PushKey("a", i)
+		k.PushKey("b", i)
+	}
+
+	got := ""
+	for i := 0; i < 6; i++ {
+		key, _, ok := k.PopAny()
+		if !ok {
+			t.Fatal(i)
+		}
+		got += key
+	}
+
+	if got != "aababb" {
+		t.Fatal(got)
+	}
+}
+
+func TestKeyedLimiter_PopAny_visitsOnlyKeysWithValuesInTheOrderTheyCameToHaveThem(t *testing.T) {
+	k := NewKeyed(time.Duration(1), WithCapacity(Unbounded))
+	for _, key := range []string{"d", "c", "a", "b"} {
+		k.PushKey(key, 1)
+	}
+	k.PopKey("d")
+	k.PushKey("c", 2)
+
+	got := ""
+	for i := 0; i < 4; i++ {
+		key, _, _ := k.PopAny()
+		got += key
+	}
+
+	if got != "cabc" {
+		t.Fatal(got)
+	}
+	if k.ready.Len() != 0 || len(k.keys) != 4 {
+		t.Fatal(k.ready.Len(), len(k.keys))
+	}
+}
+
+func TestKeyedLimiter_PopAny_skipsKeysThatAreThrottled(t *testing.T) {
+	k := NewKeyed(time.Duration(1), WithCapacity(Unbounded))
+	k.SetKeyRate("a", time.Duration(1)*time.Hour)
+	k.PushKey("a", 1)
+	k.PushKey("a", 2)
+	k.PushKey("b", 3)
+	k.PushKey("b", 4)
+
+	got := []interface{}{}
+	for i := 0; i < 3; i++ {
+		_, v, _ := k.PopAny()
+		got = append(got, v)
+	}
+
+	if got[0] != 1 || got[1] != 3 || got[2] != 4 {
+		t.Fatal(got)
+	}
+	if _, _, ok := k.TryPopAny(); ok {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_PopAny_waitsForPushesAndClose(t *testing.T) {
+	k := NewKeyed(time.Duration(1))
+	done := make(chan bool)
+	go func() {
+		key, v, ok := k.PopAny()
+		done <- key == "a" && v == 1 && ok
+		_, _, ok = k.PopAny()
+		done <- ok
+	}()
+
+	time.Sleep(time.Duration(10) * time.Millisecond)
+	k.PushKey("a", 1)
+	if !<-done {
+		t.Fail()
+	}
+
+	k.Close()
+	if <-done {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_PopAny_waitsForAPopKeyOfTheSameKey(t *testing.T) {
+	k := NewKeyed(time.Duration(1))
+	popKey := make(chan interface{})
+	go func() {
+		popKey <- k.PopKey("a")
+	}()
+	time.Sleep(time.Duration(10) * time.Millisecond)
+	popAny := make(chan interface{})
+	go func() {
+		_, v, _ := k.PopAny()
+		popAny <- v
+	}()
+	time.Sleep(time.Duration(10) * time.Millisecond)
+
+	k.PushKey("a", 1)
+	if v := <-popKey; v != 1 {
+		t.Fatal(v)
+	}
+	k.PushKey("a", 2)
+
+	select {
+	case v := <-popAny:
+		if v != 2 {
+			t.Fatal(v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("PopAny is still waiting")
+	}
+}
+
+func TestKeyedLimiter_PopAny_waitsOutTheShortestDelay(t *testing.T) {
+	d := time.Duration(20) * time.Millisecond
+	k := NewKeyed(time.Duration(1)*time.Hour, WithCapacity(Unbounded))
+	k.SetKeyRate("b", d)
+	for _, key := range []string{"a", "b"} {
+		k.PushKey(key, 1)
+		k.PushKey(key, 2)
+		k.PopKey(key)
+	}
+
+	start := time.Now()
+	if key, v, ok := k.PopAny(); key != "b" || v != 2 || !ok {
+		t.Fatal(key, v, ok)
+	}
+	if elapsed := time.Since(start); elapsed < d/2 || elapsed > time.Second {
+		t.Fatal(elapsed)
+	}
+}
+
+func TestKeyedLimiter_Delay_reportsThePacingOfTheKey(t *testing.T) {
+	k := NewKeyed(time.Duration(1) * time.Hour)
+	k.SetKeyRate("b", time.Duration(1)*time.Minute)
+	k.PushKey("a", 1)
+	k.PopKey("a")
+
+	if d := k.Delay("a"); d <= 59*time.Minute || k.Delay("b") != 0 {
+		t.Fail()
+	}
+	if k.Duration("a") != time.Hour || k.Duration("b") != time.Minute {
+		t.Fail()
+	}
+}
+
+func TestKeyedLimiter_SetObserver_observesEachKey(t *testing.T) {
+	k := NewKeyed(time.Duration(1))
+	recorders := map[string]*recorder{}
+	k.SetObserver(func(key string) Observer {
+		recorders[key] = &recorder{}
+		return recorders[key]
+	})
+
+	k.PushKey("a", 1)
+	k.PushKey("b", 2)
+	k.PopKey("a")
+
+	if recorders["a"].String() != "[push 1 pop 1]" || recorders["b"].String() != "[push 2]" {
+		t.Fail()
+	}
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"log"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+//maxLeakFrames is the number of stack frames of the creation of a Limiter kept
+//for its LeakWarning.
+const maxLeakFrames = 16
+
+//LeakWarning describes a Limiter that was garbage collected while values were
+//still queued in it and without having been closed.
+type LeakWarning struct {
+	//Name is the name of the Limiter given to WithName, if any.
+	Name string
+
+	//Len is the number of values that were queued in the Limiter.
+	Len int
+
+	//Created is the stack trace of the goroutine that created the Limiter, one
+	//"function file:line" frame per line.
+	Created string
+}
+
+func (w LeakWarning) String() string {
+	name := ""
+	if w.Name != "" {
+		name = " " + strconv.Quote(w.Name)
+	}
+	return "ratelimit: limiter" + name + " garbage collected with " + strconv.Itoa(w.Len) +
+		" queued values without being closed, created at:\n" + w.Created
+}
+
+//WithLeakWarning is a debugging aid that makes a Limiter call fn if it is
+//garbage collected while values are queued in it and it has not been closed,
+//which usually means values were silently lost by a lifecycle bug.
+//If fn is nil, then the warning is logged with the log package.
+//
+//It uses runtime.SetFinalizer, so fn is called from the finalizer goroutine at
+//some point after the Limiter becomes unreachable, or not at all if the program
+//exits first. A Limiter is kept reachable by its own goroutines, such as the one
+//started by C, for as long as they run.
+//Recording the stack trace of each creation makes New slower, so WithLeakWarning
+//is meant for tests and debug builds.
+func WithLeakWarning(fn func(warning LeakWarning)) Option {
+	return func(l *Limiter) {
+		if fn == nil {
+			fn = func(warning LeakWarning) {
+				log.Print(warning)
+			}
+		}
+		l.leaked = fn
+	}
+}
+
+//watchLeak sets the finalizer of l for WithLeakWarning.
+//skip is the number of stack frames between the caller of watchLeak and the
+//code that created l.
+func (l *Limiter) watchLeak(skip int) {
+	pcs := make([]uintptr, maxLeakFrames)
+	pcs = pcs[:runtime.Callers(skip+2, pcs)]
+
+	fn := l.leaked
+	runtime.SetFinalizer(l, func(l *Limiter) {
+		l.lock.Lock()
+		n, closed := l.values.len(), l.closed
+		l.lock.Unlock()
+
+		if n > 0 && !closed {
+			fn(LeakWarning{Name: l.name, Len: n, Created: formatFrames(pcs)})
+		}
+	})
+}
+
+func formatFrames(pcs []uintptr) string {
+	if len(pcs) == 0 {
+		return ""
+	}
+	lines := []string{}
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		lines = append(lines, frame.Function+" "+frame.File+":"+strconv.Itoa(frame.Line))
+		if !more {
+			break
+		}
+	}
+	return strings.Join(lines, "\n")
+}
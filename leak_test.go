@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithLeakWarning_warnsForALimiterCollectedWithQueuedValues(t *testing.T) {
+	warnings := make(chan LeakWarning, 2)
+	warn := func(warning LeakWarning) {
+		warnings <- warning
+	}
+	func() {
+		leaked := New(time.Second, WithCapacity(Unbounded), WithName("leaked"), WithLeakWarning(warn))
+		leaked.PushAll(0, 1)
+
+		closed := New(time.Second, WithLeakWarning(warn))
+		closed.Push(0)
+		closed.Close()
+	}()
+
+	var warning LeakWarning
+	deadline := time.Now().Add(time.Duration(5) * time.Second)
+	for warning.Len == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no warning")
+		}
+		runtime.GC()
+		select {
+		case warning = <-warnings:
+		case <-time.After(time.Duration(10) * time.Millisecond):
+		}
+	}
+
+	if warning.Name != "leaked" || warning.Len != 2 || !strings.Contains(warning.Created, "TestWithLeakWarning") {
+		t.Fatal(warning)
+	}
+	runtime.GC()
+	select {
+	case warning := <-warnings:
+		t.Fatal("a closed limiter should not warn", warning)
+	case <-time.After(time.Duration(50) * time.Millisecond):
+	}
+}
@@ -0,0 +1,105 @@
+package ratelimit
+
+import "time"
+
+//LeakyBucket is a queue of values that leak out of it at a constant interval.
+//
+//LeakyBucket differs from the pacing of a Limiter in three ways.
+//First, values are released on the ticks of a fixed clock that starts when the
+//LeakyBucket is created, so releases are never closer than d apart and are
+//always aligned to multiples of d. A Limiter releases a value as soon as d has
+//passed since its previous release, so an idle Limiter releases its next value
+//immediately while an idle LeakyBucket waits for its next tick.
+//Second, ticks on which there is no value to release, or on which the previous
+//value has not yet been received, are lost, so a LeakyBucket never releases a
+//burst of values to catch up.
+//Third, Push never blocks. A value pushed to a full LeakyBucket overflows and is
+//rejected with ErrQueueFull.
+type LeakyBucket struct {
+	values *Limiter
+	ticker *time.Ticker
+
+	out chan interface{}
+}
+
+//NewLeakyBucket creates a LeakyBucket that holds at most capacity values and
+//releases one value every d.
+//NewLeakyBucket panics if d is not positive or if capacity is less than one and
+//not Unbounded, since either is a programming error.
+func NewLeakyBucket(d time.Duration, capacity int) *LeakyBucket {
+	if d <= 0 {
+		panic("ratelimit: invalid leak duration " + d.String() + ", must be positive")
+	}
+	validateCapacity(capacity)
+
+	b := &LeakyBucket{
+		values: New(0, WithCapacity(capacity)),
+		ticker: time.NewTicker(d),
+		out:    make(chan interface{}),
+	}
+	go b.leak()
+	return b
+}
+
+//Push places value in b to be released later.
+//
+//err will be ErrQueueFull if b is full, or ErrClosed if b.Close() has already
+//been called.
+func (b *LeakyBucket) Push(value interface{}) error {
+	accepted, err := b.values.TryPush(value)
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		return ErrQueueFull
+	}
+	return nil
+}
+
+//C returns the channel on which the values of b are released.
+//It is closed after b is closed and all of its values have been released.
+func (b *LeakyBucket) C() <-chan interface{} {
+	return b.out
+}
+
+//Len returns the number of values currently queued in b.
+func (b *LeakyBucket) Len() int {
+	return b.values.Len()
+}
+
+//Cap returns the maximum number of values that can be queued in b at once.
+func (b *LeakyBucket) Cap() int {
+	return b.values.Cap()
+}
+
+//Close closes b and prevents any more values from being pushed.
+//Values already in b continue to be released on the channel returned by C.
+//
+//If b is already closed, then ErrClosed is returned, otherwise err is nil.
+func (b *LeakyBucket) Close() error {
+	return b.values.Close()
+}
+
+func (b *LeakyBucket) leak() {
+	defer close(b.out)
+	defer b.ticker.Stop()
+
+	for range b.ticker.C {
+		v, ok, done := b.tryLeak()
+		if done {
+			return
+		}
+		if ok {
+			b.out <- v
+		}
+	}
+}
+
+func (b *LeakyBucket) tryLeak() (value interface{}, ok, done bool) {
+	l := b.values
+	l.lock.Lock()
+	defer l.unlockAndDeliver()
+
+	v, ok, _ := l.tryPopLocked(l.clock.Now())
+	return v, ok, !ok && l.closed && l.values.len() == 0
+}
@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeakyBucket_Push_returnsErrQueueFullWhenFull(t *testing.T) {
+	b := NewLeakyBucket(time.Duration(1)*time.Hour, 2)
+	defer b.Close()
+
+	if b.Push(0) != nil || b.Push(1) != nil {
+		t.Fail()
+	}
+
+	if err := b.Push(2); err != ErrQueueFull {
+		t.Fail()
+	}
+
+	if b.Len() != 2 || b.Cap() != 2 {
+		t.Fail()
+	}
+}
+
+func TestNewLeakyBucket_panicsOnInvalidArguments(t *testing.T) {
+	expectPanic(t, func() { NewLeakyBucket(0, 2) })
+	expectPanic(t, func() { NewLeakyBucket(-time.Second, 2) })
+	expectPanic(t, func() { NewLeakyBucket(time.Second, 0) })
+	expectPanic(t, func() { NewLeakyBucket(time.Second, -2) })
+}
+
+func TestLeakyBucket_Push_returnsErrorIfClosed(t *testing.T) {
+	b := NewLeakyBucket(time.Duration(1)*time.Hour, 2)
+	b.Close()
+
+	if err := b.Push(0); err != ErrClosed {
+		t.Fail()
+	}
+}
+
+func TestLeakyBucket_C_releasesAllValuesThenCloses(t *testing.T) {
+	b := NewLeakyBucket(time.Millisecond, 10)
+	for i := 0; i < 5; i++ {
+		b.Push(i)
+	}
+	b.Close()
+
+	want := 0
+	for v := range b.C() {
+		if v != want {
+			t.Fail()
+		}
+		want++
+	}
+
+	if want != 5 {
+		t.Fail()
+	}
+}
+
+func TestLeakyBucket_C_releasesAtAConstantInterval(t *testing.T) {
+	d := time.Duration(20) * time.Millisecond
+	b := NewLeakyBucket(d, 10)
+	for i := 0; i < 4; i++ {
+		b.Push(i)
+	}
+	b.Close()
+
+	times := []time.Time{}
+	for range b.C() {
+		times = append(times, time.Now())
+	}
+
+	for i := 0; i < len(times)-1; i++ {
+		if interval := times[i+1].Sub(times[i]); interval < d/2 {
+			t.Fatal(interval)
+		}
+	}
+}
+
+func TestLeakyBucket_differsFromLimiterWhenIdle(t *testing.T) {
+	d := time.Duration(1) * time.Hour
+
+	rl := New(d)
+	rl.Push(0)
+	if _, ok := rl.TryPop(); !ok {
+		t.Fail()
+	}
+
+	b := NewLeakyBucket(d, 1)
+	defer b.Close()
+	b.Push(0)
+	select {
+	case <-b.C():
+		t.Fail()
+	case <-time.After(time.Duration(10) * time.Millisecond):
+	}
+}
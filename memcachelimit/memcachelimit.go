@@ -0,0 +1,84 @@
+//Package memcachelimit implements a ratelimit.Store that keeps its values in
+//memcached, so that Pacers built on a ratelimit.Store, such as
+//ratelimit.StoreGCRA, share their state between processes.
+//
+//This package does not depend on a memcached client. Instead it defines the
+//small Client interface that it uses, which is implemented by adapting a
+//client. For example, with github.com/bradfitz/gomemcache:
+//
+//	type client struct {
+//		*memcache.Client
+//	}
+//
+//	func (c client) Gets(key string) ([]byte, uint64, bool, error) {
+//		item, err := c.Client.Get(key)
+//		if err == memcache.ErrCacheMiss {
+//			return nil, 0, false, nil
+//		}
+//		...
+//	}
+//
+//	g := ratelimit.NewStoreGCRA(memcachelimit.NewStore(client{mc}), "ratelimit:api", d, 4*d)
+package memcachelimit
+
+import (
+	"bytes"
+	"context"
+	"time"
+)
+
+//Client performs the memcached commands used by a Store.
+type Client interface {
+	//Gets returns the value at key, its compare-and-swap token, and whether
+	//there is one.
+	Gets(key string) (value []byte, cas uint64, ok bool, err error)
+
+	//Add stores value at key if there is no value there and reports whether it
+	//did.
+	Add(key string, value []byte, ttl time.Duration) (bool, error)
+
+	//CompareAndSwap stores value at key if its compare-and-swap token is still
+	//cas and reports whether it did.
+	CompareAndSwap(key string, value []byte, cas uint64, ttl time.Duration) (bool, error)
+}
+
+//Store is a ratelimit.Store that keeps its values in memcached.
+//Since memcached expires values in whole seconds, ttls are rounded up to whole
+//seconds.
+type Store struct {
+	client Client
+}
+
+//NewStore creates a Store that uses client.
+func NewStore(client Client) *Store {
+	return &Store{client: client}
+}
+
+//Get implements ratelimit.Store.
+//The memcached client has no notion of a context, so ctx is not used.
+func (s *Store) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, _, ok, err := s.client.Gets(key)
+	return value, ok, err
+}
+
+//CompareAndSwap implements ratelimit.Store.
+//The memcached client has no notion of a context, so ctx is not used.
+func (s *Store) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	ttl = roundUp(ttl)
+	if old == nil {
+		return s.client.Add(key, value, ttl)
+	}
+
+	current, cas, ok, err := s.client.Gets(key)
+	if err != nil || !ok || !bytes.Equal(current, old) {
+		return false, err
+	}
+	return s.client.CompareAndSwap(key, value, cas, ttl)
+}
+
+func roundUp(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return 0
+	}
+	return (ttl + time.Second - 1) / time.Second * time.Second
+}
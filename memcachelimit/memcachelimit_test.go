@@ -0,0 +1,85 @@
+package memcachelimit
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+var _ ratelimit.Store = &Store{}
+
+type fakeItem struct {
+	value []byte
+	cas   uint64
+	ttl   time.Duration
+}
+
+type fakeMemcache struct {
+	items map[string]fakeItem
+	cas   uint64
+}
+
+func newFakeMemcache() *fakeMemcache {
+	return &fakeMemcache{items: map[string]fakeItem{}}
+}
+
+func (f *fakeMemcache) Gets(key string) ([]byte, uint64, bool, error) {
+	item, ok := f.items[key]
+	return item.value, item.cas, ok, nil
+}
+
+func (f *fakeMemcache) Add(key string, value []byte, ttl time.Duration) (bool, error) {
+	if _, ok := f.items[key]; ok {
+		return false, nil
+	}
+	f.set(key, value, ttl)
+	return true, nil
+}
+
+func (f *fakeMemcache) CompareAndSwap(key string, value []byte, cas uint64, ttl time.Duration) (bool, error) {
+	if item, ok := f.items[key]; !ok || item.cas != cas {
+		return false, nil
+	}
+	f.set(key, value, ttl)
+	return true, nil
+}
+
+func (f *fakeMemcache) set(key string, value []byte, ttl time.Duration) {
+	f.cas++
+	f.items[key] = fakeItem{value: value, cas: f.cas, ttl: ttl}
+}
+
+func TestStore_CompareAndSwap_onlySwapsTheCurrentValue(t *testing.T) {
+	f := newFakeMemcache()
+	s := NewStore(f)
+	ctx := context.Background()
+
+	if ok, _ := s.CompareAndSwap(ctx, "key", nil, []byte("a"), 0); !ok {
+		t.Fatal("absent value should be swapped")
+	}
+	if ok, _ := s.CompareAndSwap(ctx, "key", nil, []byte("b"), 0); ok {
+		t.Fatal("present value should not be swapped as absent")
+	}
+	if ok, _ := s.CompareAndSwap(ctx, "key", []byte("b"), []byte("c"), 0); ok {
+		t.Fatal("different value should not be swapped")
+	}
+	if ok, _ := s.CompareAndSwap(ctx, "key", []byte("a"), []byte("c"), 0); !ok {
+		t.Fatal("current value should be swapped")
+	}
+	if value, ok, _ := s.Get(ctx, "key"); !ok || !bytes.Equal(value, []byte("c")) {
+		t.Fatal(string(value), ok)
+	}
+}
+
+func TestStore_CompareAndSwap_roundsTTLsUpToSeconds(t *testing.T) {
+	f := newFakeMemcache()
+	s := NewStore(f)
+	s.CompareAndSwap(context.Background(), "key", nil, []byte("a"), time.Duration(1500)*time.Millisecond)
+
+	if ttl := f.items["key"].ttl; ttl != time.Duration(2)*time.Second {
+		t.Fatal(ttl)
+	}
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+//PopInfo describes how a value released by PopMeta got through a Limiter.
+type PopInfo struct {
+	//Pushed is when the value was pushed, and Popped is when it was released.
+	Pushed time.Time
+	Popped time.Time
+
+	//Wait is how long the value waited in total, from Pushed to Popped.
+	Wait time.Duration
+
+	//Queued is how long of Wait the value spent behind other values, and Paced
+	//is how long it then spent as the next value held back by the rate.
+	//Queued and Paced add up to Wait.
+	Queued time.Duration
+	Paced  time.Duration
+
+	//Position is the number of values that were queued when the value was
+	//pushed. Values with a higher priority pushed later may still have been
+	//released before it.
+	//Only the position at push is recorded, not how it changed while the value
+	//waited, since that would cost every queued value work on every release.
+	Position int
+
+	//Priority is the priority the value was pushed with.
+	Priority int
+}
+
+//PopMeta releases a value from l just like PopOk, and also returns a PopInfo
+//describing the time the value spent in l.
+//This lets consumers discard values that waited too long to still be useful.
+func (l *Limiter) PopMeta() (value interface{}, meta PopInfo, ok bool) {
+	it, released, err := l.popItem(context.Background(), time.Time{})
+	if err != nil {
+		return nil, PopInfo{}, false
+	}
+	return it.value, newPopInfo(it, released), true
+}
+
+func newPopInfo(it item, released time.Time) PopInfo {
+	next := it.pushed
+	if it.previous.After(next) {
+		next = it.previous
+	}
+	return PopInfo{
+		Pushed:   it.pushed,
+		Popped:   released,
+		Wait:     released.Sub(it.pushed),
+		Queued:   next.Sub(it.pushed),
+		Paced:    released.Sub(next),
+		Position: it.position,
+		Priority: it.priority,
+	}
+}
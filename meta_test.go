@@ -0,0 +1,39 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_PopMeta_describesTheWait(t *testing.T) {
+	d := time.Duration(20) * time.Millisecond
+	l := New(d, WithCapacity(Unbounded))
+	l.PushAll(1, 2)
+	l.PushPriority(3, -1)
+
+	_, first, _ := l.PopMeta()
+	value, meta, ok := l.PopMeta()
+
+	if !ok || value != 2 || meta.Position != 1 || meta.Priority != 0 {
+		t.Fatal(value, meta, ok)
+	}
+	if meta.Wait != meta.Popped.Sub(meta.Pushed) || meta.Queued+meta.Paced != meta.Wait {
+		t.Fatal(meta)
+	}
+	if meta.Paced < d/2 || meta.Queued != first.Popped.Sub(meta.Pushed) {
+		t.Fatal(meta)
+	}
+
+	if value, meta, _ := l.PopMeta(); value != 3 || meta.Position != 2 || meta.Priority != -1 {
+		t.Fatal(value, meta)
+	}
+}
+
+func TestLimiter_PopMeta_returnsNotOkWhenClosed(t *testing.T) {
+	l := New(time.Duration(1))
+	l.Close()
+
+	if value, meta, ok := l.PopMeta(); value != nil || ok || !meta.Pushed.IsZero() {
+		t.Fail()
+	}
+}
@@ -0,0 +1,183 @@
+package ratelimit
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+)
+
+//monitor guards the state of a queue of values and allows goroutines to wait for
+//that state to change.
+type monitor struct {
+	lock  *sync.Mutex
+	clock Clock
+
+	//changed is closed whenever the guarded state changes so that waiting
+	//goroutines can re-evaluate it.
+	//It is only created once a goroutine waits, so that the many changes nobody
+	//is waiting for, such as pushes while consumers are busy, neither allocate
+	//nor contend on waking anyone.
+	changed chan struct{}
+
+	//timers holds stopped Timers of clock for reuse by later waits, so that
+	//pacing a steady stream of waits does not allocate a Timer for each one.
+	timers []Timer
+
+	//spin is how long before the end of a wait the waiting goroutine stops
+	//sleeping and busy-waits instead, set with WithHighResolution.
+	spin time.Duration
+}
+
+//maxFreeTimers is the most stopped Timers a monitor keeps for reuse.
+const maxFreeTimers = 8
+
+func newMonitor() monitor {
+	return monitor{
+		lock:  &sync.Mutex{},
+		clock: SystemClock,
+	}
+}
+
+//pollLocked calls try until it returns ok or a non-nil error.
+//Between calls it waits for the guarded state to change, for the wait duration
+//returned by try to pass (if positive), or for ctx to be done.
+//The wait never sleeps with m.lock held and its timer is stopped as soon as the
+//wait ends, so a wait for a long rate is cut short by any broadcast, such as
+//from SetDuration, Reset, or Close, and by ctx.
+func (m *monitor) pollLocked(ctx context.Context, try func(now time.Time) (ok bool, wait time.Duration, err error)) error {
+	return m.pollUntilLocked(ctx, time.Time{}, try)
+}
+
+//pollUntilLocked works like pollLocked, but also gives up and returns
+//context.DeadlineExceeded once deadline has passed, unless deadline is zero.
+//Unlike a context with a deadline, the deadline follows m.clock and shares the
+//reused timers of m, so it costs no allocations.
+func (m *monitor) pollUntilLocked(ctx context.Context, deadline time.Time, try func(now time.Time) (ok bool, wait time.Duration, err error)) error {
+	for {
+		now := m.clock.Now()
+		ok, wait, err := try(now)
+		if ok || err != nil {
+			return err
+		}
+
+		if !deadline.IsZero() {
+			remaining := deadline.Sub(now)
+			if remaining <= 0 {
+				return context.DeadlineExceeded
+			}
+			if wait <= 0 || remaining < wait {
+				wait = remaining
+			}
+		}
+		if m.spin > 0 && wait > 0 {
+			if wait <= m.spin {
+				if err := m.spinLocked(ctx, now.Add(wait)); err != nil {
+					return err
+				}
+				continue
+			}
+			wait -= m.spin
+		}
+		if err := m.sleepLocked(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+//sleepLocked works like waitLocked, but also stops waiting once wait has passed
+//if it is positive.
+func (m *monitor) sleepLocked(ctx context.Context, wait time.Duration) error {
+	if wait <= 0 {
+		return m.waitLocked(ctx, nil)
+	}
+
+	timer := m.timerLocked(wait)
+	err := m.waitLocked(ctx, timer.C())
+	m.recycleLocked(timer)
+	return err
+}
+
+//timerLocked returns a Timer that fires once d has passed, reusing a stopped one
+//if there is one.
+func (m *monitor) timerLocked(d time.Duration) Timer {
+	n := len(m.timers)
+	if n == 0 {
+		return m.clock.NewTimer(d)
+	}
+	timer := m.timers[n-1]
+	m.timers[n-1] = nil
+	m.timers = m.timers[:n-1]
+	timer.Reset(d)
+	return timer
+}
+
+//recycleLocked stops timer and keeps it for reuse by timerLocked.
+func (m *monitor) recycleLocked(timer Timer) {
+	if !timer.Stop() {
+		//The timer fired without its value being received, so drain it to
+		//not end the wait that reuses it early.
+		select {
+		case <-timer.C():
+		default:
+		}
+	}
+	if len(m.timers) < maxFreeTimers {
+		m.timers = append(m.timers, timer)
+	}
+}
+
+//waitLocked releases m.lock until the guarded state changes, expired receives,
+//or ctx is done, and then reacquires it.
+//The returned error is non-nil only if ctx is done.
+func (m *monitor) waitLocked(ctx context.Context, expired <-chan time.Time) error {
+	changed := m.changedLocked()
+	m.lock.Unlock()
+	defer m.lock.Lock()
+
+	select {
+	case <-changed:
+		return nil
+	case <-expired:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+//spinLocked works like waitLocked, but busy-waits until the time of m.clock
+//reaches until instead of waiting for a timer, yielding the processor between
+//checks.
+func (m *monitor) spinLocked(ctx context.Context, until time.Time) error {
+	changed := m.changedLocked()
+	m.lock.Unlock()
+	defer m.lock.Lock()
+
+	for m.clock.Now().Before(until) {
+		select {
+		case <-changed:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		runtime.Gosched()
+	}
+	return nil
+}
+
+//changedLocked returns the channel that is closed by the next broadcast.
+func (m *monitor) changedLocked() chan struct{} {
+	if m.changed == nil {
+		m.changed = make(chan struct{})
+	}
+	return m.changed
+}
+
+//broadcastLocked wakes all goroutines currently in waitLocked.
+func (m *monitor) broadcastLocked() {
+	if m.changed != nil {
+		close(m.changed)
+		m.changed = nil
+	}
+}
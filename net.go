@@ -0,0 +1,60 @@
+package ratelimit
+
+import "net"
+
+type conn struct {
+	net.Conn
+
+	read  *bandwidth
+	write *bandwidth
+}
+
+//NewConn returns a net.Conn that reads from c no faster than readBytesPerSec and
+//writes to c no faster than writeBytesPerSec bytes per second, just like the
+//io.Reader and io.Writer returned by NewReader and NewWriter.
+//A rate that is not positive does not limit that direction.
+func NewConn(c net.Conn, readBytesPerSec, writeBytesPerSec int) net.Conn {
+	result := &conn{Conn: c}
+	if readBytesPerSec > 0 {
+		result.read = newBandwidth(readBytesPerSec)
+	}
+	if writeBytesPerSec > 0 {
+		result.write = newBandwidth(writeBytesPerSec)
+	}
+	return result
+}
+
+func (c *conn) Read(p []byte) (n int, err error) {
+	if c.read == nil {
+		return c.Conn.Read(p)
+	}
+	return (&reader{r: c.Conn, b: c.read}).Read(p)
+}
+
+func (c *conn) Write(p []byte) (n int, err error) {
+	if c.write == nil {
+		return c.Conn.Write(p)
+	}
+	return (&writer{w: c.Conn, b: c.write}).Write(p)
+}
+
+type listener struct {
+	net.Listener
+
+	l *Limiter
+}
+
+//NewListener returns a net.Listener that accepts connections from ln no faster
+//than l permits.
+//Each call to Accept waits for a release from l before accepting a connection.
+func NewListener(ln net.Listener, l *Limiter) net.Listener {
+	return &listener{
+		Listener: ln,
+		l:        l,
+	}
+}
+
+func (ln *listener) Accept() (net.Conn, error) {
+	ln.l.Wait()
+	return ln.Listener.Accept()
+}
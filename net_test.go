@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNewConn_limitsReadsAndWrites(t *testing.T) {
+	client, server := net.Pipe()
+	c := NewConn(client, 0, 100)
+	go func() {
+		c.Write(make([]byte, 25))
+		c.Close()
+	}()
+
+	n, _ := server.Read(make([]byte, 25))
+
+	if n != 10 {
+		t.Fatal(n)
+	}
+	server.Close()
+}
+
+func TestNewConn_readsAtMostAChunk(t *testing.T) {
+	client, server := net.Pipe()
+	c := NewConn(client, 50, 0)
+	go func() {
+		server.Write(make([]byte, 25))
+		server.Close()
+	}()
+
+	n, _ := c.Read(make([]byte, 25))
+
+	if n != 5 {
+		t.Fatal(n)
+	}
+	c.Close()
+}
+
+func TestNewListener_pacesAccept(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	l := New(time.Duration(1) * time.Hour)
+	ln := NewListener(inner, l)
+	defer ln.Close()
+
+	go net.Dial("tcp", inner.Addr().String())
+	if c, err := ln.Accept(); err != nil {
+		t.Fatal(err)
+	} else {
+		c.Close()
+	}
+
+	if l.Allow() {
+		t.Fail()
+	}
+}
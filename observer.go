@@ -0,0 +1,125 @@
+package ratelimit
+
+import "time"
+
+//Observer is notified of the events of a Limiter it is added to with
+//WithObserver, for example to collect metrics.
+//
+//The methods of an Observer are called while the Limiter is locked, so they must
+//be fast and must not use the Limiter.
+type Observer interface {
+	//Pushed is called when value is pushed.
+	Pushed(value interface{})
+
+	//Popped is called when value is released, along with how long value was
+	//queued.
+	Popped(value interface{}, wait time.Duration)
+
+	//Dropped is called when value is removed without being released by
+	//CloseDiscard, Drain, Reset, Item.Cancel, the Overflow policy of the
+	//Limiter, or because it expired.
+	Dropped(value interface{})
+
+	//Closed is called when the Limiter is closed.
+	Closed()
+}
+
+//WithObserver adds o to the Observers notified of the events of a Limiter.
+func WithObserver(o Observer) Option {
+	return func(l *Limiter) {
+		l.observers = append(l.observers, o)
+	}
+}
+
+func (l *Limiter) observePushedLocked(it item) {
+	l.stats.pushed++
+	if l.values.len() == 1 {
+		l.scheduleStaleLocked(it.pushed)
+	}
+	for _, o := range l.observers {
+		o.Pushed(it.value)
+	}
+	for _, h := range l.hooks {
+		if h.OnPush != nil {
+			h.OnPush(Event{Value: it.value, Pushed: it.pushed, Time: it.pushed})
+		}
+	}
+}
+
+func (l *Limiter) observePoppedLocked(it item, now time.Time) {
+	l.stats.popped++
+	l.stats.waits.record(now.Sub(it.pushed))
+	l.stats.pops.record(now.Sub(l.stats.created))
+	l.scheduleStaleLocked(now)
+	for _, o := range l.observers {
+		o.Popped(it.value, now.Sub(it.pushed))
+	}
+	for _, h := range l.hooks {
+		if h.OnPop != nil {
+			h.OnPop(newEvent(it, now))
+		}
+	}
+}
+
+//observeFullLocked notifies the Hooks of l that it was pushed to at capacity,
+//and returns the function set with OnSaturated to be called once l is unlocked.
+//A push that blocks is only reported once.
+func (l *Limiter) observeFullLocked(it item) func(*Limiter) {
+	if len(l.hooks) > 0 {
+		e := Event{Value: it.value, Time: l.clock.Now()}
+		for _, h := range l.hooks {
+			if h.OnFull != nil {
+				h.OnFull(e)
+			}
+		}
+	}
+	return l.onSaturated
+}
+
+func (l *Limiter) observeClosedLocked() {
+	for _, o := range l.observers {
+		o.Closed()
+	}
+	if len(l.hooks) == 0 {
+		return
+	}
+	e := Event{Time: l.clock.Now()}
+	for _, h := range l.hooks {
+		if h.OnClose != nil {
+			h.OnClose(e)
+		}
+	}
+}
+
+//discardLocked removes all values from l, counting them as dropped and notifying
+//the Observers and Hooks of l, and queueing them as dead letters.
+func (l *Limiter) discardLocked() {
+	if len(l.observers) == 0 && len(l.hooks) == 0 && l.deadLetter == nil && l.deadLetters == nil {
+		l.stats.dropped += uint64(l.values.len())
+		l.values.clear()
+		l.scheduleStaleLocked(l.clock.Now())
+		return
+	}
+	l.dropLocked(Discarded, l.values.drain()...)
+}
+
+func (l *Limiter) observeDroppedLocked(items []item) {
+	l.stats.dropped += uint64(len(items))
+	l.scheduleStaleLocked(l.clock.Now())
+	for _, it := range items {
+		for _, o := range l.observers {
+			o.Dropped(it.value)
+		}
+	}
+	if len(l.hooks) == 0 {
+		return
+	}
+	now := l.clock.Now()
+	for _, it := range items {
+		for _, h := range l.hooks {
+			if h.OnDrop != nil {
+				h.OnDrop(newEvent(it, now))
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+//recorder is an Observer that records the events it is notified of.
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) Pushed(value interface{}) {
+	r.events = append(r.events, fmt.Sprint("push ", value))
+}
+
+func (r *recorder) Popped(value interface{}, wait time.Duration) {
+	r.events = append(r.events, fmt.Sprint("pop ", value))
+}
+
+func (r *recorder) Dropped(value interface{}) {
+	r.events = append(r.events, fmt.Sprint("drop ", value))
+}
+
+func (r *recorder) Closed() {
+	r.events = append(r.events, "close")
+}
+
+func (r *recorder) String() string {
+	return fmt.Sprint(r.events)
+}
+
+func TestWithObserver_notifiesOfEvents(t *testing.T) {
+	r := &recorder{}
+	rl := New(time.Duration(1), WithCapacity(Unbounded), WithObserver(r))
+
+	rl.PushAll(1, 2, 3)
+	rl.Pop()
+	rl.Drain()
+	rl.Reset()
+	rl.Push(4)
+	rl.CloseDiscard()
+	rl.Close()
+
+	if r.String() != "[push 1 push 2 push 3 pop 1 close drop 2 drop 3 push 4 close drop 4]" {
+		t.Fatal(r)
+	}
+}
+
+//waitRecorder is an Observer that records how long popped values waited.
+type waitRecorder struct {
+	recorder
+	waits []time.Duration
+}
+
+func (r *waitRecorder) Popped(value interface{}, wait time.Duration) {
+	r.waits = append(r.waits, wait)
+}
+
+func TestWithObserver_reportsHowLongValuesWereQueued(t *testing.T) {
+	r := &waitRecorder{}
+	rl := New(time.Duration(20)*time.Millisecond, WithCapacity(2), WithObserver(r))
+	rl.Push(1)
+	rl.Push(2)
+
+	rl.Pop()
+	rl.Pop()
+
+	if len(r.waits) != 2 || r.waits[1] < time.Duration(15)*time.Millisecond {
+		t.Fatal(r.waits)
+	}
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"math/rand"
+	"time"
+)
+
+//Option configures a Limiter created with New.
+type Option func(l *Limiter)
+
+//WithCapacity sets the capacity of a Limiter, the maximum number of values that
+//can be queued in it at once.
+//capacity may be Unbounded.
+func WithCapacity(capacity int) Option {
+	return func(l *Limiter) {
+		l.capacity = capacity
+	}
+}
+
+//WithAging enables aging of the priorities of values pushed with PushPriority.
+//The effective priority of a queued value increases by one for every d that it
+//has been queued, so low priority values are eventually released even while
+//higher priority values keep being pushed.
+func WithAging(d time.Duration) Option {
+	return func(l *Limiter) {
+		l.values.aging = d
+	}
+}
+
+//WithBurst sets the pacer of a Limiter to a TokenBucket that holds burst tokens
+//and refills at the throughput duration of the Limiter.
+//This allows up to burst values to be released back-to-back after the Limiter
+//has been idle.
+func WithBurst(burst int) Option {
+	return func(l *Limiter) {
+		l.pacer = NewTokenBucket(l.d, burst)
+	}
+}
+
+//WithOverdraft sets the pacer of a Limiter to a TokenBucket that holds burst
+//tokens, refills at the throughput duration of the Limiter, and may be
+//overdrawn by up to overdraft tokens.
+//See NewOverdraftBucket.
+func WithOverdraft(burst, overdraft int) Option {
+	return func(l *Limiter) {
+		l.pacer = NewOverdraftBucket(l.d, burst, overdraft)
+	}
+}
+
+//WithCatchUp makes a Limiter catch up on releases it did not use while idle.
+//By default, pacing is strict: a value is never released sooner than the
+//throughput duration after the previous release, so any time a Limiter spends
+//idle is budget lost.
+//With catch-up pacing, releases are scheduled once every throughput duration and
+//releases whose scheduled time passed within the last window may still happen,
+//so several queued values may be released back-to-back after an idle period.
+//Catching up therefore exceeds the rate of the Limiter by up to window/d + 1
+//values, where d is the throughput duration: that many may be released at once
+//after an idle period, and over any period of length T at most (T+window)/d + 1
+//values are released.
+func WithCatchUp(window time.Duration) Option {
+	return func(l *Limiter) {
+		l.pacer = newCatchUpPacer(l.d, window)
+	}
+}
+
+//WithPacer sets the Pacer that decides when the values of a Limiter are
+//released.
+func WithPacer(p Pacer) Option {
+	return func(l *Limiter) {
+		l.pacer = p
+	}
+}
+
+//WithJitter randomizes each release time of a Limiter within ±fraction of its
+//throughput duration.
+//This prevents many clients using the same rate from synchronizing their
+//releases. Jitter applies to whichever pacer the Limiter ends up with.
+//
+//The randomness comes from a source seeded with the current time unless
+//WithRandSource is used.
+func WithJitter(fraction float64) Option {
+	return func(l *Limiter) {
+		l.jitter = fraction
+	}
+}
+
+//WithRandSource sets the source of randomness used by WithJitter.
+//Using a source with a fixed seed makes the jitter deterministic, for example in
+//tests.
+func WithRandSource(src rand.Source) Option {
+	return func(l *Limiter) {
+		l.source = src
+	}
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestWithCapacity_setsTheCapacity(t *testing.T) {
+	rl := New(time.Duration(1), WithCapacity(10))
+
+	if rl.Cap() != 10 {
+		t.Fail()
+	}
+
+	if n, _ := rl.TryPushAll(make([]interface{}, 11)...); n != 10 {
+		t.Fail()
+	}
+}
+
+func TestWithCapacity_lastOptionWins(t *testing.T) {
+	rl := New(time.Duration(1), WithCapacity(10), WithCapacity(Unbounded))
+
+	if rl.Cap() != Unbounded {
+		t.Fail()
+	}
+}
+
+func TestWithBurst_allowsBurstValuesBackToBack(t *testing.T) {
+	rl := New(time.Duration(1)*time.Hour, WithCapacity(4), WithBurst(3))
+	rl.PushAll(0, 1, 2, 3)
+
+	if values := rl.PopAvailable(); len(values) != 3 {
+		t.Fail()
+	}
+
+	if _, ok := rl.TryPop(); ok {
+		t.Fail()
+	}
+}
+
+func TestWithPacer_setsThePacer(t *testing.T) {
+	b := NewTokenBucket(time.Duration(1)*time.Hour, 2)
+	rl := New(time.Duration(1), WithCapacity(3), WithPacer(b))
+	rl.PushAll(0, 1, 2)
+
+	if values := rl.PopAvailable(); len(values) != 2 {
+		t.Fail()
+	}
+
+	if b.AllowN(time.Now(), 1) {
+		t.Fail()
+	}
+}
+
+func TestWithPacer_acceptsALimiter(t *testing.T) {
+	parent := New(time.Duration(1) * time.Hour)
+	rl := New(time.Duration(1), WithCapacity(2), WithPacer(parent))
+	rl.PushAll(0, 1)
+
+	if values := rl.PopAvailable(); len(values) != 1 {
+		t.Fail()
+	}
+
+	if parent.Allow() {
+		t.Fail()
+	}
+}
+
+func TestWithAging_preventsStarvationOfLowPriorities(t *testing.T) {
+	rl := New(time.Duration(1), WithCapacity(Unbounded), WithAging(time.Millisecond))
+	rl.Push("low")
+	time.Sleep(time.Duration(5) * time.Millisecond)
+	rl.PushPriority("high", 1)
+
+	if v := rl.Pop(); v != "low" {
+		t.Fail()
+	}
+}
+
+func TestWithJitter_wrapsTheFinalPacer(t *testing.T) {
+	rl := New(time.Duration(1)*time.Hour, WithJitter(0.1), WithBurst(2), WithRandSource(rand.NewSource(1)))
+
+	j, ok := rl.pacer.(*jitterPacer)
+	if !ok {
+		t.Fatal(rl.pacer)
+	}
+
+	if _, ok := j.pacer.(*TokenBucket); !ok || j.fraction != 0.1 {
+		t.Fail()
+	}
+
+	if !rl.Allow() {
+		t.Fail()
+	}
+}
+
+func TestWithOverdraft_setsAnOverdraftBucket(t *testing.T) {
+	rl := New(time.Duration(1)*time.Hour, WithOverdraft(1, 2))
+
+	if !rl.Allow() || !rl.Allow() || !rl.Allow() || rl.Allow() {
+		t.Fail()
+	}
+}
+
+func TestWithCatchUp_setsACatchUpPacer(t *testing.T) {
+	rl := New(time.Duration(1)*time.Hour, WithCatchUp(time.Duration(2)*time.Hour))
+
+	if p, ok := rl.pacer.(*catchUpPacer); !ok || p.window != time.Duration(2)*time.Hour {
+		t.Fail()
+	}
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+//Overflow is the policy a Limiter follows when a value is pushed while it is at
+//capacity, set with WithOverflow.
+type Overflow int
+
+const (
+	//Block makes a push wait for space, or TryPush refuse the value. It is the
+	//default.
+	Block Overflow = iota
+
+	//DropOldest makes a push evict the value that has been queued the longest,
+	//regardless of its priority, to make space for the new value, so pushing
+	//never blocks. This suits telemetry, where fresh values matter more than old
+	//ones.
+	DropOldest
+
+	//DropNewest makes a push silently drop the value being pushed, so Push
+	//returns nil and TryPush returns accepted false without an error. The values
+	//already queued are kept. The dropped value is only passed to the dead-letter
+	//function, and is not counted as pushed or dropped.
+	DropNewest
+
+	//Reject makes a push return ErrQueueFull immediately instead of waiting for
+	//space.
+	Reject
+)
+
+//WithOverflow sets the policy a Limiter follows when a value is pushed while it
+//is at capacity.
+//A push at capacity is still reported to OnSaturated and to the OnFull hook
+//under every policy but DropOldest, which always makes space.
+//Values evicted by DropOldest are reported to Observers and Hooks as dropped and
+//counted in Stats.Dropped. Values refused by DropNewest were never queued, so
+//they are not. Both are passed to the function set with WithDeadLetter.
+func WithOverflow(policy Overflow) Option {
+	return func(l *Limiter) {
+		l.overflow = policy
+	}
+}
+
+//makeSpaceLocked discards the expired values of l and then evicts values
+//according to its Overflow policy until there is space for another value,
+//reporting whether there is.
+func (l *Limiter) makeSpaceLocked() bool {
+	if !l.hasSpaceLocked() {
+		l.expireLocked(l.clock.Now())
+	}
+	if l.overflow != DropOldest {
+		return l.hasSpaceLocked()
+	}
+	for !l.hasSpaceLocked() && l.values.len() > 0 {
+		l.dropLocked(Overflowed, l.values.popOldest())
+	}
+	return l.hasSpaceLocked()
+}
+
+//overflowLocked applies the Overflow policy of l to it, which could not be
+//pushed because l is full.
+//done reports whether the push of it is finished with err, rather than waiting
+//for space or being refused.
+func (l *Limiter) overflowLocked(it item) (done bool, err error) {
+	switch l.overflow {
+	case DropNewest:
+		//it was never queued, so it is not reported as dropped from the queue,
+		//which would make Pushed - Popped - Dropped disagree with Len.
+		it.pushed = l.clock.Now()
+		l.deadLetterLocked(Overflowed, it)
+		return true, nil
+	case Reject:
+		return true, ErrQueueFull
+	}
+	return false, nil
+}
@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithOverflow_DropOldest_evictsTheOldestValueInsteadOfBlocking(t *testing.T) {
+	l := New(time.Duration(1), WithCapacity(2), WithOverflow(DropOldest))
+	l.Push(1)
+	l.Push(2)
+
+	if err := l.Push(3); err != nil {
+		t.Fatal(err)
+	}
+	if accepted, err := l.TryPush(4); !accepted || err != nil {
+		t.Fatal(accepted, err)
+	}
+
+	if got := l.PopN(2); !reflect.DeepEqual(got, []interface{}{3, 4}) {
+		t.Fatal(got)
+	}
+	if stats := l.Stats(); stats.Dropped != 2 {
+		t.Fatal(stats.Dropped)
+	}
+}
+
+func TestWithOverflow_DropOldest_evictsByAgeRegardlessOfPriority(t *testing.T) {
+	l := New(time.Duration(1), WithCapacity(2), WithOverflow(DropOldest))
+	l.PushPriority("high", 10)
+	l.Push("low")
+
+	l.Push("new")
+
+	if got := l.PopN(2); !reflect.DeepEqual(got, []interface{}{"low", "new"}) {
+		t.Fatal(got)
+	}
+}
+
+func TestWithOverflow_DropOldest_shrinksToALoweredCapacity(t *testing.T) {
+	l := New(time.Duration(1), WithCapacity(3), WithOverflow(DropOldest))
+	l.PushAll(1, 2, 3)
+	l.SetCapacity(1)
+
+	l.Push(4)
+
+	if got := l.Drain(); !reflect.DeepEqual(got, []interface{}{4}) {
+		t.Fatal(got)
+	}
+}
+
+func TestWithOverflow_DropNewest_dropsTheValueBeingPushed(t *testing.T) {
+	var dropped []interface{}
+	l := New(time.Duration(1), WithCapacity(2), WithOverflow(DropNewest), WithDeadLetter(func(d DroppedValue) {
+		dropped = append(dropped, d.Value)
+	}))
+	l.Push(1)
+
+	if err := l.Push(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Push(3); err != nil {
+		t.Fatal(err)
+	}
+	if accepted, err := l.TryPush(4); accepted || err != nil {
+		t.Fatal(accepted, err)
+	}
+	if n, err := l.TryPushAll(5, 6); n != 0 || err != nil {
+		t.Fatal(n, err)
+	}
+
+	if got := l.Drain(); !reflect.DeepEqual(got, []interface{}{1, 2}) {
+		t.Fatal(got)
+	}
+	if !reflect.DeepEqual(dropped, []interface{}{3, 4, 5, 6}) {
+		t.Fatal(dropped)
+	}
+	if stats := l.Stats(); stats.Pushed != 2 || stats.Dropped != 2 {
+		t.Fatal(stats.Pushed, stats.Dropped)
+	}
+}
+
+func TestWithOverflow_Reject_returnsErrQueueFullImmediately(t *testing.T) {
+	l := New(time.Duration(1)*time.Hour, WithOverflow(Reject))
+	l.Push(1)
+
+	if err := l.Push(2); err != ErrQueueFull {
+		t.Fatal(err)
+	}
+	if err := l.PushTimeout(2, time.Duration(1)*time.Hour); err != ErrQueueFull {
+		t.Fatal(err)
+	}
+	if accepted, err := l.TryPush(2); accepted || err != ErrQueueFull {
+		t.Fatal(accepted, err)
+	}
+	if n, err := l.PushAll(2, 3); n != 0 || err != ErrQueueFull {
+		t.Fatal(n, err)
+	}
+	if n, err := l.TryPushAll(2, 3); n != 0 || err != ErrQueueFull {
+		t.Fatal(n, err)
+	}
+	if l.Len() != 1 {
+		t.Fatal(l.Len())
+	}
+}
+
+func TestWithOverflow_Reject_stillReportsSaturation(t *testing.T) {
+	calls := 0
+	l := New(time.Duration(1), WithOverflow(Reject))
+	l.OnSaturated(func(l *Limiter) {
+		calls++
+	})
+	l.Push(1)
+
+	l.Push(2)
+	l.TryPush(3)
+
+	if calls != 2 {
+		t.Fatal(calls)
+	}
+}
+
+func TestWithOverflow_Block_isTheDefault(t *testing.T) {
+	l := New(time.Duration(1), WithOverflow(Block))
+	l.Push(1)
+
+	if accepted, _ := l.TryPush(2); accepted {
+		t.Fatal("should not accept a value at capacity")
+	}
+	if err := l.PushTimeout(2, time.Duration(10)*time.Millisecond); err != ErrTimeout {
+		t.Fatal(err)
+	}
+}
+
+func TestWithDeadLetter_receivesEvictedValuesWithoutTheLockHeld(t *testing.T) {
+	var l *Limiter
+	var dropped []DroppedValue
+	l = New(time.Duration(1), WithCapacity(2), WithOverflow(DropOldest), WithDeadLetter(func(d DroppedValue) {
+		if l.Len() != 2 {
+			t.Error("should be able to use the limiter")
+		}
+		dropped = append(dropped, d)
+	}))
+	l.PushAll(1, 2)
+
+	l.Push(3)
+	l.TryPushAll(4, 5)
+
+	if len(dropped) != 3 {
+		t.Fatal(dropped)
+	}
+	for i, d := range dropped {
+		if d.Value != i+1 || d.Reason != Overflowed || d.Pushed.IsZero() || d.Time.Before(d.Pushed) {
+			t.Fatal(i, d)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+//Pacer decides when the releases of a Limiter are permitted.
+//
+//AllowN and DelayN must agree with each other: DelayN must return a positive
+//duration whenever AllowN would return false for the same arguments.
+//Implementations must be safe for concurrent use.
+type Pacer interface {
+	//AllowN reports whether n releases may happen at time t.
+	//If they may, then they are counted by the Pacer.
+	AllowN(t time.Time, n int) bool
+
+	//DelayN returns how long after t it will be before n releases may happen,
+	//or zero if they may happen at t.
+	//The releases are not counted by the Pacer.
+	DelayN(t time.Time, n int) time.Duration
+}
+
+type durationSetter interface {
+	SetDuration(d time.Duration)
+}
+
+//durationAtSetter is implemented by the pacers whose SetDuration depends on the
+//current time, so that they can be given the time of the Clock of their Limiter.
+type durationAtSetter interface {
+	setDurationAt(t time.Time, d time.Duration)
+}
+
+//setPacerDuration changes the throughput duration of p to d at time t, if p has
+//a way to.
+func setPacerDuration(p Pacer, t time.Time, d time.Duration) {
+	switch setter := p.(type) {
+	case durationAtSetter:
+		setter.setDurationAt(t, d)
+	case durationSetter:
+		setter.SetDuration(d)
+	}
+}
+
+type resetter interface {
+	Reset()
+}
+
+//clocked is implemented by the pacers that tell the time with a Clock of their
+//own, such as a Limiter created with WithClock.
+type clocked interface {
+	pacerClock() Clock
+}
+
+//clockOf returns the Clock whose times should be passed to p.
+func clockOf(p Pacer) Clock {
+	if c, ok := p.(clocked); ok {
+		return c.pacerClock()
+	}
+	return SystemClock
+}
+
+//waitPacer blocks until p permits n releases, counting them, or until ctx is
+//done, in which case ctx.Err() is returned.
+func waitPacer(ctx context.Context, p Pacer, n int) error {
+	clock := clockOf(p)
+	var timer Timer
+	for {
+		now := clock.Now()
+		if p.AllowN(now, n) {
+			return nil
+		}
+
+		delay := p.DelayN(now, n)
+		if timer == nil {
+			timer = clock.NewTimer(delay)
+			defer timer.Stop()
+		} else {
+			timer.Reset(delay)
+		}
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
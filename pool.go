@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//Pool runs tasks with a fixed number of workers no faster than the rate of a
+//Limiter.
+//At most one task is started every throughput duration and no more tasks than
+//there are workers run at once.
+type Pool struct {
+	l     *Limiter
+	group *sync.WaitGroup
+}
+
+//NewPool creates a Pool with workers workers whose tasks are queued in a
+//Limiter with throughput duration d configured by opts.
+//The capacity of the Limiter bounds the number of tasks waiting to run.
+//NewPool panics if workers is less than one, since no task would ever run.
+func NewPool(d time.Duration, workers int, opts ...Option) *Pool {
+	if workers < 1 {
+		panic("ratelimit: invalid number of workers " + strconv.Itoa(workers) + ", must be at least 1")
+	}
+	p := &Pool{
+		l:     New(d, opts...),
+		group: &sync.WaitGroup{},
+	}
+
+	p.group.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *Pool) work() {
+	defer p.group.Done()
+
+	for task, ok := p.l.PopOk(); ok; task, ok = p.l.PopOk() {
+		task.(func())()
+	}
+}
+
+//Submit queues task to be run by a worker of p.
+//Submit does not return until there is space in p to queue task.
+//
+//err will be ErrClosed if p.Shutdown() has already been called.
+func (p *Pool) Submit(task func()) error {
+	return p.l.Push(task)
+}
+
+//TrySubmit attempts to queue task without blocking.
+//accepted is false if there is no space in p to queue task.
+//
+//err will be ErrClosed if p.Shutdown() has already been called.
+func (p *Pool) TrySubmit(task func()) (accepted bool, err error) {
+	return p.l.TryPush(task)
+}
+
+//Len returns the number of tasks waiting to run in p.
+func (p *Pool) Len() int {
+	return p.l.Len()
+}
+
+//Shutdown stops p from accepting tasks and waits for all tasks already queued to
+//run and return.
+//If ctx is done first, then ctx.Err() is returned while the remaining tasks keep
+//running in the background.
+//
+//If p is already shut down, then ErrClosed is returned.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	if err := p.l.Close(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.group.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool_runsAllTasksBeforeShutdownReturns(t *testing.T) {
+	p := NewPool(time.Duration(1), 3, WithCapacity(Unbounded))
+	count := int32(0)
+	for i := 0; i < 10; i++ {
+		p.Submit(func() {
+			atomic.AddInt32(&count, 1)
+		})
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil || atomic.LoadInt32(&count) != 10 {
+		t.Fail()
+	}
+	if p.Submit(func() {}) != ErrClosed || p.Shutdown(context.Background()) != ErrClosed {
+		t.Fail()
+	}
+}
+
+func TestPool_runsNoMoreTasksThanWorkersAtOnce(t *testing.T) {
+	p := NewPool(time.Duration(1), 2, WithCapacity(Unbounded))
+	lock := &sync.Mutex{}
+	running, max := 0, 0
+	for i := 0; i < 6; i++ {
+		p.Submit(func() {
+			lock.Lock()
+			running++
+			if running > max {
+				max = running
+			}
+			lock.Unlock()
+
+			time.Sleep(time.Duration(5) * time.Millisecond)
+
+			lock.Lock()
+			running--
+			lock.Unlock()
+		})
+	}
+	p.Shutdown(context.Background())
+
+	if max != 2 {
+		t.Fatal(max)
+	}
+}
+
+func TestPool_Shutdown_returnsContextErrorIfTasksDoNotFinish(t *testing.T) {
+	p := NewPool(time.Duration(1), 1)
+	release := make(chan struct{})
+	p.Submit(func() {
+		<-release
+	})
+	defer close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(10)*time.Millisecond)
+	defer cancel()
+
+	if err := p.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fail()
+	}
+}
+
+func TestPool_TrySubmit_honorsTheCapacity(t *testing.T) {
+	p := NewPool(time.Duration(1)*time.Hour, 1)
+	p.Submit(func() {})
+	time.Sleep(time.Duration(10) * time.Millisecond)
+	p.Submit(func() {})
+
+	if accepted, err := p.TrySubmit(func() {}); accepted || err != nil {
+		t.Fail()
+	}
+	if p.Len() != 1 {
+		t.Fail()
+	}
+}
+
+func TestNewPool_panicsWithoutWorkers(t *testing.T) {
+	expectPanic(t, func() { NewPool(time.Duration(1), 0) })
+	expectPanic(t, func() { NewPool(time.Duration(1), -1) })
+}
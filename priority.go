@@ -0,0 +1,210 @@
+package ratelimit
+
+import (
+	"sort"
+	"time"
+)
+
+//levels holds the items of a Limiter in one first-in-first-out queue per
+//priority.
+//The next item to be released is the head of the queue with the highest
+//effective priority. Without aging, the effective priority of an item is its
+//priority. With aging, the effective priority increases by one for every aging
+//duration that the item has been queued, so low priority items are not starved
+//by a steady supply of high priority ones.
+//Items with equal effective priorities are released in the order they were
+//pushed.
+type levels struct {
+	queues map[int]*queue
+	n      int
+	seq    uint64
+
+	aging time.Duration
+}
+
+func newLevels(size int) *levels {
+	return &levels{
+		queues: map[int]*queue{
+			0: newQueue(size),
+		},
+	}
+}
+
+func (ls *levels) len() int {
+	return ls.n
+}
+
+func (ls *levels) push(it item) {
+	it.seq = ls.seq
+	ls.seq++
+
+	q := ls.queues[it.priority]
+	if q == nil {
+		q = newQueue(0)
+		ls.queues[it.priority] = q
+	}
+	q.push(it)
+	ls.n++
+}
+
+//peek returns the next item to be released without removing it.
+func (ls *levels) peek() item {
+	return ls.next().peek()
+}
+
+//pop removes and returns the next item to be released.
+func (ls *levels) pop() item {
+	q := ls.next()
+	it := q.pop()
+	ls.n--
+	if q.len() == 0 && it.priority != 0 {
+		delete(ls.queues, it.priority)
+	}
+	return it
+}
+
+//popOldest removes and returns the item that has been in ls the longest,
+//regardless of when it would be released.
+func (ls *levels) popOldest() item {
+	var oldest *queue
+	for _, q := range ls.queues {
+		if q.len() == 0 {
+			continue
+		}
+		if oldest == nil || q.peek().seq < oldest.peek().seq {
+			oldest = q
+		}
+	}
+	it := oldest.pop()
+	ls.n--
+	if oldest.len() == 0 && it.priority != 0 {
+		delete(ls.queues, it.priority)
+	}
+	return it
+}
+
+//next returns the queue whose head is the next item to be released, or nil if
+//ls is empty.
+func (ls *levels) next() *queue {
+	var next *queue
+	for _, q := range ls.queues {
+		if q.len() == 0 {
+			continue
+		}
+		if next == nil || ls.before(q.peek(), next.peek()) {
+			next = q
+		}
+	}
+	return next
+}
+
+//oldest returns when the longest queued item in ls was pushed.
+//ls must not be empty.
+func (ls *levels) oldest() time.Time {
+	var oldest time.Time
+	for _, q := range ls.queues {
+		if q.len() == 0 {
+			continue
+		}
+		if pushed := q.peek().pushed; oldest.IsZero() || pushed.Before(oldest) {
+			oldest = pushed
+		}
+	}
+	return oldest
+}
+
+//before reports whether a should be released before b.
+func (ls *levels) before(a, b item) bool {
+	if ls.aging > 0 {
+		//Both items age at the same rate, so comparing effective priorities
+		//at any time is the same as comparing these keys.
+		ka := a.pushed.Add(-time.Duration(a.priority) * ls.aging)
+		kb := b.pushed.Add(-time.Duration(b.priority) * ls.aging)
+		if !ka.Equal(kb) {
+			return ka.Before(kb)
+		}
+	} else if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.seq < b.seq
+}
+
+//drain removes and returns all items in ls in the order they would be
+//released.
+func (ls *levels) drain() []item {
+	items := make([]item, 0, ls.n)
+	for ls.n > 0 {
+		items = append(items, ls.pop())
+	}
+	return items
+}
+
+//items returns all items in ls in the order they would be released without
+//removing them.
+func (ls *levels) items() []item {
+	items := make([]item, 0, ls.n)
+	for _, q := range ls.queues {
+		q.each(func(it item) {
+			items = append(items, it)
+		})
+	}
+	sort.Sort(releaseOrder{ls, items})
+	return items
+}
+
+//releaseOrder sorts items in the order they would be released from ls.
+type releaseOrder struct {
+	ls    *levels
+	items []item
+}
+
+func (r releaseOrder) Len() int {
+	return len(r.items)
+}
+
+func (r releaseOrder) Less(i, j int) bool {
+	return r.ls.before(r.items[i], r.items[j])
+}
+
+func (r releaseOrder) Swap(i, j int) {
+	r.items[i], r.items[j] = r.items[j], r.items[i]
+}
+
+//removeIf removes and returns the items of ls for which fn returns true.
+func (ls *levels) removeIf(fn func(it item) bool) (removed []item) {
+	for priority := range ls.queues {
+		removed = append(removed, ls.removeIfPriority(priority, fn)...)
+	}
+	return removed
+}
+
+//removeIfPriority works like removeIf but only considers the items with
+//priority.
+func (ls *levels) removeIfPriority(priority int, fn func(it item) bool) (removed []item) {
+	q := ls.queues[priority]
+	if q == nil {
+		return nil
+	}
+	removed = q.removeIf(fn)
+	if q.len() == 0 && priority != 0 {
+		delete(ls.queues, priority)
+	}
+	ls.n -= len(removed)
+	return removed
+}
+
+//clear removes all items from ls.
+func (ls *levels) clear() {
+	for priority, q := range ls.queues {
+		q.clear()
+		if priority != 0 {
+			delete(ls.queues, priority)
+		}
+	}
+	ls.n = 0
+}
+
+//resize reallocates the buffer of the default priority queue to hold size items.
+func (ls *levels) resize(size int) {
+	ls.queues[0].resize(size)
+}
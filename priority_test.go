@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLevels_popsHigherPrioritiesFirst(t *testing.T) {
+	ls := newLevels(0)
+	ls.push(item{value: "low", priority: -1})
+	ls.push(item{value: "default"})
+	ls.push(item{value: "high", priority: 1})
+	ls.push(item{value: "high2", priority: 1})
+
+	for _, want := range []string{"high", "high2", "default", "low"} {
+		if v := ls.pop().value; v != want {
+			t.Fatal(v, want)
+		}
+	}
+
+	if ls.len() != 0 || len(ls.queues) != 1 {
+		t.Fail()
+	}
+}
+
+func TestLevels_agingRaisesTheEffectivePriorityOfOldItems(t *testing.T) {
+	ls := newLevels(0)
+	ls.aging = time.Second
+	now := time.Now()
+
+	ls.push(item{value: "old", priority: 0, pushed: now})
+	ls.push(item{value: "new", priority: 2, pushed: now.Add(time.Second)})
+	ls.push(item{value: "newer", priority: 2, pushed: now.Add(3 * time.Second)})
+
+	for _, want := range []string{"new", "old", "newer"} {
+		if v := ls.pop().value; v != want {
+			t.Fatal(v, want)
+		}
+	}
+}
+
+func TestLevels_drainReturnsItemsInReleaseOrder(t *testing.T) {
+	ls := newLevels(0)
+	ls.push(item{value: 0})
+	ls.push(item{value: 1, priority: 1})
+
+	items := ls.drain()
+	if len(items) != 2 || items[0].value != 1 || items[1].value != 0 {
+		t.Fail()
+	}
+}
+
+func TestLevels_clearRemovesAllItems(t *testing.T) {
+	ls := newLevels(1)
+	ls.push(item{value: 0})
+	ls.push(item{value: 1, priority: 1})
+
+	ls.clear()
+
+	if ls.len() != 0 || len(ls.queues) != 1 || ls.queues[0].len() != 0 {
+		t.Fail()
+	}
+}
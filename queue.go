@@ -0,0 +1,185 @@
+package ratelimit
+
+import "time"
+
+//item is a value queued in a Limiter along with the information used to decide
+//when it is released.
+type item struct {
+	value interface{}
+
+	priority int
+	seq      uint64
+	pushed   time.Time
+
+	cost int
+
+	//handle is the Item returned by PushItem for the value, if any.
+	handle *Item
+
+	//ttl is how long the value may be queued before it expires, or zero or less
+	//if it never does.
+	ttl time.Duration
+
+	//position is the number of values queued ahead of it when it was pushed,
+	//and previous is when the value before it was released, which are reported
+	//by PopMeta.
+	position int
+	previous time.Time
+}
+
+//releases returns the number of releases it takes to release it.
+func (it item) releases() int {
+	if it.cost < 1 {
+		return 1
+	}
+	return it.cost
+}
+
+//maxChunkSize is the number of items in the largest chunks of a queue.
+const maxChunkSize = 1024
+
+//chunk is a segment of the items of a queue.
+type chunk struct {
+	items []item
+	next  *chunk
+}
+
+//queue is a first-in-first-out queue of items stored in a linked list of chunks.
+//Chunks are added as the queue grows, each twice the size of the one before up
+//to maxChunkSize, and dropped as soon as all of their items have been popped, so
+//the memory used by a queue follows the number of items in it rather than its
+//capacity.
+//
+//A queue is not safe for concurrent use. It is guarded by the lock of its Limiter
+//together with the pacer, since priorities, expiry, overflow and the order of
+//pops all need a consistent view of both, so it is not a lock-free queue.
+type queue struct {
+	//head is the chunk holding the oldest items, starting at index first, and
+	//tail is the chunk holding the newest items, ending before index last.
+	head  *chunk
+	first int
+	tail  *chunk
+	last  int
+
+	n int
+
+	//spare is an emptied chunk of maxChunkSize kept for reuse, so that a queue
+	//whose length hovers around a chunk boundary does not allocate repeatedly.
+	spare *chunk
+}
+
+func newQueue(size int) *queue {
+	q := &queue{}
+	q.resize(size)
+	return q
+}
+
+func (q *queue) len() int {
+	return q.n
+}
+
+func (q *queue) push(it item) {
+	if q.tail == nil {
+		q.head = q.newChunk(1)
+		q.tail = q.head
+	} else if q.last == len(q.tail.items) {
+		size := 2 * len(q.tail.items)
+		if size > maxChunkSize {
+			size = maxChunkSize
+		}
+		q.tail.next = q.newChunk(size)
+		q.tail = q.tail.next
+		q.last = 0
+	}
+	q.tail.items[q.last] = it
+	q.last++
+	q.n++
+}
+
+//newChunk returns an empty chunk of size items, reusing the spare chunk of q if
+//it is the right size.
+func (q *queue) newChunk(size int) *chunk {
+	if c := q.spare; c != nil && len(c.items) == size {
+		q.spare = nil
+		return c
+	}
+	return &chunk{items: make([]item, size)}
+}
+
+//peek returns the oldest item in q without removing it.
+func (q *queue) peek() item {
+	return q.head.items[q.first]
+}
+
+//pop removes and returns the oldest item in q.
+//The slot it occupied is cleared so that q does not retain a reference to its
+//value, and its chunk is dropped once all of its items have been popped.
+func (q *queue) pop() item {
+	it := q.head.items[q.first]
+	q.head.items[q.first] = item{}
+	q.first++
+	q.n--
+
+	switch {
+	case q.n == 0:
+		//The only chunk left is empty, so it can be reused from the start.
+		q.first, q.last = 0, 0
+	case q.first == len(q.head.items):
+		done := q.head
+		q.head, q.first = done.next, 0
+		done.next = nil
+		if len(done.items) == maxChunkSize {
+			q.spare = done
+		}
+	}
+	return it
+}
+
+//each calls fn with each item in q from oldest to newest without removing them.
+func (q *queue) each(fn func(it item)) {
+	for c, i, n := q.head, q.first, 0; n < q.n; n++ {
+		if i == len(c.items) {
+			c, i = c.next, 0
+		}
+		fn(c.items[i])
+		i++
+	}
+}
+
+//removeIf removes and returns the items of q for which fn returns true, keeping
+//the order of the others.
+func (q *queue) removeIf(fn func(it item) bool) (removed []item) {
+	for n := q.n; n > 0; n-- {
+		if it := q.pop(); fn(it) {
+			removed = append(removed, it)
+		} else {
+			q.push(it)
+		}
+	}
+	return removed
+}
+
+//clear removes all items from q.
+func (q *queue) clear() {
+	for q.n > 0 {
+		q.pop()
+	}
+}
+
+//resize prepares q to hold size items without allocating as it grows, up to
+//maxChunkSize items.
+//It only has an effect while q is empty, since the chunks of a queue with items
+//already grow as needed.
+func (q *queue) resize(size int) {
+	if q.n > 0 || size <= 0 {
+		return
+	}
+	if size > maxChunkSize {
+		size = maxChunkSize
+	}
+	if q.head == nil || len(q.head.items) != size {
+		q.head = &chunk{items: make([]item, size)}
+		q.tail = q.head
+	}
+	q.first, q.last = 0, 0
+}
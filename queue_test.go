@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func pushValues(q *queue, values ...interface{}) {
+	for _, v := range values {
+		q.push(item{value: v})
+	}
+}
+
+func TestQueue_popsItemsInOrderAcrossGrowth(t *testing.T) {
+	q := newQueue(2)
+
+	pushValues(q, 0, 1)
+	if v := q.pop().value; v != 0 {
+		t.Fail()
+	}
+
+	pushValues(q, 2, 3, 4, 5, 6, 7, 8, 9)
+
+	for want := 1; want < 10; want++ {
+		if v := q.pop().value; v != want {
+			t.Fail()
+		}
+	}
+
+	if q.len() != 0 {
+		t.Fail()
+	}
+}
+
+func TestQueue_pushGrowsFromZeroSize(t *testing.T) {
+	q := newQueue(0)
+
+	pushValues(q, 0)
+
+	if q.len() != 1 || q.peek().value != 0 || q.pop().value != 0 {
+		t.Fail()
+	}
+}
+
+func TestQueue_popClearsReference(t *testing.T) {
+	q := newQueue(2)
+	pushValues(q, 0, 1)
+	q.pop()
+
+	if q.head.items[0].value != nil {
+		t.Fail()
+	}
+}
+
+func TestQueue_clearRemovesAllItems(t *testing.T) {
+	q := newQueue(2)
+	pushValues(q, 0, 1)
+	chunk := q.head
+
+	q.clear()
+
+	if q.len() != 0 || chunk.items[0].value != nil || chunk.items[1].value != nil {
+		t.Fail()
+	}
+}
+
+func TestQueue_resizePreallocatesOnlyWhileEmpty(t *testing.T) {
+	q := newQueue(4)
+	if len(q.head.items) != 4 {
+		t.Fatal(len(q.head.items))
+	}
+	pushValues(q, 0, 1, 2, 3)
+	q.pop()
+	pushValues(q, 4)
+
+	q.resize(8)
+	if len(q.head.items) != 4 {
+		t.Fail()
+	}
+
+	for want := 1; want < 5; want++ {
+		if v := q.pop().value; v != want {
+			t.Fail()
+		}
+	}
+
+	q.resize(2 * maxChunkSize)
+	if len(q.head.items) != maxChunkSize {
+		t.Fatal(len(q.head.items))
+	}
+}
+
+func TestQueue_growsAndShrinksByChunks(t *testing.T) {
+	q := newQueue(0)
+	const n = 10 * maxChunkSize
+	for i := 0; i < n; i++ {
+		q.push(item{value: i})
+	}
+
+	chunks := 0
+	for c := q.head; c != nil; c = c.next {
+		if len(c.items) > maxChunkSize {
+			t.Fatal(len(c.items))
+		}
+		chunks++
+	}
+	if chunks < 10 || chunks > 20 {
+		t.Fatal(chunks)
+	}
+
+	seen := 0
+	q.each(func(it item) {
+		if it.value != seen {
+			t.Fatal(it.value, seen)
+		}
+		seen++
+	})
+	if seen != n {
+		t.Fatal(seen)
+	}
+
+	for want := 0; want < n-1; want++ {
+		if v := q.pop().value; v != want {
+			t.Fatal(v, want)
+		}
+	}
+	if q.head != q.tail || q.head.next != nil {
+		t.Fatal("popped chunks should be dropped")
+	}
+}
+
+func TestNew_doesNotAllocateAHugeCapacityUpFront(t *testing.T) {
+	before := heapAlloc()
+	rl := New(time.Second, WithCapacity(1<<20))
+	after := heapAlloc()
+
+	if after > before && after-before > 1<<20 {
+		t.Fatal(after - before)
+	}
+	runtime.KeepAlive(rl)
+}
@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+//ErrQuotaExceeded designates that a Quota does not have enough of its budget
+//remaining in the current period.
+var ErrQuotaExceeded = errors.New("ratelimit: quota exceeded")
+
+//Quota is a budget of n operations per period, such as a daily or monthly
+//allowance of an upstream API.
+//Unlike a Pacer, a Quota does not space operations out. It simply tracks how
+//much of the budget has been consumed and refuses to consume more than remains
+//until the budget resets at the start of the next period.
+type Quota struct {
+	lock *sync.Mutex
+
+	n    int
+	next func(t time.Time) time.Time
+
+	resets time.Time
+	used   int
+}
+
+//NewQuota creates a Quota of n operations per period, where periods are aligned
+//to multiples of their duration since the zero time, just like the windows of a
+//FixedWindow.
+//For example, a period of 24 hours resets at midnight UTC.
+func NewQuota(n int, period time.Duration) *Quota {
+	return newQuota(n, func(t time.Time) time.Time {
+		return t.Truncate(period).Add(period)
+	})
+}
+
+//NewMonthlyQuota creates a Quota of n operations per calendar month in loc,
+//which resets at midnight on the first day of each month.
+func NewMonthlyQuota(n int, loc *time.Location) *Quota {
+	return newQuota(n, func(t time.Time) time.Time {
+		year, month, _ := t.In(loc).Date()
+		return time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+	})
+}
+
+func newQuota(n int, next func(t time.Time) time.Time) *Quota {
+	return &Quota{
+		lock: &sync.Mutex{},
+		n:    n,
+		next: next,
+	}
+}
+
+//Consume consumes n operations from the budget of q.
+//If fewer than n operations remain in the current period, then nothing is
+//consumed and ErrQuotaExceeded is returned.
+func (q *Quota) Consume(n int) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.advanceLocked(time.Now())
+	if q.used+n > q.n {
+		return ErrQuotaExceeded
+	}
+
+	q.used += n
+	return nil
+}
+
+//Remaining returns the number of operations remaining in the budget of q for the
+//current period.
+func (q *Quota) Remaining() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.advanceLocked(time.Now())
+	return q.n - q.used
+}
+
+//ResetsAt returns the time at which the current period ends and the budget of q
+//is restored.
+func (q *Quota) ResetsAt() time.Time {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.advanceLocked(time.Now())
+	return q.resets
+}
+
+//Reset restores the full budget of q for the current period.
+func (q *Quota) Reset() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.used = 0
+}
+
+//advanceLocked restores the budget of q if the period it was consumed in ended
+//before t.
+func (q *Quota) advanceLocked(t time.Time) {
+	if !t.Before(q.resets) {
+		q.resets = q.next(t)
+		q.used = 0
+	}
+}
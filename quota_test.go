@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuota_Consume_enforcesTheBudget(t *testing.T) {
+	q := NewQuota(5, time.Hour)
+
+	if q.Consume(3) != nil || q.Remaining() != 2 {
+		t.Fail()
+	}
+	if q.Consume(3) != ErrQuotaExceeded || q.Remaining() != 2 {
+		t.Fail()
+	}
+	if q.Consume(2) != nil || q.Remaining() != 0 {
+		t.Fail()
+	}
+}
+
+func TestQuota_advanceLocked_restoresTheBudgetEachPeriod(t *testing.T) {
+	q := NewQuota(5, time.Hour)
+	q.Consume(5)
+	resets := q.ResetsAt()
+
+	q.lock.Lock()
+	q.advanceLocked(resets.Add(-1))
+	used := q.used
+	q.advanceLocked(resets)
+	q.lock.Unlock()
+
+	if used != 5 || q.used != 0 || !q.resets.Equal(resets.Add(time.Hour)) {
+		t.Fail()
+	}
+}
+
+func TestQuota_ResetsAt_isAlignedToThePeriod(t *testing.T) {
+	q := NewQuota(1, time.Hour)
+
+	if resets := q.ResetsAt(); !resets.Equal(resets.Truncate(time.Hour)) || !resets.After(time.Now()) {
+		t.Fail()
+	}
+}
+
+func TestNewMonthlyQuota_resetsAtTheStartOfTheNextMonth(t *testing.T) {
+	q := NewMonthlyQuota(1, time.UTC)
+
+	got := q.next(time.Date(2020, time.December, 15, 12, 0, 0, 0, time.UTC))
+
+	if !got.Equal(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fail()
+	}
+}
+
+func TestQuota_Reset_restoresTheBudget(t *testing.T) {
+	q := NewQuota(2, time.Hour)
+	q.Consume(2)
+
+	q.Reset()
+
+	if q.Remaining() != 2 {
+		t.Fail()
+	}
+}
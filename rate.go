@@ -0,0 +1,165 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Rate is a throughput expressed as a number of values per period, such as 100
+//per second, which is less error-prone to write than the duration between two
+//values.
+//
+//The text form of a Rate is the whole number of values followed by a slash and
+//the period, such as "100/s", "5/m", or "10k/h". The number may have a k or M
+//suffix for thousands and millions. The period is a unit of s, m, or h, or any
+//duration accepted by time.ParseDuration such as "ms" or "10s".
+//
+//Rate implements encoding.TextUnmarshaler and flag.Value, so it can be read
+//directly from configuration files and command line flags.
+type Rate struct {
+	N   int
+	Per time.Duration
+}
+
+//ParseRate parses the text form of a Rate.
+func ParseRate(s string) (Rate, error) {
+	i := strings.Index(s, "/")
+	if i < 0 {
+		return Rate{}, invalidRate(s)
+	}
+
+	n, ok := parseCount(s[:i])
+	if !ok {
+		return Rate{}, invalidRate(s)
+	}
+	per, ok := parsePeriod(s[i+1:])
+	if !ok {
+		return Rate{}, invalidRate(s)
+	}
+	return Rate{N: n, Per: per}, nil
+}
+
+func invalidRate(s string) error {
+	return fmt.Errorf("ratelimit: invalid rate %q", s)
+}
+
+//parseCount parses a positive whole number of values with an optional
+//multiplier suffix. A fractional number is accepted if the multiplier makes it
+//whole, as in "1.5k".
+func parseCount(s string) (int, bool) {
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"):
+		multiplier, s = 1e3, s[:len(s)-1]
+	case strings.HasSuffix(s, "M"):
+		multiplier, s = 1e6, s[:len(s)-1]
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	f *= multiplier
+	if f < 1 || f != math.Trunc(f) || f > math.MaxInt32 {
+		return 0, false
+	}
+	return int(f), true
+}
+
+//parsePeriod parses a positive period, which may omit a count of one.
+func parsePeriod(s string) (time.Duration, bool) {
+	if s == "" {
+		return 0, false
+	}
+	if s[0] < '0' || s[0] > '9' {
+		s = "1" + s
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+//Duration returns the throughput duration of r, the average time between two
+//values, rounded to the nearest nanosecond.
+//The zero Rate has a Duration of zero.
+func (r Rate) Duration() time.Duration {
+	if r.N <= 0 {
+		return 0
+	}
+	return (r.Per + time.Duration(r.N)/2) / time.Duration(r.N)
+}
+
+//String returns the text form of r, using the shortest number and period that
+//represent it, such as "10k/h".
+func (r Rate) String() string {
+	n := strconv.Itoa(r.N)
+	switch {
+	case r.N != 0 && r.N%1000000 == 0:
+		n = strconv.Itoa(r.N/1000000) + "M"
+	case r.N != 0 && r.N%1000 == 0:
+		n = strconv.Itoa(r.N/1000) + "k"
+	}
+
+	per := r.Per.String()
+	switch r.Per {
+	case time.Second:
+		per = "s"
+	case time.Minute:
+		per = "m"
+	case time.Hour:
+		per = "h"
+	}
+	return n + "/" + per
+}
+
+//MarshalText implements encoding.TextMarshaler.
+func (r Rate) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+//UnmarshalText implements encoding.TextUnmarshaler.
+func (r *Rate) UnmarshalText(text []byte) error {
+	return r.Set(string(text))
+}
+
+//Set implements flag.Value.
+func (r *Rate) Set(s string) error {
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+//PerSecond creates a Limiter that releases n values per second and is otherwise
+//configured by opts.
+//The throughput duration is rounded to the nearest nanosecond when a second is
+//not a whole multiple of n values.
+//PerSecond panics if n is not positive, rather than creating an unlimited
+//Limiter, since that is a programming error.
+func PerSecond(n int, opts ...Option) *Limiter {
+	return perPeriod(n, time.Second, opts)
+}
+
+//PerMinute is like PerSecond, but releases n values per minute.
+func PerMinute(n int, opts ...Option) *Limiter {
+	return perPeriod(n, time.Minute, opts)
+}
+
+//PerHour is like PerSecond, but releases n values per hour.
+func PerHour(n int, opts ...Option) *Limiter {
+	return perPeriod(n, time.Hour, opts)
+}
+
+func perPeriod(n int, per time.Duration, opts []Option) *Limiter {
+	if n <= 0 {
+		panic("ratelimit: invalid rate " + strconv.Itoa(n) + " per " + per.String() + ", must be positive")
+	}
+	return New(Rate{N: n, Per: per}.Duration(), opts...)
+}
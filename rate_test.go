@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestParseRate_parsesValidRates(t *testing.T) {
+	cases := []struct {
+		s    string
+		rate Rate
+	}{
+		{"100/s", Rate{100, time.Second}},
+		{"5/m", Rate{5, time.Minute}},
+		{"10k/h", Rate{10000, time.Hour}},
+		{"1.5k/s", Rate{1500, time.Second}},
+		{"2M/h", Rate{2000000, time.Hour}},
+		{"3/10s", Rate{3, time.Duration(10) * time.Second}},
+		{"1/ms", Rate{1, time.Millisecond}},
+	}
+
+	for _, c := range cases {
+		rate, err := ParseRate(c.s)
+		if err != nil || rate != c.rate {
+			t.Error(c.s, rate, err)
+		}
+	}
+}
+
+func TestParseRate_rejectsInvalidRates(t *testing.T) {
+	for _, s := range []string{"", "100", "/s", "100/", "0/s", "-1/s", "1.5/s", "x/s", "1/x", "1/0s", "1/-1s"} {
+		if _, err := ParseRate(s); err == nil {
+			t.Error(s)
+		}
+	}
+}
+
+func TestRate_String_roundTrips(t *testing.T) {
+	for _, s := range []string{"100/s", "5/m", "10k/h", "1500/s", "2M/h", "3/10s", "1/1ms"} {
+		rate, err := ParseRate(s)
+		if err != nil || rate.String() != s {
+			t.Error(s, rate.String(), err)
+		}
+	}
+}
+
+func TestRate_Duration_dividesThePeriod(t *testing.T) {
+	if d := (Rate{100, time.Second}).Duration(); d != time.Duration(10)*time.Millisecond {
+		t.Error(d)
+	}
+	if d := (Rate{3, time.Duration(2)}).Duration(); d != time.Duration(1) {
+		t.Error(d)
+	}
+	if d := (Rate{}).Duration(); d != 0 {
+		t.Error(d)
+	}
+}
+
+func TestRate_UnmarshalText_readsJSON(t *testing.T) {
+	v := struct {
+		Rate Rate `json:"rate"`
+	}{}
+
+	if err := json.Unmarshal([]byte(`{"rate": "5/m"}`), &v); err != nil || v.Rate != (Rate{5, time.Minute}) {
+		t.Fatal(v.Rate, err)
+	}
+	if data, _ := json.Marshal(v); string(data) != `{"rate":"5/m"}` {
+		t.Fatal(string(data))
+	}
+}
+
+func TestRate_Set_isAFlagValue(t *testing.T) {
+	rate := Rate{1, time.Second}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&rate, "rate", "")
+
+	if err := fs.Parse([]string{"-rate", "10k/h"}); err != nil || rate != (Rate{10000, time.Hour}) {
+		t.Fatal(rate, err)
+	}
+}
+
+func TestPerSecond_dividesASecond(t *testing.T) {
+	if d := PerSecond(100).Duration(); d != time.Duration(10)*time.Millisecond {
+		t.Error(d)
+	}
+	if d := PerSecond(3).Duration(); d != time.Duration(333333333) {
+		t.Error(d)
+	}
+	if d := PerSecond(7).Duration(); d != time.Duration(142857143) {
+		t.Error(d)
+	}
+}
+
+func TestPerSecond_PerMinute_PerHour_panicIfNIsNotPositive(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		expectPanic(t, func() { PerSecond(n) })
+		expectPanic(t, func() { PerMinute(n) })
+		expectPanic(t, func() { PerHour(n) })
+	}
+}
+
+func TestPerMinute_PerHour_divideTheirPeriods(t *testing.T) {
+	if d := PerMinute(120).Duration(); d != time.Duration(500)*time.Millisecond {
+		t.Error(d)
+	}
+	if d := PerHour(7200, WithCapacity(2)).Duration(); d != time.Duration(500)*time.Millisecond {
+		t.Error(d)
+	}
+}
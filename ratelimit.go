@@ -3,60 +3,332 @@
 package ratelimit
 
 import (
-	"errors"
+	"context"
+	"math/rand"
+	"strconv"
 	"sync"
 	"time"
 )
 
-//DefaultCapacity is the capacity used for calls to New.
+//DefaultCapacity is the capacity used for calls to New without WithCapacity.
 const DefaultCapacity = 1
 
-//ErrClosed designates that a Limiter is already closed in calls to Push and Close.
-var ErrClosed = errors.New("ratelimit: limiter already closed")
+//Unbounded is the capacity of a Limiter whose queue grows as needed so that
+//pushing to it never blocks.
+const Unbounded = -1
 
 //Limiter is a primitive that rate limits values pushed to it.
-//A maximum of one value can be popped in the allotted duration.
+//By default, a maximum of one value can be popped in the allotted duration.
 //
 //Limiter acts as a first-in-first-out queue where the next value to pop will not
 //be released until at least a given duration has passed since the last value
 //has been popped.
+//
+//The releases of a Limiter are decided by its Pacer, which is a TokenBucket with
+//a burst of one unless WithBurst or WithPacer are used.
+//Limiter itself implements Pacer.
+//
+//A Limiter keeps no reference to a value once it has been popped, discarded, or
+//drained, so large values can be garbage collected as soon as the caller is done
+//with them.
 type Limiter struct {
-	lock     *sync.Mutex
-	nextTime time.Time
+	monitor
+
+	name string
+
+	d     time.Duration
+	pacer Pacer
+
+	//paused is the time before which l permits no releases.
+	paused time.Time
+
+	jitter float64
+	source rand.Source
+
+	maxCatchUp time.Duration
+
+	observers []Observer
+	hooks     []Hooks
+	stats     stats
+	stale     *staleAlerter
+
+	onSaturated func(*Limiter)
 
-	d time.Duration
+	leaked func(warning LeakWarning)
 
-	values chan interface{}
+	values   *levels
+	capacity int
+	overflow Overflow
+	closed   bool
+
+	//ttl is the time to live set with WithTTL, and expiring is whether any value
+	//with a time to live has been pushed to l.
+	ttl      time.Duration
+	expiring bool
+
+	//deadLetter is the function set with WithDeadLetter and deadLetters is the
+	//channel returned by DeadLetters. dead are the values dropped while l is
+	//locked that have yet to be passed to deadLetter.
+	deadLetter  func(d DroppedValue)
+	deadLetters chan DroppedValue
+	dead        []DroppedValue
+
+	poppers waiters
+
+	out chan interface{}
+
+	group *sync.WaitGroup
+
+	//keyed is whether l is only created to check the options of a KeyedLimiter.
+	keyed bool
 }
 
-//New creates a Limiter with a capacity of DefaultCapacity and throughput duration d.
-func New(d time.Duration) *Limiter {
-	return NewCapacity(d, DefaultCapacity)
+//New creates a Limiter with throughput duration d configured by opts.
+//Without any options the Limiter has a capacity of DefaultCapacity.
+//
+//A d of zero means the Limiter is unlimited: values are released as soon as they
+//are popped. New panics if d is negative or if the capacity is less than one and
+//not Unbounded, since either is a programming error.
+func New(d time.Duration, opts ...Option) *Limiter {
+	l := &Limiter{
+		monitor:  newMonitor(),
+		d:        d,
+		capacity: DefaultCapacity,
+		values:   newLevels(0),
+		group:    &sync.WaitGroup{},
+	}
+	l.pacer = NewTokenBucket(d, 1)
+	for _, opt := range opts {
+		opt(l)
+	}
+	validateDuration(d)
+	validateCapacity(l.capacity)
+	l.stats = newStats(l.clock.Now())
+	if l.jitter > 0 {
+		l.pacer = newJitterPacer(l.pacer, d, l.jitter, l.source)
+	}
+	if l.maxCatchUp > 0 {
+		l.pacer = newJumpPacer(l.pacer, l.maxCatchUp)
+	}
+	l.values.resize(initialQueueSize(l.capacity))
+	if l.leaked != nil {
+		l.watchLeak(1)
+	}
+	return l
 }
 
 //NewCapacity creates a Limiter with capacity and throughput duration d.
+//capacity may be Unbounded.
+//
+//Deprecated: Use New with WithCapacity instead.
 func NewCapacity(d time.Duration, capacity int) *Limiter {
-	return &Limiter{
-		lock:     &sync.Mutex{},
-		nextTime: time.Now(),
-		d:        d,
-		values:   make(chan interface{}, capacity),
+	return New(d, WithCapacity(capacity))
+}
+
+//NewUnbounded creates a Limiter with a capacity of Unbounded and throughput
+//duration d.
+//Pushing to the returned Limiter never blocks, so it should only be used when
+//values are popped at least as fast as they are pushed on average.
+//
+//It is shorthand for New(d, WithCapacity(Unbounded)).
+func NewUnbounded(d time.Duration) *Limiter {
+	return New(d, WithCapacity(Unbounded))
+}
+
+func validateDuration(d time.Duration) {
+	if d < 0 {
+		panic("ratelimit: negative duration " + d.String())
+	}
+}
+
+func validateCapacity(capacity int) {
+	if capacity < 1 && capacity != Unbounded {
+		panic("ratelimit: invalid capacity " + strconv.Itoa(capacity) + ", must be at least 1 or Unbounded")
+	}
+}
+
+func initialQueueSize(capacity int) int {
+	if capacity == Unbounded {
+		return 0
 	}
+	return capacity
 }
 
 //Push places value in l to be popped later.
 //Push does not return until there is space in l to store value (determined by
-//l's capacity).
+//l's capacity), unless the Overflow policy of l makes space.
 //
-//err will be ErrClosed if l.Close() has already been called.
+//err will be ErrClosed if l.Close() has already been called, or if l is closed
+//while Push is blocked waiting for space, in which case value is not stored.
+//err will be ErrQueueFull if l is full and its Overflow policy is Reject.
 func (l *Limiter) Push(value interface{}) (err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			err = ErrClosed
+	return l.opError("push", l.push(context.Background(), time.Time{}, item{value: value}))
+}
+
+//PushPriority places value in l with priority prio to be popped later.
+//Values with a higher priority are released before values with a lower one,
+//while still honoring the rate of l. Values pushed with Push have a priority of
+//zero. Use WithAging to prevent low priority values from being starved.
+//
+//PushPriority otherwise works just like Push.
+func (l *Limiter) PushPriority(value interface{}, prio int) error {
+	return l.opError("push", l.push(context.Background(), time.Time{}, item{value: value, priority: prio}))
+}
+
+//PushCost places value in l to be popped later, where releasing value uses cost
+//releases of the rate of l.
+//For example, with a throughput duration of one second, a value with a cost of 5
+//counts as five releases, so the release after it happens no sooner than five
+//seconds later.
+//How a cost larger than the burst of the pacer of l is paced depends on the
+//pacer. The default TokenBucket releases such a value once it holds a token and
+//delays later releases until its debt is paid back.
+//A cost less than one is treated as one.
+//
+//PushCost otherwise works just like Push.
+func (l *Limiter) PushCost(value interface{}, cost int) error {
+	return l.opError("push", l.push(context.Background(), time.Time{}, item{value: value, cost: cost}))
+}
+
+//TryPush attempts to place value in l without blocking.
+//If there is space in l to store value, then value is pushed and accepted is
+//true, otherwise TryPush returns immediately with accepted false.
+//
+//err will be ErrClosed if l.Close() has already been called, or ErrQueueFull if
+//value is refused and the Overflow policy of l is Reject.
+func (l *Limiter) TryPush(value interface{}) (accepted bool, err error) {
+	l.lock.Lock()
+	it := item{value: value}
+	accepted, err = l.tryPushLocked(it)
+	var saturated func(*Limiter)
+	if !accepted && err == nil {
+		saturated = l.observeFullLocked(it)
+		_, err = l.overflowLocked(it)
+	}
+	l.unlockAndDeliver()
+
+	l.notifySaturated(saturated)
+	return accepted, l.opError("push", err)
+}
+
+//PushTimeout places value in l to be popped later.
+//It works just like Push, but gives up and returns ErrTimeout if there is no
+//space in l to store value within d.
+//d is measured with the Clock of l.
+func (l *Limiter) PushTimeout(value interface{}, d time.Duration) error {
+	deadline := l.clock.Now().Add(d)
+	return l.opError("push", timeoutError(l.push(context.Background(), deadline, item{value: value})))
+}
+
+//PushAll places values in l, in order, to be popped later.
+//Each value is pushed just like with Push.
+//
+//n is the number of values pushed. err will be ErrClosed if l is closed before
+//all values are pushed.
+func (l *Limiter) PushAll(values ...interface{}) (n int, err error) {
+	for _, v := range values {
+		if err := l.Push(v); err != nil {
+			return n, err
 		}
-	}()
-	l.values <- value
-	return
+		n++
+	}
+	return n, nil
+}
+
+//TryPushAll places as many values in l, in order, as there is space for without
+//blocking.
+//
+//n is the number of values pushed. err will be ErrClosed if l.Close() has
+//already been called, or ErrQueueFull once a value is refused and the Overflow
+//policy of l is Reject. With DropNewest, every value that does not fit is
+//dropped and not counted in n.
+func (l *Limiter) TryPushAll(values ...interface{}) (n int, err error) {
+	l.lock.Lock()
+	n, saturated, err := l.tryPushAllLocked(values)
+	l.unlockAndDeliver()
+
+	l.notifySaturated(saturated)
+	return n, l.opError("push", err)
+}
+
+//tryPushAllLocked implements TryPushAll, returning the function set with
+//OnSaturated if l fills up before all values are pushed.
+func (l *Limiter) tryPushAllLocked(values []interface{}) (n int, saturated func(*Limiter), err error) {
+	for _, v := range values {
+		it := item{value: v}
+		accepted, err := l.tryPushLocked(it)
+		if err != nil {
+			return n, nil, err
+		}
+		if !accepted {
+			saturated = l.observeFullLocked(it)
+			if done, err := l.overflowLocked(it); !done || err != nil {
+				return n, saturated, err
+			}
+			continue
+		}
+		n++
+	}
+	return n, saturated, nil
+}
+
+//push places it in l, waiting for space until ctx is done or deadline has passed,
+//unless deadline is zero.
+func (l *Limiter) push(ctx context.Context, deadline time.Time, it item) error {
+	l.lock.Lock()
+	defer l.unlockAndDeliver()
+
+	for full := false; ; full = true {
+		if accepted, err := l.tryPushLocked(it); accepted || err != nil {
+			return err
+		}
+		if !full {
+			if saturated := l.observeFullLocked(it); saturated != nil {
+				l.lock.Unlock()
+				l.notifySaturated(saturated)
+				l.lock.Lock()
+				continue
+			}
+		}
+		if done, err := l.overflowLocked(it); done {
+			return err
+		}
+		wait := time.Duration(0)
+		if !deadline.IsZero() {
+			if wait = deadline.Sub(l.clock.Now()); wait <= 0 {
+				return context.DeadlineExceeded
+			}
+		}
+		if err := l.sleepLocked(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+func (l *Limiter) tryPushLocked(it item) (accepted bool, err error) {
+	if l.closed {
+		return false, ErrClosed
+	}
+	if !l.makeSpaceLocked() {
+		return false, nil
+	}
+
+	it.pushed = l.clock.Now()
+	if it.ttl == 0 {
+		it.ttl = l.ttl
+	}
+	if it.ttl > 0 {
+		l.expiring = true
+	}
+	it.position = l.values.len()
+	l.values.push(it)
+	l.observePushedLocked(it)
+	l.broadcastLocked()
+	return true, nil
+}
+
+func (l *Limiter) hasSpaceLocked() bool {
+	return l.capacity == Unbounded || l.values.len() < l.capacity
 }
 
 //Pop releases a value from l.
@@ -64,6 +336,10 @@ func (l *Limiter) Push(value interface{}) (err error) {
 //provided duration has passed since the most recent return of Pop.
 //
 //If l is closed, then the returned value will be nil.
+//
+//Concurrent calls to Pop, and the other blocking pops of l, are served in the
+//order they started waiting: a value is never released to a caller while
+//another has been waiting longer.
 func (l *Limiter) Pop() interface{} {
 	v, _ := l.PopOk()
 	return v
@@ -73,35 +349,543 @@ func (l *Limiter) Pop() interface{} {
 //It works just like Pop, but has an extra return value ok that designates if l
 //is not closed and value is therefore legitimate.
 func (l *Limiter) PopOk() (value interface{}, ok bool) {
-	v, ok := <-l.values
+	v, err := l.pop(context.Background(), time.Time{})
+	return v, err == nil
+}
+
+//TryPop attempts to release a value from l without blocking.
+//ok is false if there is no value in l to pop, if the provided duration has not
+//yet passed since the most recent release of a value, or if l is closed.
+//ok is also false while other goroutines are blocked popping from l, since they
+//are waiting for values ahead of it.
+func (l *Limiter) TryPop() (value interface{}, ok bool) {
+	value, ok, _ = l.tryPop()
+	return value, ok
+}
+
+//tryPop implements TryPop.
+//If there is a value that the pacer of l does not permit to be released yet, then
+//wait is the time remaining until it does. wait is zero if l is empty or other
+//goroutines are popping from it.
+func (l *Limiter) tryPop() (value interface{}, ok bool, wait time.Duration) {
+	l.lock.Lock()
+	defer l.unlockAndDeliver()
+
+	if l.poppers.len() > 0 {
+		return nil, false, 0
+	}
+	now := l.clock.Now()
+	value, ok, wait = l.tryPopLocked(now)
 	if !ok {
-		return nil, ok
+		//Nobody keeps waiting for the release that was held back.
+		l.stats.throttle(now, false)
 	}
+	return value, ok, wait
+}
 
-	l.waitAndBumpNextTime()
+//PopTimeout releases a value from l.
+//It works just like Pop, but gives up and returns ErrTimeout if a value cannot
+//be released within d.
+//d is measured with the Clock of l.
+//
+//err will be ErrClosed if l is closed and there are no more values to pop.
+func (l *Limiter) PopTimeout(d time.Duration) (value interface{}, err error) {
+	v, err := l.pop(context.Background(), l.clock.Now().Add(d))
+	return v, l.opError("pop", timeoutError(err))
+}
+
+//PopContext releases a value from l.
+//It works just like Pop, but gives up and returns ctx.Err() if ctx is done before
+//a value can be released.
+//
+//err will be ErrClosed if l is closed and there are no more values to pop.
+func (l *Limiter) PopContext(ctx context.Context) (value interface{}, err error) {
+	v, err := l.pop(ctx, time.Time{})
+	return v, l.opError("pop", err)
+}
 
-	return v, ok
+//PopN releases up to n values from l in a single call.
+//Each value is released just like with Pop, therefore PopN waits for n rate
+//windows to pass before returning all n values.
+//
+//Fewer than n values are returned only if l is closed and there are no more
+//values to pop.
+func (l *Limiter) PopN(n int) []interface{} {
+	values := make([]interface{}, 0, n)
+	for len(values) < n {
+		v, ok := l.PopOk()
+		if !ok {
+			break
+		}
+		values = append(values, v)
+	}
+	return values
 }
 
-func (l *Limiter) waitAndBumpNextTime() {
+//PopAvailable releases every value from l that is currently permitted to be
+//released without blocking.
+//The returned slice is empty if TryPop would return ok false.
+func (l *Limiter) PopAvailable() []interface{} {
+	l.lock.Lock()
+	defer l.unlockAndDeliver()
+
+	values := []interface{}{}
+	if l.poppers.len() > 0 {
+		return values
+	}
+	now := l.clock.Now()
+	for v, ok, _ := l.tryPopLocked(now); ok; v, ok, _ = l.tryPopLocked(now) {
+		values = append(values, v)
+	}
+	l.stats.throttle(now, false)
+	return values
+}
+
+//pop releases the next value of l, giving up once ctx is done or deadline has
+//passed, unless deadline is zero.
+func (l *Limiter) pop(ctx context.Context, deadline time.Time) (value interface{}, err error) {
+	it, _, err := l.popItem(ctx, deadline)
+	return it.value, err
+}
+
+//popItem releases the next item of l along with the time it was released.
+func (l *Limiter) popItem(ctx context.Context, deadline time.Time) (it item, released time.Time, err error) {
+	l.lock.Lock()
+	defer l.unlockAndDeliver()
+
+	ticket := l.poppers.join()
+	defer func() {
+		l.poppers.leave(ticket)
+		l.broadcastLocked()
+	}()
+
+	err = l.pollUntilLocked(ctx, deadline, func(now time.Time) (bool, time.Duration, error) {
+		if !l.poppers.first(ticket) {
+			if l.closed && l.values.len() == 0 {
+				return false, 0, ErrClosed
+			}
+			return false, 0, nil
+		}
+		popped, ok, wait := l.tryPopItemLocked(now)
+		if ok {
+			it, released = popped, now
+			return true, 0, nil
+		}
+		if l.closed && l.values.len() == 0 {
+			return false, 0, ErrClosed
+		}
+		return false, wait, nil
+	})
+	if err != nil {
+		l.stats.throttle(l.clock.Now(), false)
+	}
+	return it, released, err
+}
+
+//tryPopLocked releases the next value of l if there is one and the pacer of l
+//permits it.
+//If there is a value but it cannot be released yet, then wait is the time
+//remaining until it can be.
+func (l *Limiter) tryPopLocked(now time.Time) (value interface{}, ok bool, wait time.Duration) {
+	it, ok, wait := l.tryPopItemLocked(now)
+	return it.value, ok, wait
+}
+
+//tryPopItemLocked works like tryPopLocked but returns the whole item released.
+func (l *Limiter) tryPopItemLocked(now time.Time) (it item, ok bool, wait time.Duration) {
+	l.expireNextLocked(now)
+	if l.values.len() == 0 {
+		//Nothing is held back by the rate while l is empty.
+		l.stats.throttle(now, false)
+		return item{}, false, 0
+	}
+	if ok, wait := l.tryWaitNLocked(now, l.values.peek().releases()); !ok {
+		return item{}, false, wait
+	}
+
+	it = l.values.pop()
+	it.previous = l.stats.lastPop
+	l.stats.lastPop = now
+	l.observePoppedLocked(it, now)
+	l.broadcastLocked()
+	return it, true, 0
+}
+
+//Wait blocks until the provided duration has passed since the most recent
+//release from l without pushing or popping a value.
+//The return of Wait counts as a release so that Wait can be used by itself to
+//pace arbitrary operations.
+func (l *Limiter) Wait() {
+	l.WaitContext(context.Background())
+}
+
+//WaitContext works just like Wait, but gives up and returns ctx.Err() if ctx is
+//done before the provided duration has passed.
+func (l *Limiter) WaitContext(ctx context.Context) error {
 	l.lock.Lock()
 	defer l.lock.Unlock()
 
-	time.Sleep(l.nextTime.Sub(time.Now()))
+	err := l.pollLocked(ctx, func(now time.Time) (bool, time.Duration, error) {
+		ok, wait := l.tryWaitLocked(now)
+		return ok, wait, nil
+	})
+	if err != nil {
+		l.stats.throttle(l.clock.Now(), false)
+	}
+	return l.opError("wait", err)
+}
 
-	l.nextTime = time.Now().Add(l.d)
+//Do blocks until the provided duration has passed since the most recent release
+//from l, just like WaitContext, and then calls fn and returns its error.
+//
+//If ctx is done before fn can be called, then fn is not called and ctx.Err() is
+//returned.
+func (l *Limiter) Do(ctx context.Context, fn func() error) error {
+	if err := l.WaitContext(ctx); err != nil {
+		return err
+	}
+	return fn()
 }
 
-//Close closes l and prevents any more values from being pushed.
-//Note that values not yet popped are still available to receive.
+//Go blocks until the provided duration has passed since the most recent release
+//from l, just like Wait, and then calls fn in a new goroutine.
+//Calling Go in a loop therefore starts goroutines no faster than the rate of l.
 //
-//If l is already closed, then ErrClosed is returned, otherwise err is nil.
-func (l *Limiter) Close() (err error) {
+//Use WaitAll to wait for the goroutines started by Go to return.
+func (l *Limiter) Go(fn func()) {
+	l.Wait()
+
+	l.group.Add(1)
+	go func() {
+		defer l.group.Done()
+		fn()
+	}()
+}
+
+//WaitAll blocks until all goroutines started by Go have returned.
+func (l *Limiter) WaitAll() {
+	l.group.Wait()
+}
+
+func (l *Limiter) tryWaitLocked(now time.Time) (ok bool, wait time.Duration) {
+	return l.tryWaitNLocked(now, 1)
+}
+
+func (l *Limiter) tryWaitNLocked(now time.Time, n int) (ok bool, wait time.Duration) {
+	if l.allowNLocked(now, n) {
+		l.stats.throttle(now, false)
+		return true, 0
+	}
+	l.stats.throttle(now, true)
+	return false, l.delayNLocked(now, n)
+}
+
+//allowNLocked reports whether n releases may happen at t, counting them if they
+//may, taking into account any pause of l.
+func (l *Limiter) allowNLocked(t time.Time, n int) bool {
+	if t.Before(l.paused) || !l.pacer.AllowN(t, n) {
+		return false
+	}
+	if n > 0 {
+		l.stats.released += uint64(n)
+	}
+	return true
+}
+
+//delayNLocked returns how long after t it will be before n releases may happen,
+//taking into account any pause of l.
+func (l *Limiter) delayNLocked(t time.Time, n int) time.Duration {
+	delay := l.pacer.DelayN(t, n)
+	if paused := l.paused.Sub(t); paused > delay {
+		return paused
+	}
+	return delay
+}
+
+//Allow reports whether an event may happen now without blocking.
+//It is shorthand for AllowN(time.Now(), 1), using the Clock of l.
+func (l *Limiter) Allow() bool {
+	return l.AllowN(l.clock.Now(), 1)
+}
+
+//AllowN reports whether n events may happen at time t without blocking.
+//If they may, then they count as n releases from l, and the next value will not
+//be released until n multiples of the provided duration have passed since t.
+//
+//Allow and AllowN draw from the same budget as Pop and Wait, so an event that
+//is allowed delays the release of values queued in l.
+func (l *Limiter) AllowN(t time.Time, n int) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.allowNLocked(t, n)
+}
+
+//DelayN returns how long after t it will be before n events may happen.
+//It does not count as any releases from l.
+func (l *Limiter) DelayN(t time.Time, n int) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.delayNLocked(t, n)
+}
+
+//pacerClock returns the Clock of l, so that a ConcurrencyLimiter paced by l asks
+//it about the times of that Clock.
+func (l *Limiter) pacerClock() Clock {
+	return l.clock
+}
+
+//Duration returns the throughput duration of l.
+func (l *Limiter) Duration() time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.d
+}
+
+//NextAvailable returns the earliest time at which l permits its next release.
+//This does not take into account whether there is a value queued in l to pop.
+//
+//The returned time is never before the current time.
+func (l *Limiter) NextAvailable() time.Time {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.clock.Now()
+	return now.Add(l.delayNLocked(now, 1))
+}
+
+//Delay returns how long until l permits its next release, or zero if it permits
+//one now.
+func (l *Limiter) Delay() time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.delayNLocked(l.clock.Now(), 1)
+}
+
+//PauseUntil prevents l from permitting any releases before t, for example
+//because a server has asked its clients to back off until then.
+//A pause never shortens a pause already in effect.
+func (l *Limiter) PauseUntil(t time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if t.After(l.paused) {
+		l.paused = t
+		l.broadcastLocked()
+	}
+}
+
+//SetDuration changes the throughput duration of l to d without affecting the
+//values queued in l.
+//The pacer of l is updated if it has a SetDuration(time.Duration) method, as
+//TokenBucket does, and goroutines waiting on l are woken to observe it.
+//
+//SetDuration panics if d is negative, just like New.
+func (l *Limiter) SetDuration(d time.Duration) {
+	validateDuration(d)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.d = d
+	setPacerDuration(l.pacer, l.clock.Now(), d)
+	l.broadcastLocked()
+}
+
+//Len returns the number of values currently queued in l.
+func (l *Limiter) Len() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.values.len()
+}
+
+//Cap returns the capacity of l, the maximum number of values that can be queued
+//in l at once.
+//It returns Unbounded if there is no maximum.
+func (l *Limiter) Cap() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.capacity
+}
+
+//SetCapacity changes the capacity of l to capacity.
+//Values already queued in l are kept in order, even if there are more of them
+//than capacity, in which case pushes block until enough values have been popped.
+//Goroutines blocked in Push are woken to observe any new space.
+//
+//capacity may be Unbounded. SetCapacity panics for any other capacity less than
+//one, just like New.
+func (l *Limiter) SetCapacity(capacity int) {
+	validateCapacity(capacity)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.capacity = capacity
+	if capacity != Unbounded {
+		l.values.resize(capacity)
+	}
+	l.broadcastLocked()
+}
+
+//C returns a channel on which the values of l are delivered as they are released.
+//This allows l to be used in a select statement alongside other channels.
+//
+//The channel is fed by a goroutine, started by the first call to C, that pops
+//values from l. It is closed after l is closed and all of its values have been
+//delivered. Subsequent calls to C return the same channel until it is closed.
+//
+//Note that the goroutine holds on to one released value until it is received,
+//so the channel should be drained until it is closed.
+func (l *Limiter) C() <-chan interface{} {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.out == nil {
+		l.out = make(chan interface{})
+		go l.feed(l.out)
+	}
+	return l.out
+}
+
+func (l *Limiter) feed(out chan interface{}) {
 	defer func() {
-		if r := recover(); r != nil {
-			err = ErrClosed
+		l.lock.Lock()
+		defer l.lock.Unlock()
+
+		if l.out == out {
+			l.out = nil
 		}
+		close(out)
 	}()
-	close(l.values)
-	return
+
+	for v, ok := l.PopOk(); ok; v, ok = l.PopOk() {
+		out <- v
+	}
+}
+
+//Close closes l and prevents any more values from being pushed.
+//Note that values not yet popped are still available to receive, and they are
+//still released at the rate of l, so a Pop waiting out the rate for one of them
+//keeps waiting. Pops waiting on an empty l return promptly with ErrClosed.
+//Use CloseDiscard or CloseContext to stop waiting pops without waiting for the
+//remaining values to be released.
+//
+//Close is safe to call concurrently with pushes. The closed state of l is
+//guarded by its lock, so every push either completes before Close and its value
+//is kept, or happens after Close and returns ErrClosed.
+//
+//If l is already closed, then ErrClosed is returned, otherwise err is nil.
+func (l *Limiter) Close() (err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.closed {
+		return l.opError("close", ErrClosed)
+	}
+
+	l.closed = true
+	l.observeClosedLocked()
+	l.broadcastLocked()
+	return nil
+}
+
+//CloseDiscard closes l and discards all values that have not yet been popped so
+//that l no longer holds references to them.
+//Pops that are waiting, even for a long rate, return promptly with ErrClosed.
+//
+//If l is already closed, then ErrClosed is returned, but the values remaining in
+//l are still discarded.
+func (l *Limiter) CloseDiscard() (err error) {
+	l.lock.Lock()
+	defer l.unlockAndDeliver()
+
+	if l.closed {
+		err = ErrClosed
+	} else {
+		l.observeClosedLocked()
+	}
+
+	l.closed = true
+	l.discardLocked()
+	l.broadcastLocked()
+	return l.opError("close", err)
+}
+
+//CloseContext closes l and then waits for the values remaining in l to be popped
+//at its rate until ctx is done.
+//If ctx is done first, then the values still remaining are discarded, dropped is
+//the number of them, and err is ctx.Err().
+//
+//If l is already closed, then ErrClosed is returned once the remaining values are
+//popped, but they are still discarded if ctx is done first.
+func (l *Limiter) CloseContext(ctx context.Context) (dropped int, err error) {
+	l.lock.Lock()
+	defer l.unlockAndDeliver()
+
+	if l.closed {
+		err = ErrClosed
+	} else {
+		l.observeClosedLocked()
+	}
+
+	l.closed = true
+	l.broadcastLocked()
+
+	waitErr := l.pollLocked(ctx, func(now time.Time) (bool, time.Duration, error) {
+		return l.values.len() == 0, 0, nil
+	})
+	if waitErr != nil {
+		dropped = l.values.len()
+		l.discardLocked()
+		l.broadcastLocked()
+		return dropped, l.opError("close", waitErr)
+	}
+	return 0, l.opError("close", err)
+}
+
+//Drain closes l and returns all values that have not yet been popped.
+//The values are returned immediately, in order, without waiting for the
+//provided duration between them.
+//
+//If l is already closed, then Drain still returns the values remaining in l.
+func (l *Limiter) Drain() []interface{} {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if !l.closed {
+		l.observeClosedLocked()
+	}
+
+	l.closed = true
+	items := l.values.drain()
+	l.observeDroppedLocked(items)
+	l.broadcastLocked()
+
+	values := make([]interface{}, len(items))
+	for i, it := range items {
+		values[i] = it.value
+	}
+	return values
+}
+
+//Reset discards all values that have not yet been popped, resets the pacer of l
+//if it has a Reset() method, as TokenBucket does, and reopens l if it is closed.
+//This allows l to be reused instead of creating a new Limiter.
+func (l *Limiter) Reset() {
+	l.lock.Lock()
+	defer l.unlockAndDeliver()
+
+	l.closed = false
+	l.discardLocked()
+	l.paused = time.Time{}
+	if resetter, ok := l.pacer.(resetter); ok {
+		resetter.Reset()
+	}
+	l.broadcastLocked()
 }
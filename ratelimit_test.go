@@ -1,6 +1,10 @@
 package ratelimit
 
 import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
 	"testing"
 	"time"
 )
@@ -19,26 +23,1027 @@ func TestNewCapacity_createsALimiterWithCapacity(t *testing.T) {
 	}
 }
 
+func TestNew_panicsForInvalidArguments(t *testing.T) {
+	for _, create := range []func(){
+		func() { New(-time.Second) },
+		func() { New(time.Second, WithCapacity(0)) },
+		func() { New(time.Second, WithCapacity(-2)) },
+		func() { New(time.Second).SetDuration(-time.Second) },
+		func() { New(time.Second).SetCapacity(0) },
+	} {
+		expectPanic(t, create)
+	}
+}
+
+func expectPanic(t *testing.T, fn func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	fn()
+}
+
+func TestNew_releasesWithoutWaitingForZeroDuration(t *testing.T) {
+	rl := NewUnbounded(0)
+	rl.PushAll(0, 1, 2)
+
+	if values := rl.PopAvailable(); len(values) != 3 {
+		t.Fatal(values)
+	}
+}
+
+func TestNewUnbounded_createsALimiterThatNeverBlocksPush(t *testing.T) {
+	rl := NewUnbounded(time.Duration(1) * time.Hour)
+
+	for i := 0; i < 100; i++ {
+		if accepted, err := rl.TryPush(i); !accepted || err != nil {
+			t.Fail()
+		}
+	}
+
+	if rl.Len() != 100 || rl.Cap() != Unbounded {
+		t.Fail()
+	}
+
+	if v := rl.Pop(); v != 0 {
+		t.Fail()
+	}
+}
+
 func TestLimiter_Push_returnsErrorIfClosed(t *testing.T) {
 	rl := New(time.Duration(1))
 
-	if err := rl.Close(); err != nil {
+	if err := rl.Close(); err != nil {
+		t.Fail()
+	}
+
+	if err := rl.Push(0); err != ErrClosed {
+		t.Fail()
+	}
+}
+
+func TestLimiter_PushPriority_releasesHigherPrioritiesFirst(t *testing.T) {
+	rl := New(time.Duration(1), WithCapacity(3))
+	rl.Push(0)
+	rl.PushPriority(1, 1)
+	rl.PushPriority(2, -1)
+
+	for _, want := range []int{1, 0, 2} {
+		if v := rl.Pop(); v != want {
+			t.Fail()
+		}
+	}
+}
+
+func TestLimiter_PushPriority_honorsTheRate(t *testing.T) {
+	rl := New(time.Duration(1)*time.Hour, WithCapacity(2))
+	rl.Push(0)
+	rl.Pop()
+	rl.PushPriority(1, 10)
+
+	if _, ok := rl.TryPop(); ok {
+		t.Fail()
+	}
+}
+
+func TestLimiter_PushPriority_returnsErrorIfClosed(t *testing.T) {
+	rl := New(time.Duration(1))
+	rl.Close()
+
+	if err := rl.PushPriority(0, 1); err != ErrClosed {
+		t.Fail()
+	}
+}
+
+func TestLimiter_PushCost_usesCostReleases(t *testing.T) {
+	rl := New(time.Duration(1)*time.Hour, WithCapacity(2), WithBurst(3))
+	rl.PushCost(0, 2)
+	rl.PushCost(1, 2)
+
+	if v, ok := rl.TryPop(); v != 0 || !ok {
+		t.Fail()
+	}
+	if _, ok := rl.TryPop(); ok || !rl.Allow() {
+		t.Fail()
+	}
+}
+
+func TestLimiter_PushCost_delaysTheNextReleaseByCostDurations(t *testing.T) {
+	rl := New(time.Duration(1)*time.Hour, WithCapacity(2))
+	rl.PushCost(0, 3)
+	rl.Push(1)
+	rl.Pop()
+
+	if d := rl.Delay(); d <= time.Duration(179)*time.Minute || d > time.Duration(3)*time.Hour {
+		t.Fatal(d)
+	}
+}
+
+func TestLimiter_TryPush_acceptsValuesUntilFull(t *testing.T) {
+	rl := NewCapacity(time.Duration(1), 2)
+
+	for i := 0; i < 2; i++ {
+		if accepted, err := rl.TryPush(i); !accepted || err != nil {
+			t.Fail()
+		}
+	}
+
+	if accepted, err := rl.TryPush(2); accepted || err != nil {
+		t.Fail()
+	}
+
+	if v := rl.Pop(); v != 0 {
+		t.Fail()
+	}
+
+	if accepted, err := rl.TryPush(2); !accepted || err != nil {
+		t.Fail()
+	}
+}
+
+func TestLimiter_TryPush_returnsErrorIfClosed(t *testing.T) {
+	rl := New(time.Duration(1))
+
+	if err := rl.Close(); err != nil {
+		t.Fail()
+	}
+
+	if accepted, err := rl.TryPush(0); accepted || err != ErrClosed {
+		t.Fail()
+	}
+}
+
+func TestLimiter_TryPop_returnsFalseIfEmpty(t *testing.T) {
+	rl := New(time.Duration(1))
+
+	if v, ok := rl.TryPop(); v != nil || ok {
+		t.Fail()
+	}
+}
+
+func TestLimiter_TryPop_returnsFalseIfDurationHasNotPassed(t *testing.T) {
+	rl := NewCapacity(time.Duration(1)*time.Hour, 2)
+	rl.Push(0)
+	rl.Push(1)
+
+	if v, ok := rl.TryPop(); v != 0 || !ok {
+		t.Fail()
+	}
+
+	if v, ok := rl.TryPop(); v != nil || ok {
+		t.Fail()
+	}
+}
+
+func TestLimiter_TryPop_returnsFalseIfClosed(t *testing.T) {
+	rl := New(time.Duration(1))
+	rl.Close()
+
+	if v, ok := rl.TryPop(); v != nil || ok {
+		t.Fail()
+	}
+}
+
+func TestLimiter_PushTimeout_returnsErrTimeoutIfFull(t *testing.T) {
+	rl := New(time.Duration(1))
+	rl.Push(0)
+
+	if err := rl.PushTimeout(1, time.Millisecond); err != ErrTimeout {
+		t.Fail()
+	}
+}
+
+func TestLimiter_PushTimeout_pushesIfSpaceBecomesAvailable(t *testing.T) {
+	rl := New(time.Duration(1))
+	rl.Push(0)
+
+	go rl.Pop()
+
+	if err := rl.PushTimeout(1, time.Second); err != nil {
+		t.Fail()
+	}
+}
+
+func TestLimiter_PushTimeout_returnsErrorIfClosed(t *testing.T) {
+	rl := New(time.Duration(1))
+	rl.Close()
+
+	if err := rl.PushTimeout(0, time.Second); err != ErrClosed {
+		t.Fail()
+	}
+}
+
+func TestLimiter_PopTimeout_returnsErrTimeoutIfEmpty(t *testing.T) {
+	rl := New(time.Duration(1))
+
+	if v, err := rl.PopTimeout(time.Millisecond); v != nil || err != ErrTimeout {
+		t.Fail()
+	}
+}
+
+func TestLimiter_PopTimeout_returnsErrTimeoutIfDurationHasNotPassed(t *testing.T) {
+	rl := NewCapacity(time.Duration(1)*time.Hour, 2)
+	rl.Push(0)
+	rl.Push(1)
+
+	if v, err := rl.PopTimeout(time.Millisecond); v != 0 || err != nil {
+		t.Fail()
+	}
+
+	if v, err := rl.PopTimeout(time.Millisecond); v != nil || err != ErrTimeout {
+		t.Fail()
+	}
+
+	if rl.values.len() != 1 {
+		t.Fail()
+	}
+}
+
+func TestLimiter_PopTimeout_returnsErrorIfClosed(t *testing.T) {
+	rl := New(time.Duration(1))
+	rl.Close()
+
+	if v, err := rl.PopTimeout(time.Second); v != nil || err != ErrClosed {
+		t.Fail()
+	}
+}
+
+func TestLimiter_PopN_returnsNValues(t *testing.T) {
+	rl := NewCapacity(time.Duration(1), 5)
+	for i := 0; i < 5; i++ {
+		rl.Push(i)
+	}
+
+	values := rl.PopN(3)
+	if len(values) != 3 {
+		t.Fatal(values)
+	}
+	for i, v := range values {
+		if v != i {
+			t.Fail()
+		}
+	}
+}
+
+func TestLimiter_PopN_returnsFewerValuesIfClosed(t *testing.T) {
+	rl := NewCapacity(time.Duration(1), 5)
+	rl.Push(0)
+	rl.Push(1)
+	rl.Close()
+
+	values := rl.PopN(3)
+	if len(values) != 2 || values[0] != 0 || values[1] != 1 {
+		t.Fail()
+	}
+}
+
+func TestLimiter_PopAvailable_returnsPermittedValues(t *testing.T) {
+	rl := NewCapacity(time.Duration(1)*time.Hour, 3)
+	for i := 0; i < 3; i++ {
+		rl.Push(i)
+	}
+
+	values := rl.PopAvailable()
+	if len(values) != 1 || values[0] != 0 {
+		t.Fail()
+	}
+
+	if values := rl.PopAvailable(); len(values) != 0 {
+		t.Fail()
+	}
+}
+
+func TestLimiter_NextAvailable_returnsNowIfAReleaseIsPermitted(t *testing.T) {
+	rl := New(time.Duration(1) * time.Hour)
+
+	before := time.Now()
+	next := rl.NextAvailable()
+
+	if next.Before(before) || next.After(time.Now()) {
+		t.Fail()
+	}
+
+	if rl.Delay() != 0 {
+		t.Fail()
+	}
+}
+
+func TestLimiter_NextAvailable_returnsTheTimeOfTheNextRelease(t *testing.T) {
+	d := time.Duration(1) * time.Hour
+	rl := New(d)
+
+	before := time.Now()
+	rl.Wait()
+	next := rl.NextAvailable()
+
+	if next.Before(before.Add(d)) || next.After(time.Now().Add(d)) {
+		t.Fail()
+	}
+
+	if delay := rl.Delay(); delay <= 0 || delay > d {
+		t.Fail()
+	}
+}
+
+func TestLimiter_SetDuration_changesTheDuration(t *testing.T) {
+	rl := New(time.Duration(1) * time.Hour)
+
+	rl.SetDuration(time.Duration(1) * time.Second)
+
+	if rl.Duration() != time.Duration(1)*time.Second {
+		t.Fail()
+	}
+}
+
+func TestLimiter_SetDuration_wakesAWaitingPop(t *testing.T) {
+	rl := NewCapacity(time.Duration(1)*time.Hour, 2)
+	rl.PushAll(0, 1)
+	rl.Pop()
+
+	go func() {
+		time.Sleep(time.Duration(10) * time.Millisecond)
+		rl.SetDuration(time.Duration(1))
+	}()
+
+	if v, err := rl.PopTimeout(time.Second); v != 1 || err != nil {
+		t.Fail()
+	}
+}
+
+func TestLimiter_SetDuration_keepsQueuedValues(t *testing.T) {
+	rl := NewCapacity(time.Duration(1), 2)
+	rl.PushAll(0, 1)
+
+	rl.SetDuration(time.Duration(2))
+
+	if values := rl.PopN(2); len(values) != 2 || values[0] != 0 || values[1] != 1 {
+		t.Fail()
+	}
+}
+
+func TestLimiter_Len_returnsTheNumberOfQueuedValues(t *testing.T) {
+	rl := NewCapacity(time.Duration(1), 3)
+
+	if rl.Len() != 0 {
+		t.Fail()
+	}
+
+	rl.Push(0)
+	rl.Push(1)
+	if rl.Len() != 2 {
+		t.Fail()
+	}
+
+	rl.Pop()
+	if rl.Len() != 1 {
+		t.Fail()
+	}
+}
+
+func TestLimiter_Cap_returnsTheCapacity(t *testing.T) {
+	if New(time.Duration(1)).Cap() != DefaultCapacity {
+		t.Fail()
+	}
+
+	if NewCapacity(time.Duration(1), 10).Cap() != 10 {
+		t.Fail()
+	}
+}
+
+func TestLimiter_SetCapacity_growsTheCapacity(t *testing.T) {
+	rl := NewCapacity(time.Duration(1), 1)
+	rl.Push(0)
+
+	done := make(chan struct{})
+	go func() {
+		rl.Push(1)
+		close(done)
+	}()
+
+	rl.SetCapacity(2)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fail()
+	}
+
+	if rl.Cap() != 2 {
+		t.Fail()
+	}
+
+	if values := rl.PopN(2); len(values) != 2 || values[0] != 0 || values[1] != 1 {
+		t.Fail()
+	}
+}
+
+func TestLimiter_SetCapacity_shrinkingKeepsQueuedValues(t *testing.T) {
+	rl := NewCapacity(time.Duration(1), 3)
+	rl.PushAll(0, 1, 2)
+
+	rl.SetCapacity(1)
+
+	if accepted, _ := rl.TryPush(3); accepted {
+		t.Fail()
+	}
+
+	for i := 0; i < 3; i++ {
+		if v := rl.Pop(); v != i {
+			t.Fail()
+		}
+	}
+
+	if accepted, _ := rl.TryPush(3); !accepted {
+		t.Fail()
+	}
+}
+
+func TestLimiter_SetCapacity_canMakeALimiterUnbounded(t *testing.T) {
+	rl := NewCapacity(time.Duration(1), 1)
+	rl.Push(0)
+
+	rl.SetCapacity(Unbounded)
+
+	if n, err := rl.TryPushAll(1, 2, 3); n != 3 || err != nil {
+		t.Fail()
+	}
+
+	rl.SetCapacity(2)
+
+	if accepted, _ := rl.TryPush(4); accepted || rl.Len() != 4 {
+		t.Fail()
+	}
+}
+
+func TestLimiter_C_deliversValuesUntilClosed(t *testing.T) {
+	rl := New(time.Duration(1))
+
+	go func() {
+		for i := 0; i < 10; i++ {
+			rl.Push(i)
+		}
+		rl.Close()
+	}()
+
+	want := 0
+	for v := range rl.C() {
+		if v != want {
+			t.Fail()
+		}
+		want++
+	}
+
+	if want != 10 {
+		t.Fail()
+	}
+}
+
+func TestLimiter_C_returnsTheSameChannel(t *testing.T) {
+	rl := New(time.Duration(1))
+
+	if rl.C() != rl.C() {
+		t.Fail()
+	}
+}
+
+func TestLimiter_C_canBeUsedInASelect(t *testing.T) {
+	rl := New(time.Duration(1))
+	done := make(chan struct{})
+
+	go rl.Push(0)
+
+	select {
+	case v := <-rl.C():
+		if v != 0 {
+			t.Fail()
+		}
+	case <-done:
+		t.Fail()
+	case <-time.After(time.Second):
+		t.Fail()
+	}
+}
+
+func TestLimiter_PushAll_pushesAllValues(t *testing.T) {
+	rl := NewCapacity(time.Duration(1), 3)
+
+	if n, err := rl.PushAll(0, 1, 2); n != 3 || err != nil {
+		t.Fail()
+	}
+
+	for i := 0; i < 3; i++ {
+		if v := rl.Pop(); v != i {
+			t.Fail()
+		}
+	}
+}
+
+func TestLimiter_PushAll_returnsErrorIfClosed(t *testing.T) {
+	rl := NewCapacity(time.Duration(1), 3)
+	rl.Close()
+
+	if n, err := rl.PushAll(0, 1, 2); n != 0 || err != ErrClosed {
+		t.Fail()
+	}
+}
+
+func TestLimiter_TryPushAll_pushesUntilFull(t *testing.T) {
+	rl := NewCapacity(time.Duration(1), 2)
+
+	if n, err := rl.TryPushAll(0, 1, 2); n != 2 || err != nil {
+		t.Fail()
+	}
+
+	if rl.Len() != 2 {
+		t.Fail()
+	}
+}
+
+func TestLimiter_TryPushAll_returnsErrorIfClosed(t *testing.T) {
+	rl := NewCapacity(time.Duration(1), 2)
+	rl.Close()
+
+	if n, err := rl.TryPushAll(0, 1); n != 0 || err != ErrClosed {
+		t.Fail()
+	}
+}
+
+func TestLimiter_Close_returnsErrorIfClosed(t *testing.T) {
+	rl := New(time.Duration(1))
+
+	if err := rl.Close(); err != nil {
+		t.Fail()
+	}
+
+	if err := rl.Close(); err != ErrClosed {
+		t.Fail()
+	}
+}
+
+func TestLimiter_Close_unblocksPushesWaitingForSpace(t *testing.T) {
+	rl := New(time.Duration(1) * time.Hour)
+	rl.Push(0)
+	errs := make(chan error)
+	for i := 0; i < 3; i++ {
+		go func(i int) {
+			errs <- rl.Push(i)
+		}(i)
+	}
+	time.Sleep(time.Duration(10) * time.Millisecond)
+
+	rl.Close()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-errs:
+			if err != ErrClosed {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Push is still blocked after Close")
+		}
+	}
+	if rl.Len() != 1 {
+		t.Fatal(rl.Len())
+	}
+}
+
+func TestLimiter_Close_isSafeConcurrentlyWithPushes(t *testing.T) {
+	rl := NewUnbounded(time.Duration(1))
+	accepted := make(chan int, 100)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			switch err := rl.Push(i); err {
+			case nil:
+				accepted <- i
+			case ErrClosed:
+			default:
+				t.Error(err)
+			}
+		}(i)
+	}
+	rl.Close()
+	wg.Wait()
+	close(accepted)
+
+	n := 0
+	for range accepted {
+		n++
+	}
+	if values := rl.Drain(); len(values) != n {
+		t.Fatal(len(values), n)
+	}
+}
+
+func TestLimiter_CloseDiscard_closesAndDiscardsQueuedValues(t *testing.T) {
+	rl := NewCapacity(time.Duration(1), 3)
+	rl.PushAll(0, 1, 2)
+
+	if err := rl.CloseDiscard(); err != nil {
+		t.Fail()
+	}
+
+	if rl.Len() != 0 {
+		t.Fail()
+	}
+
+	if v, ok := rl.PopOk(); v != nil || ok {
+		t.Fail()
+	}
+}
+
+func TestLimiter_CloseDiscard_interruptsAPopWaitingOutALongRate(t *testing.T) {
+	rl := NewCapacity(time.Duration(1)*time.Hour, 2)
+	rl.PushAll(0, 1)
+	rl.Pop()
+
+	done := make(chan bool)
+	go func() {
+		_, ok := rl.PopOk()
+		done <- ok
+	}()
+	time.Sleep(time.Duration(10) * time.Millisecond)
+	rl.CloseDiscard()
+
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatal("Pop should not release a discarded value")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Pop is still waiting after CloseDiscard")
+	}
+}
+
+func TestLimiter_Close_keepsReleasingTheRemainingValuesAtTheRate(t *testing.T) {
+	d := time.Duration(20) * time.Millisecond
+	rl := NewCapacity(d, 3)
+	rl.PushAll(0, 1, 2)
+	rl.Pop()
+	start := time.Now()
+
+	rl.Close()
+
+	values := []interface{}{}
+	for v, ok := rl.PopOk(); ok; v, ok = rl.PopOk() {
+		values = append(values, v)
+	}
+	if len(values) != 2 || values[0] != 1 || values[1] != 2 || rl.Len() != 0 {
+		t.Fatal(values, rl.Len())
+	}
+	if time.Since(start) < 2*d-time.Duration(5)*time.Millisecond {
+		t.Fatal("values remaining after Close should be released at the rate")
+	}
+}
+
+func TestLimiter_C_deliversTheValuesRemainingAfterClose(t *testing.T) {
+	rl := NewCapacity(time.Duration(10)*time.Millisecond, 3)
+	rl.PushAll(0, 1, 2)
+	c := rl.C()
+	<-c
+
+	rl.Close()
+
+	values := []interface{}{}
+	for v := range c {
+		values = append(values, v)
+	}
+	if len(values) != 2 || values[0] != 1 || values[1] != 2 {
+		t.Fatal(values)
+	}
+}
+
+func TestLimiter_Close_interruptsAPopOnAnEmptyLimiter(t *testing.T) {
+	rl := New(time.Duration(1) * time.Hour)
+	rl.Wait()
+
+	done := make(chan error)
+	go func() {
+		_, err := rl.PopContext(context.Background())
+		done <- err
+	}()
+	time.Sleep(time.Duration(10) * time.Millisecond)
+	rl.Close()
+
+	select {
+	case err := <-done:
+		if err != ErrClosed {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Pop is still waiting after Close")
+	}
+}
+
+func TestLimiter_CloseDiscard_returnsErrorIfClosed(t *testing.T) {
+	rl := NewCapacity(time.Duration(1), 3)
+	rl.PushAll(0, 1, 2)
+	rl.Close()
+
+	if err := rl.CloseDiscard(); err != ErrClosed {
+		t.Fail()
+	}
+
+	if rl.Len() != 0 {
+		t.Fail()
+	}
+}
+
+func TestLimiter_CloseContext_waitsForQueuedValuesToBePopped(t *testing.T) {
+	rl := NewCapacity(time.Duration(1)*time.Millisecond, 3)
+	rl.PushAll(0, 1, 2)
+	go func() {
+		for _, ok := rl.PopOk(); ok; _, ok = rl.PopOk() {
+		}
+	}()
+
+	dropped, err := rl.CloseContext(context.Background())
+
+	if dropped != 0 || err != nil || rl.Len() != 0 {
+		t.Fatal(dropped, err, rl.Len())
+	}
+	if err := rl.Push(3); err != ErrClosed {
+		t.Fatal(err)
+	}
+}
+
+func TestLimiter_CloseContext_discardsTheRemainderWhenDone(t *testing.T) {
+	rl := NewCapacity(time.Duration(1)*time.Hour, 3)
+	rl.PushAll(0, 1, 2)
+	go rl.Pop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(20)*time.Millisecond)
+	defer cancel()
+	dropped, err := rl.CloseContext(ctx)
+
+	if dropped != 2 || err != context.DeadlineExceeded || rl.Len() != 0 {
+		t.Fatal(dropped, err, rl.Len())
+	}
+	if stats := rl.Stats(); stats.Dropped != 2 {
+		t.Fatal(stats.Dropped)
+	}
+}
+
+func TestLimiter_CloseContext_returnsErrorIfClosed(t *testing.T) {
+	rl := New(time.Duration(1))
+	rl.Close()
+
+	if dropped, err := rl.CloseContext(context.Background()); dropped != 0 || err != ErrClosed {
+		t.Fatal(dropped, err)
+	}
+}
+
+func TestLimiter_Drain_closesAndReturnsQueuedValues(t *testing.T) {
+	rl := NewCapacity(time.Duration(1)*time.Hour, 3)
+	rl.PushAll(0, 1, 2)
+	rl.Pop()
+
+	values := rl.Drain()
+	if len(values) != 2 || values[0] != 1 || values[1] != 2 {
 		t.Fail()
 	}
 
-	if err := rl.Push(0); err != ErrClosed {
+	if err := rl.Push(3); err != ErrClosed {
+		t.Fail()
+	}
+
+	if v, ok := rl.PopOk(); v != nil || ok {
 		t.Fail()
 	}
 }
 
-func TestLimiter_Close_returnsErrorIfClosed(t *testing.T) {
+func TestLimiter_Drain_returnsRemainingValuesIfClosed(t *testing.T) {
+	rl := NewCapacity(time.Duration(1), 2)
+	rl.PushAll(0, 1)
+	rl.Close()
+
+	if values := rl.Drain(); len(values) != 2 {
+		t.Fail()
+	}
+
+	if values := rl.Drain(); len(values) != 0 {
+		t.Fail()
+	}
+}
+
+func TestLimiter_Reset_reopensAClosedLimiter(t *testing.T) {
+	rl := NewCapacity(time.Duration(1), 2)
+	rl.PushAll(0, 1)
+	rl.Close()
+
+	rl.Reset()
+
+	if rl.Len() != 0 {
+		t.Fail()
+	}
+
+	if err := rl.Push(2); err != nil {
+		t.Fail()
+	}
+
+	if v, ok := rl.PopOk(); v != 2 || !ok {
+		t.Fail()
+	}
+}
+
+func TestLimiter_Reset_resetsTheReleaseTime(t *testing.T) {
+	rl := NewCapacity(time.Duration(1)*time.Hour, 2)
+	rl.PushAll(0, 1)
+	rl.Pop()
+
+	rl.Reset()
+	rl.Push(2)
+
+	if v, ok := rl.TryPop(); v != 2 || !ok {
+		t.Fail()
+	}
+}
+
+func TestLimiter_Reset_allowsCToBeUsedAgain(t *testing.T) {
 	rl := New(time.Duration(1))
+	rl.Close()
+	for range rl.C() {
+	}
 
-	if err := rl.Close(); err != nil {
+	rl.Reset()
+	go rl.Push(0)
+
+	select {
+	case v, ok := <-rl.C():
+		if v != 0 || !ok {
+			t.Fail()
+		}
+	case <-time.After(time.Second):
 		t.Fail()
 	}
+}
 
-	if err := rl.Close(); err != ErrClosed {
+func TestLimiter_Wait_countsAsARelease(t *testing.T) {
+	rl := New(time.Duration(1) * time.Hour)
+
+	rl.Wait()
+	rl.Push(0)
+
+	if v, ok := rl.TryPop(); v != nil || ok {
+		t.Fail()
+	}
+}
+
+func TestLimiter_WaitContext_returnsErrorIfContextIsDone(t *testing.T) {
+	rl := New(time.Duration(1) * time.Hour)
+	rl.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	if err := rl.WaitContext(ctx); err != context.DeadlineExceeded {
+		t.Fail()
+	}
+}
+
+func TestLimiter_Reset_wakesAWaitDuringALongRate(t *testing.T) {
+	rl := New(time.Duration(1) * time.Hour)
+	rl.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		rl.Wait()
+		close(done)
+	}()
+	time.Sleep(time.Duration(10) * time.Millisecond)
+	rl.Reset()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wait should return once the pacer is reset")
+	}
+}
+
+func TestLimiter_WaitContext_waitsForTheDuration(t *testing.T) {
+	d := time.Duration(10) * time.Millisecond
+	rl := New(d)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := rl.WaitContext(context.Background()); err != nil {
+			t.Fail()
+		}
+	}
+
+	if time.Since(start) < 2*d {
+		t.Fail()
+	}
+}
+
+func TestLimiter_Do_callsFnAndReturnsItsError(t *testing.T) {
+	rl := New(time.Duration(1))
+	want := errors.New("fn")
+
+	called := false
+	err := rl.Do(context.Background(), func() error {
+		called = true
+		return want
+	})
+
+	if !called || err != want {
+		t.Fail()
+	}
+}
+
+func TestLimiter_Do_doesNotCallFnIfContextIsDone(t *testing.T) {
+	rl := New(time.Duration(1) * time.Hour)
+	rl.Wait()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := rl.Do(ctx, func() error {
+		t.Fail()
+		return nil
+	})
+
+	if err != context.Canceled {
+		t.Fail()
+	}
+}
+
+func TestLimiter_Go_runsAllFunctionsAndWaitAllJoinsThem(t *testing.T) {
+	rl := New(time.Duration(1))
+
+	lock := &sync.Mutex{}
+	count := 0
+	for i := 0; i < 10; i++ {
+		rl.Go(func() {
+			lock.Lock()
+			defer lock.Unlock()
+			count++
+		})
+	}
+
+	rl.WaitAll()
+
+	if count != 10 {
+		t.Fail()
+	}
+}
+
+func TestLimiter_Go_startsFunctionsAtTheLimitedRate(t *testing.T) {
+	d := time.Duration(10) * time.Millisecond
+	rl := New(d)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		rl.Go(func() {})
+	}
+	rl.WaitAll()
+
+	if time.Since(start) < 2*d {
+		t.Fail()
+	}
+}
+
+func TestLimiter_Allow_allowsOneEventPerDuration(t *testing.T) {
+	rl := New(time.Duration(1) * time.Hour)
+
+	if !rl.Allow() {
+		t.Fail()
+	}
+
+	if rl.Allow() {
+		t.Fail()
+	}
+}
+
+func TestLimiter_Allow_sharesTheBudgetWithPop(t *testing.T) {
+	rl := New(time.Duration(1) * time.Hour)
+	rl.Push(0)
+	rl.Pop()
+
+	if rl.Allow() {
+		t.Fail()
+	}
+}
+
+func TestLimiter_AllowN_consumesNDurations(t *testing.T) {
+	d := time.Duration(1) * time.Minute
+	rl := New(d)
+	now := time.Now()
+
+	if !rl.AllowN(now, 3) {
+		t.Fail()
+	}
+
+	if rl.AllowN(now.Add(2*d), 1) {
+		t.Fail()
+	}
+
+	if !rl.AllowN(now.Add(3*d), 1) {
 		t.Fail()
 	}
 }
@@ -111,9 +1116,161 @@ func TestLimiter_endToEndWorksForLargeDurations(t *testing.T) {
 		want++
 	}
 
+	if want != 4 {
+		t.Fatal(want)
+	}
+
 	for i := 0; i < len(popTimes)-1; i++ {
 		if popTimes[i+1].Sub(popTimes[i]) < d {
 			t.Fail()
 		}
 	}
 }
+
+func TestLimiter_PauseUntil_preventsReleasesUntilTheTime(t *testing.T) {
+	rl := New(time.Duration(1))
+	now := time.Now()
+	rl.PauseUntil(now.Add(time.Hour))
+	rl.PauseUntil(now)
+
+	if rl.AllowN(now.Add(30*time.Minute), 1) || rl.DelayN(now, 1) != time.Hour {
+		t.Fail()
+	}
+	if !rl.AllowN(now.Add(time.Hour), 1) {
+		t.Fail()
+	}
+
+	rl.PauseUntil(time.Now().Add(time.Hour))
+	rl.Reset()
+	if !rl.Allow() {
+		t.Fail()
+	}
+}
+
+func TestLimiter_PopContext_returnsContextErrorIfDone(t *testing.T) {
+	rl := New(time.Duration(1))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := rl.PopContext(ctx); err != context.Canceled {
+		t.Fail()
+	}
+
+	rl.Push(1)
+	if v, err := rl.PopContext(context.Background()); v != 1 || err != nil {
+		t.Fail()
+	}
+	rl.Close()
+	if _, err := rl.PopContext(context.Background()); err != ErrClosed {
+		t.Fail()
+	}
+}
+
+func TestLimiter_Pop_servesConcurrentCallersInTheOrderTheyWait(t *testing.T) {
+	l := NewCapacity(time.Duration(1)*time.Millisecond, Unbounded)
+
+	const callers = 10
+	results := make([]chan interface{}, callers)
+	for i := range results {
+		results[i] = make(chan interface{}, 1)
+		go func(result chan interface{}) {
+			result <- l.Pop()
+		}(results[i])
+		waitForPoppers(l, i+1)
+	}
+	for i := 0; i < callers; i++ {
+		l.Push(i)
+	}
+
+	for i, result := range results {
+		if v := <-result; v != i {
+			t.Fatalf("caller %v received %v", i, v)
+		}
+	}
+}
+
+func TestLimiter_PopContext_leavesTheLineWhenDone(t *testing.T) {
+	l := New(time.Duration(1) * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := l.PopContext(ctx)
+		done <- err
+	}()
+	waitForPoppers(l, 1)
+	cancel()
+	<-done
+
+	l.Push(1)
+	if v, err := l.PopTimeout(time.Second); v != 1 || err != nil {
+		t.Fatal(v, err)
+	}
+}
+
+func waitForPoppers(l *Limiter, n int) {
+	for {
+		l.lock.Lock()
+		count := l.poppers.len()
+		l.lock.Unlock()
+		if count == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLimiter_doesNotRetainValuesAfterTheyLeave(t *testing.T) {
+	const size = 16 << 20
+
+	for name, remove := range map[string]func(rl *Limiter){
+		"Pop": func(rl *Limiter) {
+			for i := 0; i < 4; i++ {
+				rl.Pop()
+			}
+		},
+		"PopAvailable": func(rl *Limiter) { rl.PopAvailable() },
+		"CloseDiscard": func(rl *Limiter) { rl.CloseDiscard() },
+		"Drain":        func(rl *Limiter) { rl.Drain() },
+		"Reset":        func(rl *Limiter) { rl.Reset() },
+	} {
+		rl := NewUnbounded(0)
+		rl.PushPriority(make([]byte, size), 1)
+		for i := 0; i < 3; i++ {
+			rl.Push(make([]byte, size))
+		}
+		remove(rl)
+
+		if heap := heapAlloc(); heap >= size {
+			t.Errorf("%v: %v bytes still allocated", name, heap)
+		}
+		if rl.Len() != 0 {
+			t.Fatal(name, rl.Len())
+		}
+	}
+}
+
+func heapAlloc() uint64 {
+	runtime.GC()
+	stats := &runtime.MemStats{}
+	runtime.ReadMemStats(stats)
+	return stats.HeapAlloc
+}
+
+func TestLimiter_pacingDoesNotAllocate(t *testing.T) {
+	rl := New(time.Microsecond)
+	rl.Wait()
+
+	if allocs := testing.AllocsPerRun(100, func() { rl.Allow() }); allocs != 0 {
+		t.Error("Allow", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, rl.Wait); allocs != 0 {
+		t.Error("Wait", allocs)
+	}
+	timeout := func() {
+		rl.PushTimeout(0, time.Second)
+		rl.PopTimeout(time.Second)
+	}
+	if allocs := testing.AllocsPerRun(100, timeout); allocs != 0 {
+		t.Error("PushTimeout and PopTimeout", allocs)
+	}
+}
@@ -0,0 +1,309 @@
+//Package ratelimitconfig builds ratelimit Limiters from configuration files
+//and applies changes to the files while the Limiters are in use, so that limits
+//can be tuned without redeploying.
+//
+//Configurations are read as JSON by default. Other formats such as YAML are
+//read by providing their Unmarshal function, for example gopkg.in/yaml.v3's
+//yaml.Unmarshal:
+//
+//	limiters:
+//	  - name: api
+//	    duration: 100/s
+//	    burst: 20
+//	    capacity: 100
+//	  - name: tenants
+//	    duration: 1s
+//	    keyed: true
+//	    keys:
+//	      premium:
+//	        duration: 100ms
+package ratelimitconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+//Unmarshal parses data into v, like json.Unmarshal.
+type Unmarshal func(data []byte, v interface{}) error
+
+//Config is a set of Limiter definitions.
+type Config struct {
+	Limiters []Limiter `json:"limiters" yaml:"limiters"`
+}
+
+//Limiter defines a ratelimit.Limiter, or a ratelimit.KeyedLimiter if Keyed is
+//set.
+type Limiter struct {
+	//Name identifies the Limiter in a Registry.
+	Name string `json:"name" yaml:"name"`
+
+	//Duration is the throughput duration of the Limiter, such as "10ms", or a
+	//ratelimit.Rate such as "100/s".
+	Duration string `json:"duration" yaml:"duration"`
+
+	//Burst is the number of values that may be released at once, if more than
+	//one. It only takes effect when the Limiter is created.
+	Burst int `json:"burst,omitempty" yaml:"burst,omitempty"`
+
+	//Capacity is the capacity of the Limiter, which is ratelimit.DefaultCapacity
+	//if zero and may be ratelimit.Unbounded.
+	Capacity int `json:"capacity,omitempty" yaml:"capacity,omitempty"`
+
+	//Keyed makes the Limiter a KeyedLimiter, and Keys overrides the duration
+	//and capacity of some of its keys.
+	Keyed bool           `json:"keyed,omitempty" yaml:"keyed,omitempty"`
+	Keys  map[string]Key `json:"keys,omitempty" yaml:"keys,omitempty"`
+}
+
+//Key overrides the defaults of one key of a KeyedLimiter.
+//Empty fields keep the default.
+//Its Duration may also be a ratelimit.Rate.
+type Key struct {
+	Duration string `json:"duration,omitempty" yaml:"duration,omitempty"`
+	Capacity int    `json:"capacity,omitempty" yaml:"capacity,omitempty"`
+}
+
+//ErrNoName is returned for a Limiter definition without a name.
+var ErrNoName = errors.New("ratelimitconfig: limiter without a name")
+
+//Parse parses data into a Config using unmarshal, or json.Unmarshal if unmarshal
+//is nil, and validates it.
+func Parse(data []byte, unmarshal Unmarshal) (Config, error) {
+	if unmarshal == nil {
+		unmarshal = json.Unmarshal
+	}
+
+	c := Config{}
+	if err := unmarshal(data, &c); err != nil {
+		return Config{}, err
+	}
+	if _, err := c.parse(); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+//ParseFile is like Parse but reads the data from the file at path.
+func ParseFile(path string, unmarshal Unmarshal) (Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	return Parse(data, unmarshal)
+}
+
+//spec is a validated Limiter definition.
+type spec struct {
+	Limiter
+
+	d    time.Duration
+	keys map[string]keySpec
+}
+
+type keySpec struct {
+	d        time.Duration
+	hasD     bool
+	capacity int
+}
+
+func (c Config) parse() ([]spec, error) {
+	seen := map[string]bool{}
+	specs := make([]spec, 0, len(c.Limiters))
+	for _, def := range c.Limiters {
+		if def.Name == "" {
+			return nil, ErrNoName
+		}
+		if seen[def.Name] {
+			return nil, fmt.Errorf("ratelimitconfig: limiter %q defined twice", def.Name)
+		}
+		seen[def.Name] = true
+
+		if def.Capacity < ratelimit.Unbounded {
+			return nil, fmt.Errorf("ratelimitconfig: limiter %s: invalid capacity %d", def.Name, def.Capacity)
+		}
+
+		s := spec{Limiter: def, keys: map[string]keySpec{}}
+		d, err := parseDuration(def.Name, def.Duration)
+		if err != nil {
+			return nil, err
+		}
+		s.d = d
+		for key, k := range def.Keys {
+			if k.Capacity < ratelimit.Unbounded {
+				return nil, fmt.Errorf("ratelimitconfig: limiter %s key %s: invalid capacity %d", def.Name, key, k.Capacity)
+			}
+			ks := keySpec{capacity: k.Capacity}
+			if k.Duration != "" {
+				ks.d, err = parseDuration(def.Name+" key "+key, k.Duration)
+				if err != nil {
+					return nil, err
+				}
+				ks.hasD = true
+			}
+			s.keys[key] = ks
+		}
+		specs = append(specs, s)
+	}
+	return specs, nil
+}
+
+//parseDuration parses value as a time.Duration, or as a ratelimit.Rate if it
+//contains a slash.
+func parseDuration(name, value string) (time.Duration, error) {
+	if strings.Contains(value, "/") {
+		rate, err := ratelimit.ParseRate(value)
+		if err != nil {
+			return 0, fmt.Errorf("ratelimitconfig: limiter %s: %v", name, err)
+		}
+		return rate.Duration(), nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("ratelimitconfig: limiter %s: %v", name, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("ratelimitconfig: limiter %s: negative duration %v", name, d)
+	}
+	return d, nil
+}
+
+func (s spec) capacity() int {
+	if s.Capacity == 0 {
+		return ratelimit.DefaultCapacity
+	}
+	return s.Capacity
+}
+
+func (s spec) options() []ratelimit.Option {
+	opts := []ratelimit.Option{ratelimit.WithCapacity(s.capacity())}
+	if s.Burst > 1 {
+		opts = append(opts, ratelimit.WithBurst(s.Burst))
+	}
+	return opts
+}
+
+//Registry holds the Limiters built from a Config by name.
+//It is safe for concurrent use.
+type Registry struct {
+	lock    *sync.Mutex
+	limiter map[string]*ratelimit.Limiter
+	keyed   map[string]*ratelimit.KeyedLimiter
+}
+
+//NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		lock:    &sync.Mutex{},
+		limiter: map[string]*ratelimit.Limiter{},
+		keyed:   map[string]*ratelimit.KeyedLimiter{},
+	}
+}
+
+//Apply creates the Limiters defined by c that are not yet in r, and updates
+//those that are with SetDuration and SetCapacity, keeping their queued values.
+//
+//Limiters that are in r but not in c are kept unchanged, and overrides of keys
+//that are no longer in c keep their last values.
+//An error is returned, and nothing is changed, if c is invalid or changes
+//whether a Limiter in r is keyed.
+func (r *Registry) Apply(c Config) error {
+	specs, err := c.parse()
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, s := range specs {
+		_, isLimiter := r.limiter[s.Name]
+		_, isKeyed := r.keyed[s.Name]
+		if (isLimiter && s.Keyed) || (isKeyed && !s.Keyed) {
+			return fmt.Errorf("ratelimitconfig: limiter %q cannot change whether it is keyed", s.Name)
+		}
+	}
+
+	for _, s := range specs {
+		if s.Keyed {
+			r.applyKeyedLocked(s)
+		} else {
+			r.applyLimiterLocked(s)
+		}
+	}
+	return nil
+}
+
+func (r *Registry) applyLimiterLocked(s spec) {
+	l, ok := r.limiter[s.Name]
+	if !ok {
+		r.limiter[s.Name] = ratelimit.New(s.d, s.options()...)
+		return
+	}
+	l.SetDuration(s.d)
+	l.SetCapacity(s.capacity())
+}
+
+func (r *Registry) applyKeyedLocked(s spec) {
+	k, ok := r.keyed[s.Name]
+	if !ok {
+		k = ratelimit.NewKeyed(s.d, s.options()...)
+		r.keyed[s.Name] = k
+	} else {
+		k.SetDuration(s.d)
+		k.SetCapacity(s.capacity())
+	}
+
+	for key, ks := range s.keys {
+		if ks.hasD {
+			k.SetKeyRate(key, ks.d)
+		}
+		if ks.capacity != 0 {
+			k.SetKeyCapacity(key, ks.capacity)
+		}
+	}
+}
+
+//Limiter returns the Limiter named name and whether there is one.
+func (r *Registry) Limiter(name string) (*ratelimit.Limiter, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	l, ok := r.limiter[name]
+	return l, ok
+}
+
+//Keyed returns the KeyedLimiter named name and whether there is one.
+func (r *Registry) Keyed(name string) (*ratelimit.KeyedLimiter, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	k, ok := r.keyed[name]
+	return k, ok
+}
+
+//Names returns the names of all Limiters and KeyedLimiters in r in sorted
+//order.
+func (r *Registry) Names() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	names := make([]string, 0, len(r.limiter)+len(r.keyed))
+	for name := range r.limiter {
+		names = append(names, name)
+	}
+	for name := range r.keyed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,169 @@
+package ratelimitconfig
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+const config = `{
+	"limiters": [
+		{"name": "api", "duration": "1h", "burst": 2, "capacity": 3},
+		{"name": "tenants", "duration": "1h", "keyed": true, "keys": {
+			"premium": {"duration": "1/m", "capacity": -1}
+		}}
+	]
+}`
+
+func TestParse_rejectsInvalidConfigs(t *testing.T) {
+	for _, data := range []string{
+		`{"limiters": [{"duration": "1s"}]}`,
+		`{"limiters": [{"name": "a", "duration": "fast"}]}`,
+		`{"limiters": [{"name": "a", "duration": "1s", "keys": {"k": {"duration": "x"}}}]}`,
+		`{"limiters": [{"name": "a", "duration": "1s"}, {"name": "a", "duration": "1s"}]}`,
+		`{"limiters": [{"name": "a", "duration": "0/s"}]}`,
+		`{"limiters": [{"name": "a", "duration": "-1s"}]}`,
+		`{"limiters": [{"name": "a", "duration": "1s", "capacity": -2}]}`,
+		`{"limiters": [{"name": "a", "duration": "1s", "keys": {"k": {"capacity": -2}}}]}`,
+		`{"limiters": `,
+	} {
+		if _, err := Parse([]byte(data), nil); err == nil {
+			t.Error(data)
+		}
+	}
+}
+
+func TestParse_usesTheUnmarshalFunction(t *testing.T) {
+	unmarshal := func(data []byte, v interface{}) error {
+		v.(*Config).Limiters = []Limiter{{Name: string(data), Duration: "1s"}}
+		return nil
+	}
+
+	c, err := Parse([]byte("api"), unmarshal)
+	if err != nil || len(c.Limiters) != 1 || c.Limiters[0].Name != "api" {
+		t.Fatal(c, err)
+	}
+}
+
+func TestRegistry_Apply_buildsTheLimiters(t *testing.T) {
+	c, err := Parse([]byte(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry()
+	if err := r.Apply(c); err != nil {
+		t.Fatal(err)
+	}
+
+	l, ok := r.Limiter("api")
+	if !ok || l.Duration() != time.Duration(1)*time.Hour || l.Cap() != 3 {
+		t.Fatal("api")
+	}
+	if !l.Allow() || !l.Allow() || l.Allow() {
+		t.Fatal("burst")
+	}
+
+	k, ok := r.Keyed("tenants")
+	if !ok || k.Duration("premium") != time.Minute || k.Duration("other") != time.Duration(1)*time.Hour {
+		t.Fatal("tenants")
+	}
+
+	if names := r.Names(); strings.Join(names, ",") != "api,tenants" {
+		t.Fatal(names)
+	}
+}
+
+func TestRegistry_Apply_updatesExistingLimiters(t *testing.T) {
+	r := NewRegistry()
+	c, _ := Parse([]byte(config), nil)
+	r.Apply(c)
+	l, _ := r.Limiter("api")
+	l.Push(1)
+
+	c.Limiters[0].Duration = "1m"
+	c.Limiters[0].Capacity = ratelimit.Unbounded
+	c.Limiters[1].Duration = "2h"
+	if err := r.Apply(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if same, _ := r.Limiter("api"); same != l {
+		t.Fatal("limiter should be kept")
+	}
+	if l.Duration() != time.Minute || l.Cap() != ratelimit.Unbounded || l.Len() != 1 {
+		t.Fail()
+	}
+	if k, _ := r.Keyed("tenants"); k.Duration("other") != time.Duration(2)*time.Hour {
+		t.Fail()
+	}
+}
+
+func TestRegistry_Apply_rejectsChangingWhetherALimiterIsKeyed(t *testing.T) {
+	r := NewRegistry()
+	c, _ := Parse([]byte(config), nil)
+	r.Apply(c)
+
+	c.Limiters[0].Keyed = true
+	c.Limiters[1].Duration = "2h"
+	if err := r.Apply(c); err == nil {
+		t.Fatal("expected an error")
+	}
+	if k, _ := r.Keyed("tenants"); k.Duration("other") != time.Duration(1)*time.Hour {
+		t.Fatal("nothing should be changed")
+	}
+}
+
+func TestWatcher_Run_appliesChangesToTheFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ratelimitconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.json")
+	ioutil.WriteFile(path, []byte(`{"limiters": [{"name": "api", "duration": "1h"}]}`), 0644)
+
+	r := NewRegistry()
+	w := &Watcher{Path: path, Registry: r, Interval: time.Duration(5) * time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	for i := 0; i < 100; i++ {
+		if _, ok := r.Limiter("api"); ok {
+			break
+		}
+		time.Sleep(time.Duration(5) * time.Millisecond)
+	}
+	ioutil.WriteFile(path, []byte(`{"limiters": [{"name": "api", "duration": "90s"}]}`), 0644)
+
+	for i := 0; i < 100; i++ {
+		if l, ok := r.Limiter("api"); ok && l.Duration() == time.Duration(90)*time.Second {
+			break
+		}
+		time.Sleep(time.Duration(5) * time.Millisecond)
+	}
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Fatal(err)
+	}
+	if l, _ := r.Limiter("api"); l.Duration() != time.Duration(90)*time.Second {
+		t.Fail()
+	}
+}
+
+func TestWatcher_Run_returnsTheFirstError(t *testing.T) {
+	w := &Watcher{Path: filepath.Join(os.TempDir(), "ratelimitconfig-missing.json"), Registry: NewRegistry()}
+
+	if err := w.Run(context.Background()); err == nil {
+		t.Fail()
+	}
+}
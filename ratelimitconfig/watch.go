@@ -0,0 +1,92 @@
+package ratelimitconfig
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+//DefaultInterval is how often a Watcher checks its file if its Interval is not
+//set.
+const DefaultInterval = time.Duration(5) * time.Second
+
+//Watcher keeps a Registry up to date with a configuration file.
+//The file is polled for changes to its modification time or size, so that it
+//also works with editors and configuration systems that replace the file.
+type Watcher struct {
+	//Path is the path of the configuration file.
+	Path string
+
+	//Registry is the Registry the configuration is applied to.
+	Registry *Registry
+
+	//Unmarshal parses the file, defaulting to json.Unmarshal.
+	Unmarshal Unmarshal
+
+	//Interval is how often the file is checked, defaulting to DefaultInterval.
+	Interval time.Duration
+
+	//OnError, if set, is called with the errors reading, parsing, or applying a
+	//changed file. The Registry is left unchanged by a file with an error.
+	OnError func(error)
+}
+
+//Run applies the file to the Registry, then applies it again each time it
+//changes until ctx is done.
+//An error applying the file the first time is returned immediately. Otherwise
+//ctx.Err() is returned.
+func (w *Watcher) Run(ctx context.Context) error {
+	modified, size, err := w.apply()
+	if err != nil {
+		return err
+	}
+
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(w.Path)
+		if err != nil {
+			w.handle(err)
+			continue
+		}
+		if info.ModTime().Equal(modified) && info.Size() == size {
+			continue
+		}
+		if modified, size, err = w.apply(); err != nil {
+			w.handle(err)
+		}
+	}
+}
+
+//apply applies the file and returns the modification time and size it had
+//before it was read.
+//They are returned even if applying fails, so that a broken file is reported
+//once rather than on every check.
+func (w *Watcher) apply() (time.Time, int64, error) {
+	info, err := os.Stat(w.Path)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	c, err := ParseFile(w.Path, w.Unmarshal)
+	if err == nil {
+		err = w.Registry.Apply(c)
+	}
+	return info.ModTime(), info.Size(), err
+}
+
+func (w *Watcher) handle(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+	}
+}
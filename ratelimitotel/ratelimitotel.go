@@ -0,0 +1,109 @@
+//Package ratelimitotel instruments ratelimit Limiters for OpenTelemetry.
+//
+//This package does not depend on the OpenTelemetry SDK. Instead it defines the
+//small Meter and Span interfaces that it records to, which are implemented by
+//adapting OpenTelemetry instruments and spans. For example:
+//
+//	type meter struct {
+//		pushes, pops, drops metric.Int64Counter
+//		wait                metric.Float64Histogram
+//	}
+//
+//	func (m meter) AddPushes(n int64)         { m.pushes.Add(context.Background(), n) }
+//	func (m meter) RecordWait(d time.Duration) { m.wait.Record(context.Background(), d.Seconds()) }
+//	...
+//
+//	l := ratelimit.New(d, ratelimit.WithObserver(ratelimitotel.NewObserver(meter{...})))
+package ratelimitotel
+
+import (
+	"context"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+//Meter records the metrics of a Limiter.
+type Meter interface {
+	//AddPushes, AddPops, and AddDrops add n to the counters of values pushed,
+	//released, and removed without being released.
+	AddPushes(n int64)
+	AddPops(n int64)
+	AddDrops(n int64)
+
+	//RecordWait records how long a released value was queued to a histogram.
+	RecordWait(d time.Duration)
+}
+
+//NewObserver returns a ratelimit.Observer that records to m.
+func NewObserver(m Meter) ratelimit.Observer {
+	return observer{m}
+}
+
+type observer struct {
+	m Meter
+}
+
+func (o observer) Pushed(value interface{}) {
+	o.m.AddPushes(1)
+}
+
+func (o observer) Popped(value interface{}, wait time.Duration) {
+	o.m.AddPops(1)
+	o.m.RecordWait(wait)
+}
+
+func (o observer) Dropped(value interface{}) {
+	o.m.AddDrops(1)
+}
+
+func (o observer) Closed() {}
+
+//WaitEvent is the name of the span event recorded by a Recorder.
+const WaitEvent = "ratelimit.wait"
+
+//WaitAttribute is the attribute of WaitEvent holding how long the call waited
+//due to rate limiting, in seconds.
+const WaitAttribute = "ratelimit.wait_seconds"
+
+//Span is the part of an OpenTelemetry span that a Recorder annotates.
+type Span interface {
+	//AddEvent adds an event named name with attributes to the span.
+	AddEvent(name string, attributes map[string]interface{})
+}
+
+//Recorder annotates the spans of calls to a Limiter with how long they waited due
+//to rate limiting, so that the wait is attributed correctly in traces.
+type Recorder struct {
+	//SpanFromContext returns the span of ctx, typically by adapting the span
+	//returned by trace.SpanFromContext. It may return nil if ctx has no span.
+	SpanFromContext func(ctx context.Context) Span
+}
+
+//Wait calls l.WaitContext(ctx) and records how long it waited to the span of
+//ctx.
+func (r Recorder) Wait(ctx context.Context, l *ratelimit.Limiter) error {
+	start := time.Now()
+	err := l.WaitContext(ctx)
+	r.record(ctx, time.Since(start))
+	return err
+}
+
+//Pop calls l.PopContext(ctx) and records how long it waited to the span of ctx.
+func (r Recorder) Pop(ctx context.Context, l *ratelimit.Limiter) (interface{}, error) {
+	start := time.Now()
+	value, err := l.PopContext(ctx)
+	r.record(ctx, time.Since(start))
+	return value, err
+}
+
+func (r Recorder) record(ctx context.Context, wait time.Duration) {
+	if r.SpanFromContext == nil {
+		return
+	}
+	if span := r.SpanFromContext(ctx); span != nil {
+		span.AddEvent(WaitEvent, map[string]interface{}{
+			WaitAttribute: wait.Seconds(),
+		})
+	}
+}
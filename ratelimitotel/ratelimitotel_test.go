@@ -0,0 +1,69 @@
+package ratelimitotel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+type fakeMeter struct {
+	pushes, pops, drops int64
+	waits               []time.Duration
+}
+
+func (m *fakeMeter) AddPushes(n int64) { m.pushes += n }
+
+func (m *fakeMeter) AddPops(n int64) { m.pops += n }
+
+func (m *fakeMeter) AddDrops(n int64) { m.drops += n }
+
+func (m *fakeMeter) RecordWait(d time.Duration) { m.waits = append(m.waits, d) }
+
+func TestNewObserver_recordsToTheMeter(t *testing.T) {
+	m := &fakeMeter{}
+	l := ratelimit.New(time.Duration(1), ratelimit.WithCapacity(ratelimit.Unbounded), ratelimit.WithObserver(NewObserver(m)))
+	l.PushAll(1, 2, 3)
+	l.Pop()
+	l.CloseDiscard()
+
+	if m.pushes != 3 || m.pops != 1 || m.drops != 2 || len(m.waits) != 1 {
+		t.Fail()
+	}
+}
+
+type fakeSpan struct {
+	name       string
+	attributes map[string]interface{}
+}
+
+func (s *fakeSpan) AddEvent(name string, attributes map[string]interface{}) {
+	s.name, s.attributes = name, attributes
+}
+
+func TestRecorder_Wait_recordsTheWaitToTheSpan(t *testing.T) {
+	span := &fakeSpan{}
+	r := Recorder{SpanFromContext: func(ctx context.Context) Span {
+		return span
+	}}
+	l := ratelimit.New(time.Duration(20) * time.Millisecond)
+	l.Wait()
+
+	if err := r.Wait(context.Background(), l); err != nil {
+		t.Fatal(err)
+	}
+
+	if span.name != WaitEvent || span.attributes[WaitAttribute].(float64) < 0.015 {
+		t.Fatal(span)
+	}
+}
+
+func TestRecorder_Pop_toleratesMissingSpans(t *testing.T) {
+	l := ratelimit.New(time.Duration(1))
+	l.Push(1)
+
+	if v, err := (Recorder{}).Pop(context.Background(), l); v != 1 || err != nil {
+		t.Fail()
+	}
+}
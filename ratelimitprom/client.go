@@ -0,0 +1,50 @@
+//go:build prometheus
+
+package ratelimitprom
+
+import "github.com/prometheus/client_golang/prometheus"
+
+//Limiters that are not keyed have an empty key label, since every metric of a
+//prometheus.Collector must have the same labels.
+var (
+	variableLabels = []string{"name", "key"}
+
+	queueDepthDesc = prometheus.NewDesc("ratelimit_queue_depth", "Number of values queued in the limiter.", variableLabels, nil)
+	pushesDesc     = prometheus.NewDesc("ratelimit_pushes_total", "Total number of values pushed to the limiter.", variableLabels, nil)
+	popsDesc       = prometheus.NewDesc("ratelimit_pops_total", "Total number of values released by the limiter.", variableLabels, nil)
+	dropsDesc      = prometheus.NewDesc("ratelimit_drops_total", "Total number of values removed from the limiter without being released.", variableLabels, nil)
+	closedDesc     = prometheus.NewDesc("ratelimit_closed", "Whether the limiter is closed.", variableLabels, nil)
+	waitDesc       = prometheus.NewDesc("ratelimit_wait_seconds", "How long released values were queued in the limiter.", variableLabels, nil)
+)
+
+var _ prometheus.Collector = &Collector{}
+
+//Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{queueDepthDesc, pushesDesc, popsDesc, dropsDesc, closedDesc, waitDesc} {
+		ch <- desc
+	}
+}
+
+//Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.snapshot() {
+		name, key := s.labels.name, s.labels.key
+
+		closed := 0.0
+		if s.closed {
+			closed = 1
+		}
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(s.depth()), name, key)
+		ch <- prometheus.MustNewConstMetric(pushesDesc, prometheus.CounterValue, float64(s.pushes), name, key)
+		ch <- prometheus.MustNewConstMetric(popsDesc, prometheus.CounterValue, float64(s.pops), name, key)
+		ch <- prometheus.MustNewConstMetric(dropsDesc, prometheus.CounterValue, float64(s.drops), name, key)
+		ch <- prometheus.MustNewConstMetric(closedDesc, prometheus.GaugeValue, closed, name, key)
+
+		buckets := make(map[float64]uint64, len(c.buckets))
+		for i, bound := range c.buckets {
+			buckets[bound] = s.counts[i]
+		}
+		ch <- prometheus.MustNewConstHistogram(waitDesc, s.pops, s.sum, buckets, name, key)
+	}
+}
@@ -0,0 +1,39 @@
+//go:build prometheus
+
+package ratelimitprom
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector_isAPrometheusCollector(t *testing.T) {
+	c := NewCollector()
+	l := ratelimit.New(time.Duration(1), ratelimit.WithCapacity(ratelimit.Unbounded), ratelimit.WithObserver(c.Observer("a")))
+	k := ratelimit.NewKeyed(time.Duration(1))
+	k.SetObserver(c.Keyed("tenants"))
+	l.PushAll(1, 2, 3)
+	l.Pop()
+	k.PushKey("x", 1)
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(c)
+
+	if n, err := testutil.GatherAndCount(registry, "ratelimit_queue_depth", "ratelimit_wait_seconds"); err != nil || n != 4 {
+		t.Fatal(n, err)
+	}
+	expected := `
+# HELP ratelimit_queue_depth Number of values queued in the limiter.
+# TYPE ratelimit_queue_depth gauge
+ratelimit_queue_depth{key="",name="a"} 2
+ratelimit_queue_depth{key="x",name="tenants"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "ratelimit_queue_depth"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,305 @@
+//Package ratelimitprom exposes metrics of ratelimit Limiters to Prometheus.
+//
+//A Collector observes any number of Limiters and KeyedLimiters, and serves their
+//metrics in the Prometheus text exposition format, so it can be scraped directly
+//or mounted on an existing metrics endpoint without depending on the Prometheus
+//client library:
+//
+//	c := ratelimitprom.NewCollector()
+//	l := ratelimit.New(d, ratelimit.WithObserver(c.Observer("api")))
+//	http.Handle("/metrics", c)
+//
+//Built with the prometheus build tag, a Collector is also a prometheus.Collector
+//of github.com/prometheus/client_golang, so it can be registered with a
+//prometheus.Registry alongside other metrics:
+//
+//	prometheus.MustRegister(c)
+//
+//The following metrics are exposed for each observed Limiter, labeled by name and,
+//for keyed limiters, key:
+//
+//	ratelimit_queue_depth    gauge      values currently queued
+//	ratelimit_pushes_total   counter    values pushed
+//	ratelimit_pops_total     counter    values released
+//	ratelimit_drops_total    counter    values removed without being released
+//	ratelimit_closed         gauge      1 if the limiter is closed
+//	ratelimit_wait_seconds   histogram  how long released values were queued
+package ratelimitprom
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+//DefaultBuckets are the upper bounds in seconds of the buckets of the wait
+//histogram, matching the default buckets of the Prometheus client library.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+//Collector collects metrics of the Limiters it observes.
+type Collector struct {
+	lock *sync.Mutex
+
+	buckets []float64
+	series  map[labels]*series
+}
+
+//labels identify the Limiter that a series of metrics belongs to.
+type labels struct {
+	name string
+	key  string
+}
+
+//series holds the metrics of a single Limiter.
+type series struct {
+	c      *Collector
+	labels labels
+	keyed  bool
+
+	pushes uint64
+	pops   uint64
+	drops  uint64
+	closed bool
+
+	counts []uint64
+	sum    float64
+}
+
+//NewCollector creates a Collector whose wait histograms use buckets, or
+//DefaultBuckets if none are given.
+func NewCollector(buckets ...float64) *Collector {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+
+	return &Collector{
+		lock:    &sync.Mutex{},
+		buckets: buckets,
+		series:  map[labels]*series{},
+	}
+}
+
+//Observer returns the ratelimit.Observer that collects the metrics of the
+//Limiter named name. It should be added to a single Limiter with
+//ratelimit.WithObserver.
+func (c *Collector) Observer(name string) ratelimit.Observer {
+	return c.observer(labels{name: name}, false)
+}
+
+//Keyed returns a function for KeyedLimiter.SetObserver that collects the
+//metrics of each key of the KeyedLimiter named name.
+//Pass Evicted with the same name to KeyedLimiter.OnEvict so that the metrics of
+//evicted keys are removed rather than kept forever.
+func (c *Collector) Keyed(name string) func(key string) ratelimit.Observer {
+	return func(key string) ratelimit.Observer {
+		return c.observer(labels{name: name, key: key}, true)
+	}
+}
+
+//Evicted returns a function for KeyedLimiter.OnEvict that removes the metrics of
+//each key evicted from the KeyedLimiter named name.
+//If the key is used again, then its metrics start over from zero.
+func (c *Collector) Evicted(name string) func(key string) {
+	return func(key string) {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		delete(c.series, labels{name: name, key: key})
+	}
+}
+
+func (c *Collector) observer(l labels, keyed bool) *series {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if s, ok := c.series[l]; ok {
+		return s
+	}
+	s := &series{
+		c:      c,
+		labels: l,
+		keyed:  keyed,
+		counts: make([]uint64, len(c.buckets)),
+	}
+	c.series[l] = s
+	return s
+}
+
+func (s *series) Pushed(value interface{}) {
+	s.c.lock.Lock()
+	defer s.c.lock.Unlock()
+
+	s.pushes++
+}
+
+func (s *series) Popped(value interface{}, wait time.Duration) {
+	s.c.lock.Lock()
+	defer s.c.lock.Unlock()
+
+	s.pops++
+	seconds := wait.Seconds()
+	s.sum += seconds
+	for i, bound := range s.c.buckets {
+		if seconds <= bound {
+			s.counts[i]++
+		}
+	}
+}
+
+func (s *series) Dropped(value interface{}) {
+	s.c.lock.Lock()
+	defer s.c.lock.Unlock()
+
+	s.drops++
+}
+
+func (s *series) Closed() {
+	s.c.lock.Lock()
+	defer s.c.lock.Unlock()
+
+	s.closed = true
+}
+
+//ServeHTTP serves the metrics collected by c in the Prometheus text exposition
+//format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+//WriteTo writes the metrics collected by c to w in the Prometheus text
+//exposition format.
+func (c *Collector) WriteTo(w io.Writer) (n int64, err error) {
+	all := c.snapshot()
+
+	buffered := bufio.NewWriter(w)
+	cw := &countingWriter{w: buffered}
+	metric := func(name, kind, help string, value func(s series) float64) {
+		fmt.Fprintf(cw, "# HELP %v %v\n# TYPE %v %v\n", name, help, name, kind)
+		for _, s := range all {
+			fmt.Fprintf(cw, "%v{%v} %v\n", name, s.labelString(""), formatFloat(value(s)))
+		}
+	}
+
+	metric("ratelimit_queue_depth", "gauge", "Number of values queued in the limiter.", func(s series) float64 {
+		return float64(s.depth())
+	})
+	metric("ratelimit_pushes_total", "counter", "Total number of values pushed to the limiter.", func(s series) float64 {
+		return float64(s.pushes)
+	})
+	metric("ratelimit_pops_total", "counter", "Total number of values released by the limiter.", func(s series) float64 {
+		return float64(s.pops)
+	})
+	metric("ratelimit_drops_total", "counter", "Total number of values removed from the limiter without being released.", func(s series) float64 {
+		return float64(s.drops)
+	})
+	metric("ratelimit_closed", "gauge", "Whether the limiter is closed.", func(s series) float64 {
+		if s.closed {
+			return 1
+		}
+		return 0
+	})
+
+	const wait = "ratelimit_wait_seconds"
+	fmt.Fprintf(cw, "# HELP %v How long released values were queued in the limiter.\n# TYPE %v histogram\n", wait, wait)
+	for _, s := range all {
+		for i, bound := range c.buckets {
+			fmt.Fprintf(cw, "%v_bucket{%v} %v\n", wait, s.labelString(formatFloat(bound)), s.counts[i])
+		}
+		fmt.Fprintf(cw, "%v_bucket{%v} %v\n", wait, s.labelString("+Inf"), s.pops)
+		fmt.Fprintf(cw, "%v_sum{%v} %v\n", wait, s.labelString(""), formatFloat(s.sum))
+		fmt.Fprintf(cw, "%v_count{%v} %v\n", wait, s.labelString(""), s.pops)
+	}
+
+	if err := buffered.Flush(); err != nil && cw.err == nil {
+		cw.err = err
+	}
+	return cw.n, cw.err
+}
+
+//snapshot returns copies of all series of c sorted by their labels.
+func (c *Collector) snapshot() []series {
+	c.lock.Lock()
+	all := make([]series, 0, len(c.series))
+	for _, s := range c.series {
+		copied := *s
+		copied.counts = append([]uint64{}, s.counts...)
+		all = append(all, copied)
+	}
+	c.lock.Unlock()
+
+	sort.Sort(byLabels(all))
+	return all
+}
+
+//depth returns the number of values queued in the Limiter of s.
+func (s series) depth() uint64 {
+	return s.pushes - s.pops - s.drops
+}
+
+//labelString returns the labels of s formatted for the exposition format, with
+//an le label of le if it is not empty.
+func (s series) labelString(le string) string {
+	pairs := []string{`name="` + escape(s.labels.name) + `"`}
+	if s.keyed {
+		pairs = append(pairs, `key="`+escape(s.labels.key)+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	return strings.Join(pairs, ",")
+}
+
+//escape escapes a label value for the exposition format.
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+//byLabels sorts series by name and then key.
+type byLabels []series
+
+func (b byLabels) Len() int {
+	return len(b)
+}
+
+func (b byLabels) Less(i, j int) bool {
+	if b[i].labels.name != b[j].labels.name {
+		return b[i].labels.name < b[j].labels.name
+	}
+	return b[i].labels.key < b[j].labels.key
+}
+
+func (b byLabels) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
+
+//countingWriter counts the bytes written to w and remembers the first error.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
@@ -0,0 +1,92 @@
+package ratelimitprom
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+func TestCollector_WriteTo_writesTheMetricsOfEachLimiter(t *testing.T) {
+	c := NewCollector(1, 0.5)
+	l := ratelimit.New(time.Duration(1), ratelimit.WithCapacity(ratelimit.Unbounded), ratelimit.WithObserver(c.Observer(`a"b`)))
+	l.PushAll(1, 2, 3)
+	l.Pop()
+	l.CloseDiscard()
+
+	buffer := &bytes.Buffer{}
+	c.WriteTo(buffer)
+	out := buffer.String()
+
+	for _, want := range []string{
+		"# TYPE ratelimit_queue_depth gauge\nratelimit_queue_depth{name=\"a\\\"b\"} 0\n",
+		`ratelimit_pushes_total{name="a\"b"} 3`,
+		`ratelimit_pops_total{name="a\"b"} 1`,
+		`ratelimit_drops_total{name="a\"b"} 2`,
+		`ratelimit_closed{name="a\"b"} 1`,
+		"# TYPE ratelimit_wait_seconds histogram\n" +
+			`ratelimit_wait_seconds_bucket{name="a\"b",le="0.5"} 1` + "\n" +
+			`ratelimit_wait_seconds_bucket{name="a\"b",le="1"} 1` + "\n" +
+			`ratelimit_wait_seconds_bucket{name="a\"b",le="+Inf"} 1` + "\n",
+		`ratelimit_wait_seconds_count{name="a\"b"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%v", want, out)
+		}
+	}
+}
+
+func TestCollector_queueDepthMatchesLenWithDropNewest(t *testing.T) {
+	c := NewCollector()
+	l := ratelimit.New(time.Duration(1), ratelimit.WithOverflow(ratelimit.DropNewest), ratelimit.WithObserver(c.Observer("a")))
+	l.PushAll(1, 2, 3)
+
+	buffer := &bytes.Buffer{}
+	c.WriteTo(buffer)
+
+	if out := buffer.String(); !strings.Contains(out, `ratelimit_queue_depth{name="a"} 1`) {
+		t.Fatal(out)
+	}
+}
+
+func TestCollector_Keyed_labelsMetricsByKey(t *testing.T) {
+	c := NewCollector()
+	k := ratelimit.NewKeyed(time.Duration(1))
+	k.SetObserver(c.Keyed("tenants"))
+	k.PushKey("b", 1)
+	k.PushKey("a", 1)
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	out := w.Body.String()
+
+	a := strings.Index(out, `ratelimit_queue_depth{name="tenants",key="a"} 1`)
+	b := strings.Index(out, `ratelimit_queue_depth{name="tenants",key="b"} 1`)
+	if a < 0 || b < a {
+		t.Fatal(out)
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fail()
+	}
+}
+
+func TestCollector_Evicted_removesTheMetricsOfEvictedKeys(t *testing.T) {
+	c := NewCollector()
+	k := ratelimit.NewKeyed(time.Duration(1))
+	k.SetObserver(c.Keyed("tenants"))
+	k.OnEvict(c.Evicted("tenants"))
+	k.SetMaxKeys(1)
+	k.PushKey("a", 1)
+	k.PushKey("b", 1)
+
+	buffer := &bytes.Buffer{}
+	c.WriteTo(buffer)
+	out := buffer.String()
+
+	if strings.Contains(out, `key="a"`) || !strings.Contains(out, `ratelimit_queue_depth{name="tenants",key="b"} 1`) {
+		t.Fatal(out)
+	}
+}
@@ -0,0 +1,192 @@
+//Package ratelimittest provides a fake Clock and helpers for testing code that
+//uses ratelimit Limiters instantly and deterministically, without waiting in
+//real time.
+//
+//	c := ratelimittest.NewFakeClock(time.Unix(0, 0))
+//	l := ratelimit.New(time.Second, ratelimit.WithClock(c), ratelimit.WithBurst(2))
+//	ratelimittest.AssertSchedule(t, l, c, 0, 0, time.Second, 2*time.Second)
+package ratelimittest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+//FakeClock is a ratelimit.Clock whose time only moves when it is advanced.
+//Its timers fire as soon as the time passes them.
+type FakeClock struct {
+	lock   *sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+//NewFakeClock creates a FakeClock whose time is start.
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{
+		lock: &sync.Mutex{},
+		now:  start,
+	}
+}
+
+//Now implements ratelimit.Clock.
+func (c *FakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+//NewTimer implements ratelimit.Clock.
+func (c *FakeClock) NewTimer(d time.Duration) ratelimit.Timer {
+	return c.add(d, nil)
+}
+
+//AfterFunc implements ratelimit.Clock.
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) ratelimit.Timer {
+	return c.add(d, f)
+}
+
+func (c *FakeClock) add(d time.Duration, f func()) *fakeTimer {
+	t := &fakeTimer{clock: c, f: f}
+	if f == nil {
+		t.c = make(chan time.Time, 1)
+	}
+	t.Reset(d)
+	return t
+}
+
+//Advance moves the time of c forward by d, firing all timers that are due by
+//the new time.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+
+	due := []*fakeTimer{}
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.when.After(now) {
+			pending = append(pending, t)
+		} else {
+			due = append(due, t)
+		}
+	}
+	c.timers = pending
+	c.lock.Unlock()
+
+	for _, t := range due {
+		t.fire(now)
+	}
+}
+
+//Timers returns the number of timers of c that have not fired or been stopped,
+//which is the number of goroutines waiting for c.
+func (c *FakeClock) Timers() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return len(c.timers)
+}
+
+//BlockUntil blocks until c has at least n pending timers, for example to wait
+//for a goroutine to start waiting for a rate before advancing c.
+//It polls in real time, so it should only be used in tests.
+func (c *FakeClock) BlockUntil(n int) {
+	for c.Timers() < n {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	when  time.Time
+	c     chan time.Time
+	f     func()
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+
+	return t.removeLocked()
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.lock.Lock()
+	active := t.removeLocked()
+	t.when = t.clock.now.Add(d)
+	now := t.clock.now
+	if d > 0 {
+		t.clock.timers = append(t.clock.timers, t)
+	}
+	t.clock.lock.Unlock()
+
+	if d <= 0 {
+		t.fire(now)
+	}
+	return active
+}
+
+func (t *fakeTimer) removeLocked() bool {
+	for i, other := range t.clock.timers {
+		if other == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (t *fakeTimer) fire(now time.Time) {
+	if t.f != nil {
+		go t.f()
+		return
+	}
+	select {
+	case t.c <- now:
+	default:
+	}
+}
+
+//Schedule returns the offsets from the current time of c at which the next n
+//releases of l happen, advancing c to each of them in turn.
+//l must use c as its Clock.
+func Schedule(l *ratelimit.Limiter, c *FakeClock, n int) []time.Duration {
+	start := c.Now()
+	offsets := make([]time.Duration, 0, n)
+	for len(offsets) < n {
+		if l.Allow() {
+			offsets = append(offsets, c.Now().Sub(start))
+			continue
+		}
+		delay := l.Delay()
+		if delay <= 0 {
+			//The pacer refused without reporting a delay, so move on by the
+			//smallest step rather than looping forever.
+			delay = 1
+		}
+		c.Advance(delay)
+	}
+	return offsets
+}
+
+//AssertSchedule reports an error to t unless the next releases of l happen at
+//the offsets want from the current time of c, as returned by Schedule.
+func AssertSchedule(t testing.TB, l *ratelimit.Limiter, c *FakeClock, want ...time.Duration) {
+	t.Helper()
+
+	got := Schedule(l, c, len(want))
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("release schedule = %v, want %v", got, want)
+			return
+		}
+	}
+}
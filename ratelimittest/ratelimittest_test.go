@@ -0,0 +1,84 @@
+package ratelimittest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+func TestFakeClock_Advance_firesDueTimers(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	early := c.NewTimer(time.Second)
+	late := c.NewTimer(time.Minute)
+	fired := make(chan struct{})
+	c.AfterFunc(time.Second, func() {
+		close(fired)
+	})
+
+	c.Advance(time.Second)
+
+	select {
+	case <-early.C():
+	default:
+		t.Fatal("due timer should fire")
+	}
+	select {
+	case <-late.C():
+		t.Fatal("later timer should not fire")
+	default:
+	}
+	<-fired
+	if c.Timers() != 1 || !late.Stop() || c.Timers() != 0 {
+		t.Fatal(c.Timers())
+	}
+}
+
+func TestSchedule_returnsTheReleaseOffsets(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	l := ratelimit.New(time.Second, ratelimit.WithClock(c), ratelimit.WithBurst(2))
+
+	AssertSchedule(t, l, c, 0, 0, time.Second, time.Duration(2)*time.Second)
+	if now := c.Now(); !now.Equal(time.Unix(2, 0)) {
+		t.Fatal(now)
+	}
+}
+
+func TestAssertSchedule_reportsMismatches(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	l := ratelimit.New(time.Second, ratelimit.WithClock(c))
+	rec := &recorder{TB: t}
+
+	AssertSchedule(rec, l, c, 0, 0)
+
+	if !rec.failed {
+		t.Fail()
+	}
+}
+
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failed = true
+}
+
+func TestFakeClock_releasesAWaitingPop(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	l := ratelimit.New(time.Minute, ratelimit.WithClock(c))
+	l.Wait()
+	l.Push(1)
+
+	popped := make(chan interface{})
+	go func() {
+		popped <- l.Pop()
+	}()
+	c.BlockUntil(1)
+	c.Advance(time.Minute)
+
+	if v := <-popped; v != 1 {
+		t.Fatal(v)
+	}
+}
@@ -0,0 +1,180 @@
+package redislimit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+//respClient is a minimal Client that speaks the Redis protocol, so that the Lua
+//scripts of this package can be checked against a real server and not only
+//against fakeRedis, which mirrors them in Go.
+type respClient struct {
+	lock sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+//dialRedis connects to the Redis server at $REDISLIMIT_ADDR, skipping t if it
+//is not set.
+func dialRedis(t *testing.T) *respClient {
+	addr := os.Getenv("REDISLIMIT_ADDR")
+	if addr == "" {
+		t.Skip("REDISLIMIT_ADDR is not set")
+	}
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return &respClient{conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *respClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	deadline, _ := ctx.Deadline()
+	c.conn.SetDeadline(deadline)
+
+	command := []string{"EVAL", script, strconv.Itoa(len(keys))}
+	command = append(command, keys...)
+	for _, arg := range args {
+		command = append(command, fmt.Sprint(arg))
+	}
+	w := bufio.NewWriter(c.conn)
+	fmt.Fprintf(w, "*%d\r\n", len(command))
+	for _, arg := range command {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+//read reads a reply, returning integers as int64, bulk strings as string, and
+//arrays as []interface{}, just like the Eval of a client.
+func (c *respClient) read() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, ErrUnexpectedReply
+	}
+	kind, line := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return line, nil
+	case '-':
+		return nil, errors.New(line)
+	case ':':
+		return strconv.ParseInt(line, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		value := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, value); err != nil {
+			return nil, err
+		}
+		return string(value[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		values := make([]interface{}, n)
+		for i := range values {
+			if values[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	return nil, ErrUnexpectedReply
+}
+
+//redisKey returns a key that no other run of the tests uses.
+func redisKey(name string) string {
+	return "redislimit:test:" + name + ":" + strconv.FormatInt(time.Now().UnixNano(), 10)
+}
+
+func TestGCRA_againstRedis(t *testing.T) {
+	c := dialRedis(t)
+	key := redisKey("gcra")
+	a := NewGCRA(c, key, time.Second, time.Second)
+	b := NewGCRA(c, key, time.Second, time.Second)
+	var reported error
+	a.OnError(func(err error) {
+		reported = err
+	})
+	now := time.Now()
+
+	if delay := a.DelayN(now, 1); delay != 0 {
+		t.Fatal(delay)
+	}
+	if !a.AllowN(now, 1) || !b.AllowN(now, 1) {
+		t.Fatal("burst should be allowed", reported)
+	}
+	if a.AllowN(now, 1) || b.AllowN(now, 1) {
+		t.Fatal("burst should be shared")
+	}
+	if delay := b.DelayN(now, 1); delay != time.Second {
+		t.Fatal(delay)
+	}
+	if !a.AllowN(now.Add(time.Second), 1) {
+		t.Fatal("release should be allowed once the interval has passed")
+	}
+
+	a.Reset()
+
+	if !b.AllowN(now, 2) || reported != nil {
+		t.Fatal("Reset should forget all releases", reported)
+	}
+}
+
+func TestStore_againstRedis(t *testing.T) {
+	s := NewStore(dialRedis(t))
+	key := redisKey("store")
+	ctx := context.Background()
+
+	if _, ok, err := s.Get(ctx, key); ok || err != nil {
+		t.Fatal(ok, err)
+	}
+	if ok, err := s.CompareAndSwap(ctx, key, nil, []byte("a"), time.Second); !ok || err != nil {
+		t.Fatal("absent value should be swapped", err)
+	}
+	if ok, _ := s.CompareAndSwap(ctx, key, nil, []byte("b"), time.Second); ok {
+		t.Fatal("present value should not be swapped as absent")
+	}
+	if ok, _ := s.CompareAndSwap(ctx, key, []byte("b"), []byte("c"), time.Second); ok {
+		t.Fatal("different value should not be swapped")
+	}
+	if ok, _ := s.CompareAndSwap(ctx, key, []byte("a"), []byte("c"), 0); !ok {
+		t.Fatal("current value should be swapped")
+	}
+	if value, ok, _ := s.Get(ctx, key); !ok || string(value) != "c" {
+		t.Fatal(string(value), ok)
+	}
+
+	g := ratelimit.NewStoreGCRA(s, redisKey("storegcra"), time.Second, 0)
+	now := time.Now()
+	if !g.AllowN(now, 1) || g.AllowN(now, 1) {
+		t.Fail()
+	}
+}
@@ -0,0 +1,223 @@
+//Package redislimit implements ratelimit Pacers whose state is kept in Redis, so
+//that every instance of a horizontally scaled service shares one budget.
+//
+//The state of a Pacer is read and updated atomically by a Lua script, so
+//concurrent decisions by different instances never double count a release.
+//This package does not depend on a Redis client. Instead it defines the small
+//Client interface that it evaluates scripts with, which is implemented by
+//adapting a client. For example, with github.com/redis/go-redis:
+//
+//	type client struct {
+//		*redis.Client
+//	}
+//
+//	func (c client) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+//		return c.Client.Eval(ctx, script, keys, args...).Result()
+//	}
+//
+//	g := redislimit.NewGCRA(client{rdb}, "ratelimit:api", d, 4*d)
+//	l := ratelimit.New(d, ratelimit.WithPacer(g))
+//
+//The release times passed to a Pacer are those of the calling instance, so the
+//clocks of all instances sharing a key should be synchronized.
+package redislimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+//Client evaluates Lua scripts on a Redis server.
+type Client interface {
+	//Eval evaluates script with keys as KEYS and args as ARGV and returns its
+	//result, where Lua tables are returned as []interface{} and Lua numbers as
+	//int64.
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+//ErrUnexpectedReply is returned when a script returns a reply that is not of
+//the expected form.
+var ErrUnexpectedReply = errors.New("redislimit: unexpected reply")
+
+//gcraScript implements the generic cell rate algorithm on the theoretical
+//arrival time stored at KEYS[1], in microseconds since the Unix epoch.
+//ARGV holds the current time, the emission interval, and the burst tolerance in
+//microseconds, the number of releases, and whether they are counted.
+//It returns whether the releases may happen and, if not, how many microseconds
+//until they may.
+//The key expires once its theoretical arrival time has passed, since it is then
+//equivalent to an absent key.
+const gcraScript = `
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local tolerance = tonumber(ARGV[3])
+local n = tonumber(ARGV[4])
+local tat = tonumber(redis.call("GET", KEYS[1])) or now
+local delay = tat - tolerance - now
+if delay > 0 then
+	return {0, delay}
+end
+if ARGV[5] == "1" then
+	if tat < now then
+		tat = now
+	end
+	tat = tat + n * interval
+	redis.call("SET", KEYS[1], string.format("%.0f", tat), "PX", math.ceil((tat - now) / 1000) + 1)
+end
+return {1, 0}
+`
+
+const deleteScript = `return redis.call("DEL", KEYS[1])`
+
+//GCRA is a ratelimit.Pacer that implements the generic cell rate algorithm
+//with its state stored in Redis.
+//It behaves like ratelimit.GCRA, except that all GCRAs using the same key share
+//their releases.
+//The calls to Redis made by AllowN, DelayN, and Reset give up after a timeout,
+//which is ratelimit.DefaultRemoteTimeout unless changed with SetTimeout.
+type GCRA struct {
+	client Client
+	key    string
+	remote *ratelimit.Remote
+
+	lock      *sync.Mutex
+	interval  time.Duration
+	tolerance time.Duration
+}
+
+//NewGCRA creates a GCRA that stores its state at key using client, with
+//emission interval interval and burst tolerance tolerance.
+func NewGCRA(client Client, key string, interval, tolerance time.Duration) *GCRA {
+	return &GCRA{
+		client:    client,
+		key:       key,
+		remote:    ratelimit.NewRemote(),
+		lock:      &sync.Mutex{},
+		interval:  interval,
+		tolerance: tolerance,
+	}
+}
+
+//AllowN reports whether n releases may happen at time t, counting them if they
+//may.
+//If Redis cannot be reached, then the releases are refused and the error is
+//passed to the function set by OnError.
+func (g *GCRA) AllowN(t time.Time, n int) bool {
+	ctx, cancel := g.remote.Context()
+	defer cancel()
+
+	ok, _, err := g.TakeN(ctx, t, n)
+	if err != nil {
+		g.remote.Handle(err)
+		return false
+	}
+	return ok
+}
+
+//DelayN returns how long after t it will be before n releases may happen.
+//If Redis cannot be reached, then the emission interval is returned so that
+//callers retry later, and the error is passed to the function set by OnError.
+func (g *GCRA) DelayN(t time.Time, n int) time.Duration {
+	ctx, cancel := g.remote.Context()
+	defer cancel()
+
+	_, delay, err := g.eval(ctx, t, n, false)
+	if err != nil {
+		g.remote.Handle(err)
+		return g.Duration()
+	}
+	return delay
+}
+
+//TakeN is like AllowN, but uses ctx for the call to Redis, returns the error
+//of the call if there is one, and returns how long after t it will be before
+//the releases may happen if they may not happen at t.
+func (g *GCRA) TakeN(ctx context.Context, t time.Time, n int) (bool, time.Duration, error) {
+	return g.eval(ctx, t, n, true)
+}
+
+//Wait blocks until a release may happen, counting it, or until ctx is done, in
+//which case ctx.Err() is returned.
+//An error calling Redis is returned immediately.
+func (g *GCRA) Wait(ctx context.Context) error {
+	return ratelimit.WaitTake(ctx, g.TakeN)
+}
+
+//Duration returns the emission interval of g.
+func (g *GCRA) Duration() time.Duration {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.interval
+}
+
+//SetDuration changes the emission interval of g to d.
+//It only affects the decisions made by g, not those of other GCRAs sharing its
+//key.
+func (g *GCRA) SetDuration(d time.Duration) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.interval = d
+}
+
+//Reset forgets all releases counted at the key of g, by any GCRA.
+func (g *GCRA) Reset() {
+	ctx, cancel := g.remote.Context()
+	defer cancel()
+
+	if _, err := g.client.Eval(ctx, deleteScript, []string{g.key}); err != nil {
+		g.remote.Handle(err)
+	}
+}
+
+//SetTimeout changes how long the calls to Redis made by AllowN, DelayN, and Reset
+//may take to d, after which they fail just like any other error calling Redis.
+//A d of zero or less means they never time out.
+func (g *GCRA) SetTimeout(d time.Duration) {
+	g.remote.SetTimeout(d)
+}
+
+//OnError sets f to be called with the errors calling Redis that cannot be
+//returned to the caller, such as those of AllowN and DelayN.
+//f is called synchronously and must not call methods of g.
+func (g *GCRA) OnError(f func(error)) {
+	g.remote.OnError(f)
+}
+
+func (g *GCRA) eval(ctx context.Context, t time.Time, n int, count bool) (bool, time.Duration, error) {
+	g.lock.Lock()
+	interval, tolerance := g.interval, g.tolerance
+	g.lock.Unlock()
+
+	flag := "0"
+	if count {
+		flag = "1"
+	}
+	reply, err := g.client.Eval(ctx, gcraScript, []string{g.key},
+		microseconds(t.Sub(time.Unix(0, 0))),
+		microseconds(interval),
+		microseconds(tolerance),
+		n,
+		flag,
+	)
+	if err != nil {
+		return false, 0, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return false, 0, ErrUnexpectedReply
+	}
+	allowed, ok1 := values[0].(int64)
+	delay, ok2 := values[1].(int64)
+	if !ok1 || !ok2 {
+		return false, 0, ErrUnexpectedReply
+	}
+	return allowed == 1, time.Duration(delay) * time.Microsecond, nil
+}
+
+func microseconds(d time.Duration) int64 {
+	return int64(d / time.Microsecond)
+}
@@ -0,0 +1,209 @@
+package redislimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+//fakeRedis evaluates the scripts of this package against an in-memory map,
+//mirroring the Lua they contain.
+type fakeRedis struct {
+	lock    sync.Mutex
+	values  map[string]int64
+	strings map[string]string
+	err     error
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{values: map[string]int64{}, strings: map[string]string{}}
+}
+
+func (f *fakeRedis) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	switch script {
+	case getScript:
+		if value, ok := f.strings[keys[0]]; ok {
+			return []interface{}{int64(1), value}, nil
+		}
+		return []interface{}{int64(0)}, nil
+
+	case compareAndSwapScript:
+		value, ok := f.strings[keys[0]]
+		if (args[0] == "0" && !ok) || (args[0] == "1" && ok && value == args[1]) {
+			f.strings[keys[0]] = args[2].(string)
+			return int64(1), nil
+		}
+		return int64(0), nil
+
+	case deleteScript:
+		delete(f.values, keys[0])
+		return int64(1), nil
+
+	case gcraScript:
+		now, interval, tolerance := args[0].(int64), args[1].(int64), args[2].(int64)
+		n := int64(args[3].(int))
+		tat, ok := f.values[keys[0]]
+		if !ok || tat < now {
+			tat = now
+		}
+		if delay := tat - tolerance - now; delay > 0 {
+			return []interface{}{int64(0), delay}, nil
+		}
+		if args[4] == "1" {
+			f.values[keys[0]] = tat + n*interval
+		}
+		return []interface{}{int64(1), int64(0)}, nil
+	}
+	return nil, errors.New("unknown script")
+}
+
+func TestGCRA_AllowN_sharesTheBudgetBetweenInstances(t *testing.T) {
+	f := newFakeRedis()
+	a := NewGCRA(f, "key", time.Second, time.Second)
+	b := NewGCRA(f, "key", time.Second, time.Second)
+	now := time.Now()
+
+	if !a.AllowN(now, 1) || !b.AllowN(now, 1) {
+		t.Fatal("burst should be allowed")
+	}
+	if a.AllowN(now, 1) || b.AllowN(now, 1) {
+		t.Fatal("burst should be shared")
+	}
+	if delay := b.DelayN(now, 1); delay != time.Second {
+		t.Fatal(delay)
+	}
+	if !a.AllowN(now.Add(time.Second), 1) {
+		t.Fail()
+	}
+}
+
+func TestGCRA_AllowN_usesSeparateBudgetsForSeparateKeys(t *testing.T) {
+	f := newFakeRedis()
+	a := NewGCRA(f, "a", time.Second, 0)
+	b := NewGCRA(f, "b", time.Second, 0)
+	now := time.Now()
+
+	if !a.AllowN(now, 1) || !b.AllowN(now, 1) {
+		t.Fail()
+	}
+}
+
+func TestGCRA_AllowN_refusesAndReportsErrors(t *testing.T) {
+	f := newFakeRedis()
+	f.err = errors.New("down")
+	g := NewGCRA(f, "key", time.Second, 0)
+	var reported error
+	g.OnError(func(err error) {
+		reported = err
+	})
+
+	if g.AllowN(time.Now(), 1) || reported != f.err {
+		t.Fail()
+	}
+	if delay := g.DelayN(time.Now(), 1); delay != time.Second {
+		t.Fatal(delay)
+	}
+}
+
+func TestGCRA_AllowN_givesUpAfterTheTimeout(t *testing.T) {
+	g := NewGCRA(hangingClient{}, "key", time.Second, 0)
+	g.SetTimeout(time.Duration(10) * time.Millisecond)
+	var reported error
+	g.OnError(func(err error) {
+		reported = err
+	})
+
+	start := time.Now()
+	if g.AllowN(time.Now(), 1) || reported != context.DeadlineExceeded {
+		t.Fatal(reported)
+	}
+	reported = nil
+	g.Reset()
+	if reported != context.DeadlineExceeded {
+		t.Fatal(reported)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatal(elapsed)
+	}
+}
+
+//hangingClient is a Client whose calls never return until their context is
+//done.
+type hangingClient struct{}
+
+func (hangingClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestGCRA_TakeN_returnsUnexpectedReplies(t *testing.T) {
+	g := NewGCRA(clientFunc(func() (interface{}, error) {
+		return "OK", nil
+	}), "key", time.Second, 0)
+
+	if _, _, err := g.TakeN(context.Background(), time.Now(), 1); err != ErrUnexpectedReply {
+		t.Fatal(err)
+	}
+}
+
+type clientFunc func() (interface{}, error)
+
+func (f clientFunc) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return f()
+}
+
+func TestGCRA_Reset_forgetsReleases(t *testing.T) {
+	g := NewGCRA(newFakeRedis(), "key", time.Second, 0)
+	now := time.Now()
+	g.AllowN(now, 1)
+
+	g.Reset()
+
+	if !g.AllowN(now, 1) {
+		t.Fail()
+	}
+}
+
+func TestGCRA_Wait_waitsForTheNextRelease(t *testing.T) {
+	d := time.Duration(20) * time.Millisecond
+	g := NewGCRA(newFakeRedis(), "key", d, 0)
+	g.Wait(context.Background())
+
+	start := time.Now()
+	if err := g.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < d/2 {
+		t.Fail()
+	}
+}
+
+func TestGCRA_pacesALimiter(t *testing.T) {
+	d := time.Duration(10) * time.Millisecond
+	f := newFakeRedis()
+	a := ratelimit.New(d, ratelimit.WithPacer(NewGCRA(f, "key", d, 0)))
+	b := ratelimit.New(d, ratelimit.WithPacer(NewGCRA(f, "key", d, 0)))
+	defer a.Close()
+	defer b.Close()
+
+	start := time.Now()
+	a.Push(1)
+	b.Push(2)
+	a.Pop()
+	b.Pop()
+
+	if time.Since(start) < d/2 {
+		t.Fail()
+	}
+}
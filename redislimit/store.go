@@ -0,0 +1,96 @@
+package redislimit
+
+import (
+	"context"
+	"time"
+)
+
+//getScript returns {1, value} if KEYS[1] holds a value and {0} otherwise, so
+//that an absent key is not reported as an error by clients.
+const getScript = `
+local value = redis.call("GET", KEYS[1])
+if value then
+	return {1, value}
+end
+return {0}
+`
+
+//compareAndSwapScript stores ARGV[3] at KEYS[1] if KEYS[1] holds ARGV[2], or
+//if it is absent and ARGV[1] is "0", expiring it after ARGV[4] milliseconds if
+//that is positive.
+//It returns 1 if it stored the value and 0 otherwise.
+const compareAndSwapScript = `
+local value = redis.call("GET", KEYS[1])
+if (ARGV[1] == "0" and not value) or (ARGV[1] == "1" and value == ARGV[2]) then
+	if tonumber(ARGV[4]) > 0 then
+		redis.call("SET", KEYS[1], ARGV[3], "PX", ARGV[4])
+	else
+		redis.call("SET", KEYS[1], ARGV[3])
+	end
+	return 1
+end
+return 0
+`
+
+//Store is a ratelimit.Store that keeps its values in Redis.
+//It allows any Pacer built on a ratelimit.Store, such as ratelimit.StoreGCRA,
+//to share its state between processes, at the cost of two calls to Redis per
+//decision. GCRA makes the same decisions with one.
+type Store struct {
+	client Client
+}
+
+//NewStore creates a Store that evaluates its scripts using client.
+func NewStore(client Client) *Store {
+	return &Store{client: client}
+}
+
+//Get implements ratelimit.Store.
+func (s *Store) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := s.client.Eval(ctx, getScript, []string{key})
+	if err != nil {
+		return nil, false, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) == 0 {
+		return nil, false, ErrUnexpectedReply
+	}
+	if len(values) == 1 {
+		return nil, false, nil
+	}
+	value, err := bytesReply(values[1])
+	return value, err == nil, err
+}
+
+//CompareAndSwap implements ratelimit.Store.
+//A ttl is rounded up to whole milliseconds.
+func (s *Store) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	exists := "0"
+	if old != nil {
+		exists = "1"
+	}
+	reply, err := s.client.Eval(ctx, compareAndSwapScript, []string{key},
+		exists,
+		string(old),
+		string(value),
+		int64((ttl+time.Millisecond-1)/time.Millisecond),
+	)
+	if err != nil {
+		return false, err
+	}
+	swapped, ok := reply.(int64)
+	if !ok {
+		return false, ErrUnexpectedReply
+	}
+	return swapped == 1, nil
+}
+
+func bytesReply(reply interface{}) ([]byte, error) {
+	switch v := reply.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	}
+	return nil, ErrUnexpectedReply
+}
@@ -0,0 +1,41 @@
+package redislimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gogolfing/ratelimit"
+)
+
+var _ ratelimit.Store = &Store{}
+
+func TestStore_CompareAndSwap_onlySwapsTheCurrentValue(t *testing.T) {
+	s := NewStore(newFakeRedis())
+	ctx := context.Background()
+
+	if _, ok, err := s.Get(ctx, "key"); ok || err != nil {
+		t.Fatal(ok, err)
+	}
+	if ok, _ := s.CompareAndSwap(ctx, "key", nil, []byte("a"), time.Second); !ok {
+		t.Fatal("absent value should be swapped")
+	}
+	if ok, _ := s.CompareAndSwap(ctx, "key", []byte("b"), []byte("c"), time.Second); ok {
+		t.Fatal("different value should not be swapped")
+	}
+	if ok, _ := s.CompareAndSwap(ctx, "key", []byte("a"), []byte("c"), time.Second); !ok {
+		t.Fatal("current value should be swapped")
+	}
+	if value, ok, _ := s.Get(ctx, "key"); !ok || string(value) != "c" {
+		t.Fatal(string(value), ok)
+	}
+}
+
+func TestStore_runsAStoreGCRA(t *testing.T) {
+	g := ratelimit.NewStoreGCRA(NewStore(newFakeRedis()), "key", time.Second, 0)
+	now := time.Now()
+
+	if !g.AllowN(now, 1) || g.AllowN(now, 1) {
+		t.Fail()
+	}
+}
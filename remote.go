@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//DefaultRemoteTimeout is the timeout of a Remote unless it is changed with
+//SetTimeout.
+const DefaultRemoteTimeout = time.Duration(100) * time.Millisecond
+
+//Remote holds what is shared by Pacers whose state is kept outside of the
+//process, such as StoreGCRA and the GCRA of package redislimit.
+//
+//A Limiter calls AllowN and DelayN of its Pacer with its lock held, and they have
+//no context to give up with. So the calls such a Pacer makes for them use the
+//Context of its Remote, which is done after a timeout, and their errors are passed
+//to Handle since they cannot be returned.
+type Remote struct {
+	lock    *sync.Mutex
+	timeout time.Duration
+	onError func(error)
+}
+
+//NewRemote creates a Remote with a timeout of DefaultRemoteTimeout.
+func NewRemote() *Remote {
+	return &Remote{
+		lock:    &sync.Mutex{},
+		timeout: DefaultRemoteTimeout,
+	}
+}
+
+//SetTimeout changes how long the calls made with a Context of r may take to d.
+//A d of zero or less means they never time out.
+func (r *Remote) SetTimeout(d time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.timeout = d
+}
+
+//OnError sets f to be called with the errors passed to Handle.
+//f is called synchronously, possibly while a Limiter is locked, and must not call
+//methods of the Pacer or of a Limiter using it.
+func (r *Remote) OnError(f func(error)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.onError = f
+}
+
+//Context returns the context for a call that has no context of its own, which
+//is done once the timeout of r has passed.
+//cancel must be called once the call returns.
+func (r *Remote) Context() (ctx context.Context, cancel context.CancelFunc) {
+	r.lock.Lock()
+	timeout := r.timeout
+	r.lock.Unlock()
+
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+//Handle passes err to the function set with OnError, if there is one.
+func (r *Remote) Handle(err error) {
+	r.lock.Lock()
+	f := r.onError
+	r.lock.Unlock()
+	if f != nil {
+		f(err)
+	}
+}
+
+//WaitTake blocks until take permits a release at the current time, or until ctx
+//is done, in which case ctx.Err() is returned.
+//take is called like the TakeN method of StoreGCRA: it counts the releases it
+//permits, and otherwise returns how long it will be before they may happen.
+//An error returned by take is returned immediately.
+func WaitTake(ctx context.Context, take func(ctx context.Context, t time.Time, n int) (bool, time.Duration, error)) error {
+	var timer *time.Timer
+	for {
+		ok, delay, err := take(ctx, time.Now(), 1)
+		if err != nil || ok {
+			return err
+		}
+
+		if timer == nil {
+			timer = time.NewTimer(delay)
+			defer timer.Stop()
+		} else {
+			timer.Reset(delay)
+		}
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
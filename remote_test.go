@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRemote_Context_isDoneAfterTheTimeout(t *testing.T) {
+	r := NewRemote()
+	r.SetTimeout(time.Duration(10) * time.Millisecond)
+	ctx, cancel := r.Context()
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Context is not done after the timeout")
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Fatal(ctx.Err())
+	}
+}
+
+func TestRemote_Context_hasNoDeadlineWithoutATimeout(t *testing.T) {
+	r := NewRemote()
+	r.SetTimeout(0)
+	ctx, cancel := r.Context()
+	defer cancel()
+
+	if _, ok := ctx.Deadline(); ok {
+		t.Fail()
+	}
+}
+
+func TestWaitTake_returnsOnceTakeAllows(t *testing.T) {
+	calls := 0
+	err := WaitTake(context.Background(), func(ctx context.Context, now time.Time, n int) (bool, time.Duration, error) {
+		calls++
+		return calls == 3, time.Millisecond, nil
+	})
+
+	if err != nil || calls != 3 {
+		t.Fatal(err, calls)
+	}
+}
@@ -0,0 +1,29 @@
+package ratelimit
+
+//OnSaturated sets fn to be called with l whenever a push finds l at capacity,
+//which is when Push would block and TryPush is refused, so that producers can
+//switch to degraded behavior at that moment.
+//
+//fn is called by the pushing goroutine without the lock of l held, so it may use
+//l. A Push that blocks calls fn once, before it starts waiting for space.
+func (l *Limiter) OnSaturated(fn func(l *Limiter)) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.onSaturated = fn
+}
+
+//IsSaturated reports whether l is at capacity, so that a Push would block.
+//An Unbounded Limiter is never saturated.
+func (l *Limiter) IsSaturated() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return !l.hasSpaceLocked()
+}
+
+func (l *Limiter) notifySaturated(fn func(*Limiter)) {
+	if fn != nil {
+		fn(l)
+	}
+}
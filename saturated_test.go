@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_OnSaturated_isCalledWhenAPushFindsLFull(t *testing.T) {
+	l := New(time.Duration(1) * time.Hour)
+	calls := 0
+	l.OnSaturated(func(saturated *Limiter) {
+		if saturated != l || !saturated.IsSaturated() {
+			t.Error("should be called with the saturated limiter")
+		}
+		calls++
+	})
+	l.Push(1)
+
+	l.TryPush(2)
+	l.TryPushAll(3, 4)
+	l.PushTimeout(5, time.Duration(20)*time.Millisecond)
+
+	if calls != 3 {
+		t.Fatal(calls)
+	}
+}
+
+func TestLimiter_OnSaturated_mayUseL(t *testing.T) {
+	l := New(time.Duration(1))
+	l.OnSaturated(func(l *Limiter) {
+		l.Pop()
+	})
+	l.Push(1)
+
+	if err := l.Push(2); err != nil || l.Len() != 1 {
+		t.Fatal(err, l.Len())
+	}
+}
+
+func TestLimiter_IsSaturated(t *testing.T) {
+	l := New(time.Duration(1))
+	if l.IsSaturated() {
+		t.Fatal("empty limiter should not be saturated")
+	}
+	l.Push(1)
+	if !l.IsSaturated() {
+		t.Fatal("full limiter should be saturated")
+	}
+	if NewUnbounded(time.Duration(1)).IsSaturated() {
+		t.Fatal("unbounded limiter should never be saturated")
+	}
+}
@@ -0,0 +1,164 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+//Window is a daily period of time during which a Schedule uses a different
+//throughput duration.
+//Start and End are offsets from midnight in the location of the times being
+//paced. A Window whose End is before its Start spans midnight.
+type Window struct {
+	Start time.Duration
+	End   time.Duration
+
+	//Duration is the throughput duration used during the Window.
+	Duration time.Duration
+}
+
+//contains reports whether offset, an offset from midnight, is within w.
+func (w Window) contains(offset time.Duration) bool {
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+//Schedule describes throughput durations that change with the time of day.
+//The Duration of the first Window containing a time is used at that time, and
+//Default is used when no Window does.
+type Schedule struct {
+	Default time.Duration
+	Windows []Window
+}
+
+//durationAt returns the throughput duration s uses at time t.
+func (s Schedule) durationAt(t time.Time) time.Duration {
+	offset := sinceMidnight(t)
+	for _, w := range s.Windows {
+		if w.contains(offset) {
+			return w.Duration
+		}
+	}
+	return s.Default
+}
+
+//untilChange returns how long after t it will be before the throughput
+//duration of s may next change.
+//It is not positive if s never changes.
+func (s Schedule) untilChange(t time.Time) time.Duration {
+	offset := sinceMidnight(t)
+	next := time.Duration(0)
+	for _, w := range s.Windows {
+		for _, boundary := range []time.Duration{w.Start, w.End} {
+			until := boundary - offset
+			if until <= 0 {
+				until += 24 * time.Hour
+			}
+			if next <= 0 || until < next {
+				next = until
+			}
+		}
+	}
+	return next
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	year, month, day := t.Date()
+	return t.Sub(time.Date(year, month, day, 0, 0, 0, 0, t.Location()))
+}
+
+//ScheduledLimiter is a Limiter whose throughput duration follows a Schedule,
+//for example permitting 100 values per second during business hours and 1000
+//values per second overnight.
+//
+//The Schedule of a ScheduledLimiter may be replaced at any time with
+//SetSchedule without losing any queued values.
+type ScheduledLimiter struct {
+	*Limiter
+
+	schedule *schedulePacer
+}
+
+//NewScheduled creates a ScheduledLimiter that follows s and is otherwise
+//configured by opts.
+//The pacer of the returned ScheduledLimiter always follows s, so opts should not
+//include WithPacer or WithBurst.
+func NewScheduled(s Schedule, opts ...Option) *ScheduledLimiter {
+	p := &schedulePacer{
+		lock:     &sync.Mutex{},
+		bucket:   NewTokenBucket(s.Default, 1),
+		schedule: s,
+		d:        s.Default,
+	}
+	return &ScheduledLimiter{
+		Limiter:  New(s.Default, append(opts[:len(opts):len(opts)], WithPacer(p))...),
+		schedule: p,
+	}
+}
+
+//Schedule returns the Schedule currently followed by s.
+func (s *ScheduledLimiter) Schedule() Schedule {
+	s.schedule.lock.Lock()
+	defer s.schedule.lock.Unlock()
+
+	return s.schedule.schedule
+}
+
+//SetSchedule replaces the Schedule followed by s.
+//Values already queued in s are kept and released according to sch.
+func (s *ScheduledLimiter) SetSchedule(sch Schedule) {
+	s.schedule.lock.Lock()
+	s.schedule.schedule = sch
+	s.schedule.lock.Unlock()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.broadcastLocked()
+}
+
+//schedulePacer is the Pacer of a ScheduledLimiter.
+//It permits releases one at a time at the throughput duration its Schedule
+//uses at the time of each release.
+type schedulePacer struct {
+	lock   *sync.Mutex
+	bucket *TokenBucket
+
+	schedule Schedule
+	d        time.Duration
+}
+
+func (p *schedulePacer) AllowN(t time.Time, n int) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.updateLocked(t)
+	return p.bucket.AllowN(t, n)
+}
+
+func (p *schedulePacer) DelayN(t time.Time, n int) time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.updateLocked(t)
+	delay := p.bucket.DelayN(t, n)
+	if until := p.schedule.untilChange(t); delay > 0 && until > 0 && until < delay {
+		return until
+	}
+	return delay
+}
+
+func (p *schedulePacer) Reset() {
+	p.bucket.Reset()
+}
+
+//updateLocked sets the duration of the bucket of p to the one its Schedule uses
+//at time t.
+func (p *schedulePacer) updateLocked(t time.Time) {
+	if d := p.schedule.durationAt(t); d != p.d {
+		p.d = d
+		p.bucket.setDurationAt(t, d)
+	}
+}
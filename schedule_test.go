@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_durationAt_usesTheFirstContainingWindow(t *testing.T) {
+	s := Schedule{
+		Default: time.Second,
+		Windows: []Window{
+			{Start: 9 * time.Hour, End: 17 * time.Hour, Duration: time.Millisecond},
+			{Start: 22 * time.Hour, End: 6 * time.Hour, Duration: time.Microsecond},
+			{Start: 0, End: 24 * time.Hour, Duration: time.Minute},
+		},
+	}
+	at := func(hour int) time.Time {
+		return time.Date(2020, time.January, 1, hour, 0, 0, 0, time.UTC)
+	}
+
+	if s.durationAt(at(12)) != time.Millisecond || s.durationAt(at(17)) != time.Minute {
+		t.Fail()
+	}
+	if s.durationAt(at(23)) != time.Microsecond || s.durationAt(at(3)) != time.Microsecond {
+		t.Fail()
+	}
+	if (Schedule{Default: time.Second}).durationAt(at(12)) != time.Second {
+		t.Fail()
+	}
+}
+
+func TestSchedule_untilChange_returnsTimeUntilTheNextBoundary(t *testing.T) {
+	s := Schedule{
+		Windows: []Window{{Start: 9 * time.Hour, End: 17 * time.Hour}},
+	}
+
+	if s.untilChange(time.Date(2020, time.January, 1, 8, 0, 0, 0, time.UTC)) != time.Hour {
+		t.Fail()
+	}
+	if s.untilChange(time.Date(2020, time.January, 1, 18, 0, 0, 0, time.UTC)) != 15*time.Hour {
+		t.Fail()
+	}
+	if (Schedule{}).untilChange(time.Now()) > 0 {
+		t.Fail()
+	}
+}
+
+func TestNewScheduled_doesNotWriteToTheOptionsOfTheCaller(t *testing.T) {
+	spare := false
+	opts := []Option{WithCapacity(Unbounded), func(l *Limiter) { spare = true }}
+
+	NewScheduled(Schedule{Default: time.Hour}, opts[:1]...)
+
+	opts[1](&Limiter{})
+	if !spare {
+		t.Fail()
+	}
+}
+
+func TestScheduledLimiter_SetSchedule_keepsQueuedValues(t *testing.T) {
+	sl := NewScheduled(Schedule{Default: time.Hour}, WithCapacity(Unbounded))
+	for i := 0; i < 3; i++ {
+		sl.Push(i)
+	}
+	sl.Pop()
+
+	sl.SetSchedule(Schedule{Default: time.Millisecond})
+
+	if sl.Len() != 2 || sl.Schedule().Default != time.Millisecond {
+		t.Fail()
+	}
+	for i := 1; i < 3; i++ {
+		if value, err := sl.PopTimeout(time.Second); value != i || err != nil {
+			t.Fail()
+		}
+	}
+}
+
+func TestScheduledLimiter_usesTheClockOfTheLimiterAcrossWindows(t *testing.T) {
+	c := &manualClock{now: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	sl := NewScheduled(Schedule{
+		Default: time.Hour,
+		Windows: []Window{{Start: 0, End: 12 * time.Hour, Duration: time.Second}},
+	}, WithClock(c))
+
+	if !sl.Allow() || sl.Delay() != time.Second {
+		t.Fatal(sl.Delay())
+	}
+
+	c.advance(12 * time.Hour)
+
+	if !sl.Allow() {
+		t.Fatal(sl.Delay())
+	}
+	if delay := sl.Delay(); delay != time.Hour {
+		t.Fatal(delay)
+	}
+}
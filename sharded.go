@@ -0,0 +1,197 @@
+package ratelimit
+
+import (
+	"sort"
+	"time"
+)
+
+//DefaultShards is the number of shards used by NewShardedKeyed for a shards
+//argument less than one.
+const DefaultShards = 32
+
+//ShardedKeyedLimiter is a KeyedLimiter split into shards, each guarded by its
+//own lock and holding its own map of keys, so that operations on different keys
+//from many goroutines rarely contend with each other.
+//
+//Each key belongs to the shard chosen by a hash of the key, and works exactly as
+//it would in a KeyedLimiter. Operations on all keys, such as SetDuration, Keys,
+//and Close, apply to every shard in turn.
+//
+//Because the keys of a shard are managed independently of the other shards, a
+//maximum number of keys set with SetMaxKeys is divided evenly between the
+//shards, and hierarchical keys, whose ancestors could live in other shards, are
+//not supported. Use a KeyedLimiter for those.
+type ShardedKeyedLimiter struct {
+	shards []*KeyedLimiter
+}
+
+//NewShardedKeyed creates a ShardedKeyedLimiter with shards shards whose keys each
+//have throughput duration d and are configured by opts.
+//A shards less than one uses DefaultShards.
+//NewShardedKeyed panics for the same options that NewKeyed does.
+func NewShardedKeyed(shards int, d time.Duration, opts ...Option) *ShardedKeyedLimiter {
+	if shards < 1 {
+		shards = DefaultShards
+	}
+	s := &ShardedKeyedLimiter{
+		shards: make([]*KeyedLimiter, shards),
+	}
+	for i := range s.shards {
+		s.shards[i] = NewKeyed(d, opts...)
+	}
+	return s
+}
+
+//Shards returns the number of shards of s.
+func (s *ShardedKeyedLimiter) Shards() int {
+	return len(s.shards)
+}
+
+//shard returns the shard that key belongs to.
+func (s *ShardedKeyedLimiter) shard(key string) *KeyedLimiter {
+	//FNV-1a, computed inline so that routing a key does not allocate.
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return s.shards[hash%uint32(len(s.shards))]
+}
+
+//SetKeyRate works just like KeyedLimiter.SetKeyRate.
+func (s *ShardedKeyedLimiter) SetKeyRate(key string, d time.Duration) {
+	s.shard(key).SetKeyRate(key, d)
+}
+
+//SetKeyCapacity works just like KeyedLimiter.SetKeyCapacity.
+func (s *ShardedKeyedLimiter) SetKeyCapacity(key string, capacity int) {
+	s.shard(key).SetKeyCapacity(key, capacity)
+}
+
+//SetDuration works just like KeyedLimiter.SetDuration.
+func (s *ShardedKeyedLimiter) SetDuration(d time.Duration) {
+	for _, shard := range s.shards {
+		shard.SetDuration(d)
+	}
+}
+
+//SetCapacity works just like KeyedLimiter.SetCapacity.
+func (s *ShardedKeyedLimiter) SetCapacity(capacity int) {
+	for _, shard := range s.shards {
+		shard.SetCapacity(capacity)
+	}
+}
+
+//SetIdleTTL works just like KeyedLimiter.SetIdleTTL.
+func (s *ShardedKeyedLimiter) SetIdleTTL(ttl time.Duration) {
+	for _, shard := range s.shards {
+		shard.SetIdleTTL(ttl)
+	}
+}
+
+//OnEvict works just like KeyedLimiter.OnEvict.
+//fn may be called concurrently for keys of different shards.
+func (s *ShardedKeyedLimiter) OnEvict(fn func(key string)) {
+	for _, shard := range s.shards {
+		shard.OnEvict(fn)
+	}
+}
+
+//SetMaxKeys limits the number of keys each shard of s keeps state for to max
+//divided by the number of shards, rounded up, so that s keeps state for about
+//max keys in total. See KeyedLimiter.SetMaxKeys.
+func (s *ShardedKeyedLimiter) SetMaxKeys(max int) {
+	perShard := 0
+	if max > 0 {
+		perShard = (max + len(s.shards) - 1) / len(s.shards)
+	}
+	for _, shard := range s.shards {
+		shard.SetMaxKeys(perShard)
+	}
+}
+
+//Evictions returns the number of keys whose state has been evicted from all
+//shards of s.
+func (s *ShardedKeyedLimiter) Evictions() uint64 {
+	evictions := uint64(0)
+	for _, shard := range s.shards {
+		evictions += shard.Evictions()
+	}
+	return evictions
+}
+
+//EvictIdle works just like KeyedLimiter.EvictIdle for every shard of s.
+func (s *ShardedKeyedLimiter) EvictIdle() int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.EvictIdle()
+	}
+	return n
+}
+
+//PushKey works just like KeyedLimiter.PushKey.
+func (s *ShardedKeyedLimiter) PushKey(key string, value interface{}) error {
+	return s.shard(key).PushKey(key, value)
+}
+
+//TryPushKey works just like KeyedLimiter.TryPushKey.
+func (s *ShardedKeyedLimiter) TryPushKey(key string, value interface{}) (accepted bool, err error) {
+	return s.shard(key).TryPushKey(key, value)
+}
+
+//PopKey works just like KeyedLimiter.PopKey.
+func (s *ShardedKeyedLimiter) PopKey(key string) interface{} {
+	return s.shard(key).PopKey(key)
+}
+
+//PopKeyOk works just like KeyedLimiter.PopKeyOk.
+func (s *ShardedKeyedLimiter) PopKeyOk(key string) (value interface{}, ok bool) {
+	return s.shard(key).PopKeyOk(key)
+}
+
+//TryPopKey works just like KeyedLimiter.TryPopKey.
+func (s *ShardedKeyedLimiter) TryPopKey(key string) (value interface{}, ok bool) {
+	return s.shard(key).TryPopKey(key)
+}
+
+//Len returns the number of values currently queued for key.
+func (s *ShardedKeyedLimiter) Len(key string) int {
+	return s.shard(key).Len(key)
+}
+
+//Stats works just like KeyedLimiter.Stats.
+func (s *ShardedKeyedLimiter) Stats(key string) (stats KeyStats, ok bool) {
+	return s.shard(key).Stats(key)
+}
+
+//Duration returns the throughput duration of key.
+func (s *ShardedKeyedLimiter) Duration(key string) time.Duration {
+	return s.shard(key).Duration(key)
+}
+
+//Delay works just like KeyedLimiter.Delay.
+func (s *ShardedKeyedLimiter) Delay(key string) time.Duration {
+	return s.shard(key).Delay(key)
+}
+
+//Keys returns the keys all shards of s currently keep state for in sorted order.
+func (s *ShardedKeyedLimiter) Keys() []string {
+	keys := []string{}
+	for _, shard := range s.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//Close closes every shard of s, just like KeyedLimiter.Close.
+//
+//If s is already closed, then ErrClosed is returned, otherwise err is nil.
+func (s *ShardedKeyedLimiter) Close() (err error) {
+	for _, shard := range s.shards {
+		if shardErr := shard.Close(); shardErr != nil {
+			err = shardErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNewShardedKeyed_usesDefaultShards(t *testing.T) {
+	if s := NewShardedKeyed(0, time.Second); s.Shards() != DefaultShards {
+		t.Fatal(s.Shards())
+	}
+}
+
+func TestShardedKeyedLimiter_keysAreIndependent(t *testing.T) {
+	s := NewShardedKeyed(4, time.Duration(1)*time.Hour, WithCapacity(2))
+	for i := 0; i < 20; i++ {
+		key := strconv.Itoa(i)
+		if accepted, err := s.TryPushKey(key, i); !accepted || err != nil {
+			t.Fatal(key, accepted, err)
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		key := strconv.Itoa(i)
+		if v, ok := s.TryPopKey(key); !ok || v != i {
+			t.Fatal(key, v, ok)
+		}
+		if stats, ok := s.Stats(key); !ok || stats.Pushed != 1 || stats.Popped != 1 {
+			t.Fatal(key, stats, ok)
+		}
+	}
+	if keys := s.Keys(); len(keys) != 20 || keys[0] != "0" || keys[1] != "1" {
+		t.Fatal(keys)
+	}
+}
+
+func TestShardedKeyedLimiter_SetKeyRate_appliesToTheKeysShard(t *testing.T) {
+	s := NewShardedKeyed(4, time.Second)
+	s.SetKeyRate("premium", time.Millisecond)
+	s.SetDuration(time.Minute)
+
+	if d := s.Duration("premium"); d != time.Millisecond {
+		t.Fatal(d)
+	}
+	if d := s.Duration("other"); d != time.Minute {
+		t.Fatal(d)
+	}
+}
+
+func TestShardedKeyedLimiter_SetMaxKeys_dividesTheMaximumBetweenShards(t *testing.T) {
+	s := NewShardedKeyed(4, time.Second)
+	s.SetMaxKeys(8)
+	for i := 0; i < 100; i++ {
+		s.TryPushKey(strconv.Itoa(i), i)
+	}
+
+	if keys := s.Keys(); len(keys) > 8 {
+		t.Fatal(len(keys))
+	}
+	if evictions := s.Evictions(); evictions < 92 {
+		t.Fatal(evictions)
+	}
+}
+
+func TestShardedKeyedLimiter_Close_closesEveryShard(t *testing.T) {
+	s := NewShardedKeyed(4, time.Second)
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := s.PushKey(strconv.Itoa(i), i); err != ErrClosed {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != ErrClosed {
+		t.Fatal(err)
+	}
+}
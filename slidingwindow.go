@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+//SlidingWindow is a Pacer that permits at most n releases within any rolling
+//window of time.
+//It keeps a log of the times of the releases within the most recent window.
+//
+//Unlike a TokenBucket, which spreads releases evenly, SlidingWindow permits all
+//n releases back-to-back and then none until the earliest of them has left the
+//window. This matches quotas expressed as "n per window".
+type SlidingWindow struct {
+	lock *sync.Mutex
+
+	n      int
+	window time.Duration
+
+	//log holds the times of releases in increasing order.
+	log []time.Time
+}
+
+//NewSlidingWindow creates a SlidingWindow that permits at most n releases within
+//any window of duration window.
+func NewSlidingWindow(n int, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{
+		lock:   &sync.Mutex{},
+		n:      n,
+		window: window,
+		log:    make([]time.Time, 0, n),
+	}
+}
+
+//AllowN reports whether k releases may happen at time t without exceeding the
+//limit of w, and records them if they may.
+//
+//If k is greater than the limit of w, then the releases are permitted once the
+//window is empty.
+func (w *SlidingWindow) AllowN(t time.Time, k int) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.delayLocked(t, k) > 0 {
+		return false
+	}
+
+	for i := 0; i < k; i++ {
+		w.log = append(w.log, t)
+	}
+	return true
+}
+
+//DelayN returns how long after t it will be before k releases may happen
+//without exceeding the limit of w.
+func (w *SlidingWindow) DelayN(t time.Time, k int) time.Duration {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if delay := w.delayLocked(t, k); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+//Count returns the number of releases within the window ending at t.
+func (w *SlidingWindow) Count(t time.Time) int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.pruneLocked(t)
+	return len(w.log)
+}
+
+//Reset forgets all releases recorded by w.
+func (w *SlidingWindow) Reset() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.log = w.log[:0]
+}
+
+func (w *SlidingWindow) delayLocked(t time.Time, k int) time.Duration {
+	w.pruneLocked(t)
+
+	if k > w.n {
+		k = w.n
+	}
+	excess := len(w.log) + k - w.n
+	if excess <= 0 {
+		return 0
+	}
+	return w.log[excess-1].Add(w.window).Sub(t)
+}
+
+//pruneLocked removes the releases from the log that are no longer within the
+//window ending at t.
+func (w *SlidingWindow) pruneLocked(t time.Time) {
+	start := t.Add(-w.window)
+
+	i := 0
+	for i < len(w.log) && !w.log[i].After(start) {
+		i++
+	}
+	if i > 0 {
+		w.log = append(w.log[:0], w.log[i:]...)
+	}
+}
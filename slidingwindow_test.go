@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlidingWindow_AllowN_permitsNWithinTheWindow(t *testing.T) {
+	window := time.Duration(1) * time.Minute
+	w := NewSlidingWindow(3, window)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if !w.AllowN(now.Add(time.Duration(i)*time.Second), 1) {
+			t.Fail()
+		}
+	}
+
+	if w.AllowN(now.Add(window-1), 1) {
+		t.Fail()
+	}
+
+	if !w.AllowN(now.Add(window), 1) {
+		t.Fail()
+	}
+
+	if w.AllowN(now.Add(window), 1) {
+		t.Fail()
+	}
+
+	if !w.AllowN(now.Add(window+time.Second), 1) {
+		t.Fail()
+	}
+}
+
+func TestSlidingWindow_DelayN_returnsTimeUntilReleasesLeaveTheWindow(t *testing.T) {
+	window := time.Duration(1) * time.Minute
+	w := NewSlidingWindow(2, window)
+	now := time.Now()
+	w.AllowN(now, 1)
+	w.AllowN(now.Add(time.Second), 1)
+
+	if delay := w.DelayN(now, 1); delay != window {
+		t.Fail()
+	}
+
+	if delay := w.DelayN(now, 2); delay != window+time.Second {
+		t.Fail()
+	}
+
+	if delay := w.DelayN(now.Add(2*window), 2); delay != 0 {
+		t.Fail()
+	}
+}
+
+func TestSlidingWindow_AllowN_moreThanTheLimitWaitsForAnEmptyWindow(t *testing.T) {
+	window := time.Duration(1) * time.Minute
+	w := NewSlidingWindow(2, window)
+	now := time.Now()
+	w.AllowN(now, 1)
+
+	if w.AllowN(now, 3) {
+		t.Fail()
+	}
+
+	if !w.AllowN(now.Add(window), 3) {
+		t.Fail()
+	}
+
+	if w.Count(now.Add(window)) != 3 {
+		t.Fail()
+	}
+}
+
+func TestSlidingWindow_Reset_forgetsReleases(t *testing.T) {
+	w := NewSlidingWindow(1, time.Duration(1)*time.Minute)
+	now := time.Now()
+	w.AllowN(now, 1)
+
+	w.Reset()
+
+	if !w.AllowN(now, 1) {
+		t.Fail()
+	}
+}
+
+func TestSlidingWindow_canPaceALimiter(t *testing.T) {
+	rl := New(0, WithCapacity(5), WithPacer(NewSlidingWindow(3, time.Duration(1)*time.Hour)))
+	rl.PushAll(0, 1, 2, 3, 4)
+
+	if values := rl.PopAvailable(); len(values) != 3 {
+		t.Fail()
+	}
+}
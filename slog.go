@@ -0,0 +1,42 @@
+//go:build go1.21
+
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+//WithLogger makes a Limiter log debug records to logger when a value is
+//released after waiting longer than threshold, when a value is pushed while the
+//Limiter is at capacity, and when the Limiter is closed.
+//Each record has a "limiter" attribute of name so that the records of several
+//Limiters can be told apart.
+//
+//Records are logged while the Limiter is locked, like the calls to Hooks, so the
+//handler of logger should not block. Nothing is logged unless logger is enabled
+//for slog.LevelDebug.
+func WithLogger(logger *slog.Logger, name string, threshold time.Duration) Option {
+	log := func(msg string, attrs ...slog.Attr) {
+		ctx := context.Background()
+		if !logger.Enabled(ctx, slog.LevelDebug) {
+			return
+		}
+		logger.LogAttrs(ctx, slog.LevelDebug, msg, append(attrs, slog.String("limiter", name))...)
+	}
+
+	return WithHooks(Hooks{
+		OnPop: func(e Event) {
+			if e.Wait > threshold {
+				log("ratelimit: value throttled", slog.Duration("wait", e.Wait))
+			}
+		},
+		OnFull: func(e Event) {
+			log("ratelimit: queue full")
+		},
+		OnClose: func(e Event) {
+			log("ratelimit: closed")
+		},
+	})
+}
@@ -0,0 +1,47 @@
+//go:build go1.21
+
+package ratelimit
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithLogger_logsThrottlingFullQueuesAndClose(t *testing.T) {
+	d := time.Duration(10) * time.Millisecond
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	l := New(d, WithLogger(logger, "api", d/2))
+
+	l.Push(1)
+	l.Pop()
+	l.Push(2)
+	l.TryPush(3)
+	l.Pop()
+	l.Close()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatal(buf.String())
+	}
+	for i, msg := range []string{"queue full", "value throttled", "closed"} {
+		if !strings.Contains(lines[i], msg) || !strings.Contains(lines[i], "limiter=api") {
+			t.Error(lines[i])
+		}
+	}
+}
+
+func TestWithLogger_logsNothingAboveDebug(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(time.Duration(1), WithLogger(slog.New(slog.NewTextHandler(buf, nil)), "api", 0))
+	l.Push(1)
+	l.TryPush(2)
+	l.Close()
+
+	if buf.Len() != 0 {
+		t.Fatal(buf.String())
+	}
+}
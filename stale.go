@@ -0,0 +1,83 @@
+package ratelimit
+
+import "time"
+
+//StaleAlert describes a Limiter whose oldest queued value has waited longer
+//than the threshold given to WithStaleAlert.
+type StaleAlert struct {
+	//Oldest is when the oldest queued value was pushed, and Wait is how long it
+	//had waited when the alert fired.
+	Oldest time.Time
+	Wait   time.Duration
+
+	//Len is the number of values queued when the alert fired.
+	Len int
+}
+
+//WithStaleAlert makes a Limiter call fn when its oldest queued value has waited
+//longer than threshold, which is an early sign that values are pushed faster
+//than the rate of the Limiter releases them, before its queue overflows.
+//
+//fn is called in its own goroutine, at most once for each value that becomes
+//the oldest queued value, and even if nothing is pushed or popped after the
+//value is pushed.
+func WithStaleAlert(threshold time.Duration, fn func(alert StaleAlert)) Option {
+	return func(l *Limiter) {
+		l.stale = &staleAlerter{
+			threshold: threshold,
+			fn:        fn,
+		}
+	}
+}
+
+//staleAlerter is the state of WithStaleAlert.
+type staleAlerter struct {
+	threshold time.Duration
+	fn        func(alert StaleAlert)
+
+	//timer fires when the oldest queued value becomes stale, and alerted is
+	//when the last value alerted for was pushed.
+	timer   Timer
+	alerted time.Time
+}
+
+//scheduleStaleLocked alerts if the oldest value queued in l is stale, or
+//otherwise arranges to check again once it would be.
+//It is called whenever the oldest queued value may have changed.
+func (l *Limiter) scheduleStaleLocked(now time.Time) {
+	s := l.stale
+	if s == nil {
+		return
+	}
+
+	if l.values.len() == 0 || l.values.oldest().Equal(s.alerted) {
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+		return
+	}
+
+	oldest := l.values.oldest()
+	if due := oldest.Add(s.threshold).Sub(now); due > 0 {
+		if s.timer == nil {
+			s.timer = l.clock.AfterFunc(due, l.checkStale)
+		} else {
+			s.timer.Reset(due)
+		}
+		return
+	}
+
+	s.alerted = oldest
+	go s.fn(StaleAlert{
+		Oldest: oldest,
+		Wait:   now.Sub(oldest),
+		Len:    l.values.len(),
+	})
+}
+
+func (l *Limiter) checkStale() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.scheduleStaleLocked(l.clock.Now())
+}
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithStaleAlert_alertsWhenTheOldestValueWaitsTooLong(t *testing.T) {
+	threshold := time.Duration(20) * time.Millisecond
+	alerts := make(chan StaleAlert, 10)
+	l := New(time.Duration(1)*time.Hour, WithCapacity(Unbounded), WithStaleAlert(threshold, func(alert StaleAlert) {
+		alerts <- alert
+	}))
+	l.Wait()
+	l.PushAll(1, 2)
+
+	select {
+	case alert := <-alerts:
+		if alert.Wait < threshold || alert.Len != 2 || alert.Oldest.IsZero() {
+			t.Fatal(alert)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no alert")
+	}
+
+	select {
+	case alert := <-alerts:
+		t.Fatal("alerted twice", alert)
+	case <-time.After(2 * threshold):
+	}
+}
+
+func TestWithStaleAlert_doesNotAlertForValuesPoppedInTime(t *testing.T) {
+	threshold := time.Duration(30) * time.Millisecond
+	alerts := make(chan StaleAlert, 10)
+	l := New(time.Duration(1), WithCapacity(Unbounded), WithStaleAlert(threshold, func(alert StaleAlert) {
+		alerts <- alert
+	}))
+	l.PushAll(1, 2)
+	l.Pop()
+	l.Pop()
+
+	select {
+	case alert := <-alerts:
+		t.Fatal(alert)
+	case <-time.After(2 * threshold):
+	}
+}
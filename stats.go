@@ -0,0 +1,100 @@
+package ratelimit
+
+import "time"
+
+//Stats is a snapshot of the counters of a Limiter, returned by Stats.
+//The counters are totals since the Limiter was created and are not cleared by
+//Reset.
+type Stats struct {
+	//Pushed, Popped, and Dropped are the numbers of values pushed, released,
+	//and removed without being released by CloseDiscard, Drain, Reset,
+	//Item.Cancel, or the Overflow policy, or because they expired.
+	Pushed  uint64
+	Popped  uint64
+	Dropped uint64
+
+	//Len is the number of values currently queued.
+	Len int
+
+	//Released is the number of releases counted by the pacer, including those
+	//of Wait and Allow and the cost of values pushed with PushCost.
+	Released uint64
+
+	//Throttled is the total time that a pop or wait was held back because the
+	//rate did not yet permit its release. Time after the pop or wait gave up,
+	//or while l had no values to pop, is not counted.
+	Throttled time.Duration
+
+	//Throughput is the average number of releases per second since the Limiter
+	//was created.
+	Throughput float64
+
+	//Waits is the distribution of how long the popped values waited.
+	Waits WaitHistogram
+
+	//LostDeadLetters is the number of dead letters that were not delivered on
+	//the channel returned by DeadLetters because its buffer was full.
+	LostDeadLetters uint64
+}
+
+//stats are the counters of a Limiter.
+type stats struct {
+	created time.Time
+
+	pushed, popped, dropped, released uint64
+
+	lostDeadLetters uint64
+
+	throttled time.Duration
+	//throttledSince is when the current stretch of throttling began, or the zero
+	//time if releases are not being held back.
+	throttledSince time.Time
+
+	waits WaitHistogram
+
+	//lastPop is when the most recent value was popped, and pops holds the times
+	//of the most recent pops.
+	lastPop time.Time
+	pops    popTimes
+}
+
+func newStats(now time.Time) stats {
+	return stats{created: now}
+}
+
+//throttle records whether a release attempted at now was held back.
+func (s *stats) throttle(now time.Time, held bool) {
+	switch {
+	case held && s.throttledSince.IsZero():
+		s.throttledSince = now
+	case !held && !s.throttledSince.IsZero():
+		s.throttled += now.Sub(s.throttledSince)
+		s.throttledSince = time.Time{}
+	}
+}
+
+//Stats returns a snapshot of the counters of l.
+func (l *Limiter) Stats() Stats {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.clock.Now()
+	s := Stats{
+		Pushed:    l.stats.pushed,
+		Popped:    l.stats.popped,
+		Dropped:   l.stats.dropped,
+		Len:       l.values.len(),
+		Released:  l.stats.released,
+		Throttled: l.stats.throttled,
+		Waits:     l.stats.waits,
+
+		LostDeadLetters: l.stats.lostDeadLetters,
+	}
+	if !l.stats.throttledSince.IsZero() {
+		s.Throttled += now.Sub(l.stats.throttledSince)
+	}
+	if elapsed := now.Sub(l.stats.created); elapsed > 0 {
+		s.Throughput = float64(s.Released) / elapsed.Seconds()
+	}
+	return s
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_Stats_countsValues(t *testing.T) {
+	l := New(time.Duration(1), WithCapacity(Unbounded))
+	l.PushAll(1, 2, 3, 4)
+	l.Pop()
+	l.Pop()
+	l.Wait()
+	l.Drain()
+
+	s := l.Stats()
+	if s.Pushed != 4 || s.Popped != 2 || s.Dropped != 2 || s.Len != 0 || s.Released != 3 {
+		t.Fatal(s)
+	}
+	if s.Throughput <= 0 {
+		t.Fatal(s.Throughput)
+	}
+}
+
+func TestLimiter_Stats_countsDiscardedValuesAsDropped(t *testing.T) {
+	l := New(time.Duration(1), WithCapacity(Unbounded))
+	l.PushAll(1, 2)
+	l.Reset()
+	l.Push(3)
+
+	if s := l.Stats(); s.Pushed != 3 || s.Dropped != 2 || s.Len != 1 {
+		t.Fatal(s)
+	}
+}
+
+func TestLimiter_Stats_measuresThrottledTime(t *testing.T) {
+	d := time.Duration(20) * time.Millisecond
+	l := New(d)
+	l.Wait()
+	l.Wait()
+
+	if s := l.Stats(); s.Throttled < d/2 || s.Throttled > 10*d {
+		t.Fatal(s.Throttled)
+	}
+}
+
+func TestLimiter_Stats_stopsMeasuringThrottledTimeWhenAWaiterGivesUp(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	l := New(time.Duration(1)*time.Hour, WithClock(c))
+	l.Wait()
+	l.Push(1)
+
+	done := make(chan error)
+	go func() {
+		_, err := l.PopTimeout(time.Duration(1) * time.Minute)
+		done <- err
+	}()
+	for {
+		c.lock.Lock()
+		waiting := len(c.timers) > 0
+		c.lock.Unlock()
+		if waiting {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.advance(time.Duration(1) * time.Minute)
+	if err := <-done; err != ErrTimeout {
+		t.Fatal(err)
+	}
+	c.advance(time.Duration(10) * time.Minute)
+
+	if s := l.Stats(); s.Throttled != time.Duration(1)*time.Minute {
+		t.Fatal(s.Throttled)
+	}
+}
+
+func TestLimiter_Stats_doesNotMeasureThrottledTimeAfterTryPop(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	l := New(time.Duration(1)*time.Hour, WithClock(c))
+	l.Wait()
+	l.Push(1)
+
+	l.TryPop()
+	c.advance(time.Duration(1) * time.Minute)
+
+	if s := l.Stats(); s.Throttled != 0 {
+		t.Fatal(s.Throttled)
+	}
+}
@@ -0,0 +1,244 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//Store holds state shared by Pacers, possibly in different processes.
+//Implementations must be safe for concurrent use.
+type Store interface {
+	//Get returns the value stored at key and whether there is one.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	//CompareAndSwap stores value at key if the value currently stored there is
+	//old, or if there is none and old is nil, and reports whether it did.
+	//If ttl is positive, then the stored value is removed once ttl has elapsed.
+	CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error)
+}
+
+//memorySweepInterval is how often a MemoryStore removes all expired values.
+const memorySweepInterval = time.Minute
+
+//MemoryStore is a Store that keeps its values in memory.
+//It is useful for sharing state between Pacers in one process, and for tests.
+type MemoryStore struct {
+	lock   *sync.Mutex
+	values map[string]memoryValue
+	swept  time.Time
+}
+
+type memoryValue struct {
+	value   []byte
+	expires time.Time
+}
+
+func (v memoryValue) expired(now time.Time) bool {
+	return !v.expires.IsZero() && !now.Before(v.expires)
+}
+
+//NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		lock:   &sync.Mutex{},
+		values: map[string]memoryValue{},
+		swept:  time.Now(),
+	}
+}
+
+//Get implements Store.
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	v, ok := s.getLocked(key, time.Now())
+	if !ok {
+		return nil, false, nil
+	}
+	return append([]byte{}, v...), true, nil
+}
+
+//CompareAndSwap implements Store.
+func (s *MemoryStore) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	current, ok := s.getLocked(key, now)
+	if ok != (old != nil) || !bytes.Equal(current, old) {
+		return false, nil
+	}
+
+	v := memoryValue{value: append([]byte{}, value...)}
+	if ttl > 0 {
+		v.expires = now.Add(ttl)
+	}
+	s.values[key] = v
+	return true, nil
+}
+
+func (s *MemoryStore) getLocked(key string, now time.Time) ([]byte, bool) {
+	if now.Sub(s.swept) >= memorySweepInterval {
+		for k, v := range s.values {
+			if v.expired(now) {
+				delete(s.values, k)
+			}
+		}
+		s.swept = now
+	}
+
+	v, ok := s.values[key]
+	if !ok || v.expired(now) {
+		return nil, false
+	}
+	return v.value, true
+}
+
+//StoreGCRA is a Pacer that implements the generic cell rate algorithm with its
+//state kept in a Store.
+//It behaves like GCRA, except that all StoreGCRAs using the same Store and key
+//share their releases.
+//Its state is updated with CompareAndSwap, retrying when another StoreGCRA
+//updates it concurrently.
+//The calls to the Store made by AllowN and DelayN give up after a timeout, which
+//is DefaultRemoteTimeout unless changed with SetTimeout.
+type StoreGCRA struct {
+	store  Store
+	key    string
+	remote *Remote
+
+	lock      *sync.Mutex
+	interval  time.Duration
+	tolerance time.Duration
+}
+
+//NewStoreGCRA creates a StoreGCRA that keeps its state at key in store, with
+//emission interval interval and burst tolerance tolerance.
+func NewStoreGCRA(store Store, key string, interval, tolerance time.Duration) *StoreGCRA {
+	return &StoreGCRA{
+		store:     store,
+		key:       key,
+		remote:    NewRemote(),
+		lock:      &sync.Mutex{},
+		interval:  interval,
+		tolerance: tolerance,
+	}
+}
+
+//AllowN reports whether n releases may happen at time t, counting them if they
+//may.
+//If the Store returns an error, then the releases are refused and the error is
+//passed to the function set by OnError.
+func (g *StoreGCRA) AllowN(t time.Time, n int) bool {
+	ctx, cancel := g.remote.Context()
+	defer cancel()
+
+	ok, _, err := g.TakeN(ctx, t, n)
+	if err != nil {
+		g.remote.Handle(err)
+		return false
+	}
+	return ok
+}
+
+//DelayN returns how long after t it will be before n releases may happen.
+//If the Store returns an error, then the emission interval is returned and the
+//error is passed to the function set by OnError.
+func (g *StoreGCRA) DelayN(t time.Time, n int) time.Duration {
+	ctx, cancel := g.remote.Context()
+	defer cancel()
+
+	interval, tolerance := g.params()
+	_, tat, err := g.get(ctx)
+	if err != nil {
+		g.remote.Handle(err)
+		return interval
+	}
+	if delay := tat.Add(-tolerance).Sub(t); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+//TakeN is like AllowN, but uses ctx for the calls to the Store, returns their
+//error if there is one, and returns how long after t it will be before the
+//releases may happen if they may not happen at t.
+func (g *StoreGCRA) TakeN(ctx context.Context, t time.Time, n int) (bool, time.Duration, error) {
+	interval, tolerance := g.params()
+	for {
+		old, tat, err := g.get(ctx)
+		if err != nil {
+			return false, 0, err
+		}
+		if delay := tat.Add(-tolerance).Sub(t); delay > 0 {
+			return false, delay, nil
+		}
+
+		if tat.Before(t) {
+			tat = t
+		}
+		tat = tat.Add(time.Duration(n) * interval)
+		value := strconv.AppendInt(nil, tat.UnixNano(), 10)
+		swapped, err := g.store.CompareAndSwap(ctx, g.key, old, value, tat.Sub(t))
+		if err != nil || swapped {
+			return swapped, 0, err
+		}
+		if err := ctx.Err(); err != nil {
+			return false, 0, err
+		}
+	}
+}
+
+//Wait blocks until a release may happen, counting it, or until ctx is done, in
+//which case ctx.Err() is returned.
+//An error returned by the Store is returned immediately.
+func (g *StoreGCRA) Wait(ctx context.Context) error {
+	return WaitTake(ctx, g.TakeN)
+}
+
+//SetDuration changes the emission interval of g to d.
+//It only affects the decisions made by g, not those of other StoreGCRAs sharing
+//its key.
+func (g *StoreGCRA) SetDuration(d time.Duration) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.interval = d
+}
+
+//SetTimeout changes how long the calls to the Store made by AllowN and DelayN
+//may take to d, after which they are refused just like any other error of the
+//Store. A d of zero or less means they never time out.
+func (g *StoreGCRA) SetTimeout(d time.Duration) {
+	g.remote.SetTimeout(d)
+}
+
+//OnError sets f to be called with the errors returned by the Store that cannot
+//be returned to the caller, such as those of AllowN and DelayN.
+//f is called synchronously and must not call methods of g.
+func (g *StoreGCRA) OnError(f func(error)) {
+	g.remote.OnError(f)
+}
+
+func (g *StoreGCRA) params() (time.Duration, time.Duration) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.interval, g.tolerance
+}
+
+//get returns the stored value of g and the theoretical arrival time it holds.
+//A value that cannot be parsed is treated as the zero time, so that it is
+//overwritten by the next release.
+func (g *StoreGCRA) get(ctx context.Context) ([]byte, time.Time, error) {
+	value, ok, err := g.store.Get(ctx, g.key)
+	if err != nil || !ok {
+		return nil, time.Time{}, err
+	}
+	ns, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return value, time.Time{}, nil
+	}
+	return value, time.Unix(0, ns), nil
+}
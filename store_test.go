@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_CompareAndSwap_onlySwapsTheCurrentValue(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	if ok, _ := s.CompareAndSwap(ctx, "key", nil, []byte("a"), 0); !ok {
+		t.Fatal("absent value should be swapped")
+	}
+	if ok, _ := s.CompareAndSwap(ctx, "key", nil, []byte("b"), 0); ok {
+		t.Fatal("present value should not be swapped as absent")
+	}
+	if ok, _ := s.CompareAndSwap(ctx, "key", []byte("b"), []byte("c"), 0); ok {
+		t.Fatal("different value should not be swapped")
+	}
+	if ok, _ := s.CompareAndSwap(ctx, "key", []byte("a"), []byte("c"), 0); !ok {
+		t.Fatal("current value should be swapped")
+	}
+
+	if value, ok, _ := s.Get(ctx, "key"); !ok || string(value) != "c" {
+		t.Fatal(string(value), ok)
+	}
+}
+
+func TestMemoryStore_CompareAndSwap_expiresValuesAfterTheirTTL(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	s.CompareAndSwap(ctx, "key", nil, []byte("a"), time.Duration(10)*time.Millisecond)
+
+	time.Sleep(time.Duration(20) * time.Millisecond)
+
+	if _, ok, _ := s.Get(ctx, "key"); ok {
+		t.Fail()
+	}
+	if ok, _ := s.CompareAndSwap(ctx, "key", nil, []byte("b"), 0); !ok {
+		t.Fail()
+	}
+}
+
+func TestStoreGCRA_AllowN_sharesTheBudgetThroughTheStore(t *testing.T) {
+	s := NewMemoryStore()
+	a := NewStoreGCRA(s, "key", time.Duration(1)*time.Hour, time.Duration(1)*time.Hour)
+	b := NewStoreGCRA(s, "key", time.Duration(1)*time.Hour, time.Duration(1)*time.Hour)
+	now := time.Now()
+
+	if !a.AllowN(now, 1) || !b.AllowN(now, 1) {
+		t.Fatal("burst should be allowed")
+	}
+	if a.AllowN(now, 1) || b.AllowN(now, 1) {
+		t.Fatal("burst should be shared")
+	}
+	if delay := a.DelayN(now, 1); delay != time.Duration(1)*time.Hour {
+		t.Fatal(delay)
+	}
+	if !b.AllowN(now.Add(time.Duration(1)*time.Hour), 1) {
+		t.Fail()
+	}
+}
+
+type failingStore struct {
+	err error
+}
+
+func (s failingStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, s.err
+}
+
+func (s failingStore) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	return false, s.err
+}
+
+//hangingStore is a Store whose calls never return until their context is done.
+type hangingStore struct{}
+
+func (hangingStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	<-ctx.Done()
+	return nil, false, ctx.Err()
+}
+
+func (hangingStore) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	<-ctx.Done()
+	return false, ctx.Err()
+}
+
+func TestStoreGCRA_AllowN_givesUpAfterTheTimeout(t *testing.T) {
+	g := NewStoreGCRA(hangingStore{}, "key", time.Duration(1)*time.Hour, 0)
+	g.SetTimeout(time.Duration(10) * time.Millisecond)
+	reported := []error{}
+	g.OnError(func(err error) {
+		reported = append(reported, err)
+	})
+
+	start := time.Now()
+	if g.AllowN(time.Now(), 1) {
+		t.Fail()
+	}
+	if delay := g.DelayN(time.Now(), 1); delay != time.Duration(1)*time.Hour {
+		t.Fatal(delay)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatal(elapsed)
+	}
+	if len(reported) != 2 || reported[0] != context.DeadlineExceeded || reported[1] != context.DeadlineExceeded {
+		t.Fatal(reported)
+	}
+}
+
+func TestStoreGCRA_AllowN_refusesAndReportsStoreErrors(t *testing.T) {
+	s := failingStore{errors.New("down")}
+	g := NewStoreGCRA(s, "key", time.Duration(1)*time.Hour, 0)
+	var reported error
+	g.OnError(func(err error) {
+		reported = err
+	})
+
+	if g.AllowN(time.Now(), 1) || reported != s.err {
+		t.Fail()
+	}
+	if _, _, err := g.TakeN(context.Background(), time.Now(), 1); err != s.err {
+		t.Fail()
+	}
+}
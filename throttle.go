@@ -0,0 +1,34 @@
+package ratelimit
+
+import "time"
+
+//Throttle returns a channel that receives the values received from in, in order,
+//no faster than one every d.
+//The returned channel is closed after in is closed and all of its values have
+//been delivered.
+//
+//It is shorthand for New(d).Throttle(in).
+func Throttle(in <-chan interface{}, d time.Duration) <-chan interface{} {
+	return New(d).Throttle(in)
+}
+
+//Throttle returns a channel that receives the values received from in, in order,
+//as fast as l permits, so that l can be used as a stage of a channel pipeline.
+//Each value delivered counts as a release from l.
+//The returned channel is closed after in is closed and all of its values have
+//been delivered.
+//
+//The values are delivered by a goroutine that blocks until each value is
+//received, so the returned channel should be drained until it is closed.
+func (l *Limiter) Throttle(in <-chan interface{}) <-chan interface{} {
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+
+		for v := range in {
+			l.Wait()
+			out <- v
+		}
+	}()
+	return out
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottle_deliversAllValuesInOrderAndCloses(t *testing.T) {
+	in := make(chan interface{}, 3)
+	for i := 0; i < 3; i++ {
+		in <- i
+	}
+	close(in)
+
+	i := 0
+	for v := range Throttle(in, time.Duration(1)) {
+		if v != i {
+			t.Fail()
+		}
+		i++
+	}
+	if i != 3 {
+		t.Fail()
+	}
+}
+
+func TestLimiter_Throttle_drawsFromTheBudgetOfTheLimiter(t *testing.T) {
+	rl := New(time.Duration(1) * time.Hour)
+	in := make(chan interface{}, 2)
+	in <- 0
+	in <- 1
+	out := rl.Throttle(in)
+
+	if <-out != 0 {
+		t.Fail()
+	}
+	select {
+	case <-out:
+		t.Fail()
+	case <-time.After(time.Duration(10) * time.Millisecond):
+	}
+	if rl.Allow() {
+		t.Fail()
+	}
+}
@@ -0,0 +1,65 @@
+package ratelimit
+
+import "time"
+
+//throughputSamples is the number of most recent pops a Limiter remembers the
+//time of to measure its throughput.
+const throughputSamples = 128
+
+//popTimes is a ring buffer of the times of the most recent pops of a Limiter,
+//as offsets from when the Limiter was created so that they use the monotonic
+//clock.
+type popTimes struct {
+	offsets []time.Duration
+	next    int
+	n       int
+}
+
+func (p *popTimes) record(offset time.Duration) {
+	if p.offsets == nil {
+		p.offsets = make([]time.Duration, throughputSamples)
+	}
+	p.offsets[p.next] = offset
+	p.next = (p.next + 1) % len(p.offsets)
+	if p.n < len(p.offsets) {
+		p.n++
+	}
+}
+
+//rate returns the number of pops per second within window before now.
+func (p *popTimes) rate(now, window time.Duration) float64 {
+	if window <= 0 {
+		return 0
+	}
+
+	count := 0
+	oldest := now
+	for i := 0; i < p.n; i++ {
+		offset := p.offsets[(p.next-1-i+len(p.offsets))%len(p.offsets)]
+		if now-offset > window {
+			break
+		}
+		count++
+		oldest = offset
+	}
+
+	if count == len(p.offsets) && now > oldest {
+		//The window may hold more pops than are remembered, so estimate the
+		//rate from the span of the ones that are.
+		return float64(count) / (now - oldest).Seconds()
+	}
+	return float64(count) / window.Seconds()
+}
+
+//Throughput returns the number of values popped from l per second within the
+//most recent window, so that the achieved rate can be compared to the
+//configured one.
+//
+//Only the most recent pops are remembered, so when window holds more than a
+//hundred or so, the rate is estimated from the span of the ones remembered.
+func (l *Limiter) Throughput(window time.Duration) float64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.stats.pops.rate(l.clock.Now().Sub(l.stats.created), window)
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPopTimes_rate_countsPopsWithinTheWindow(t *testing.T) {
+	p := &popTimes{}
+	for _, offset := range []time.Duration{1, 5, 8, 9} {
+		p.record(offset * time.Second)
+	}
+
+	if rate := p.rate(time.Duration(10)*time.Second, time.Duration(4)*time.Second); rate != 0.5 {
+		t.Error(rate)
+	}
+	if rate := p.rate(time.Duration(10)*time.Second, 0); rate != 0 {
+		t.Error(rate)
+	}
+}
+
+func TestPopTimes_rate_estimatesFromTheRememberedPops(t *testing.T) {
+	p := &popTimes{}
+	for i := 0; i < 2*throughputSamples; i++ {
+		p.record(time.Duration(i) * time.Millisecond)
+	}
+
+	now := time.Duration(2*throughputSamples) * time.Millisecond
+	if rate := p.rate(now, time.Duration(1)*time.Hour); rate != 1000 {
+		t.Error(rate)
+	}
+}
+
+func TestLimiter_Throughput_measuresRecentPops(t *testing.T) {
+	l := New(time.Duration(1), WithCapacity(Unbounded))
+	l.PushAll(1, 2, 3)
+	l.PopN(3)
+
+	if rate := l.Throughput(time.Second); rate != 3 {
+		t.Fatal(rate)
+	}
+}
@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+//TokenBucket is a Pacer that permits releases while there are tokens in its
+//bucket.
+//The bucket holds at most burst tokens and is refilled with one token every d.
+//The bucket starts full, so up to burst releases may happen back-to-back after
+//the bucket has had time to fill.
+//
+//Releasing n values requires min(n, burst) tokens to be available and takes n
+//tokens from the bucket, so releasing more values than burst at once leaves the
+//bucket in debt until the extra tokens have been refilled.
+//
+//A TokenBucket with a burst of one permits one release every d, which is the
+//default pacing of a Limiter.
+//
+//A TokenBucket created with NewOverdraftBucket may also overdraw its bucket by up
+//to an overdraft limit of tokens, permitting a spike of releases beyond its
+//burst. Once the overdraft limit is reached, further releases are delayed until
+//the debt has been paid back and the bucket holds tokens again.
+type TokenBucket struct {
+	lock *sync.Mutex
+
+	d         time.Duration
+	burst     int
+	overdraft int
+
+	//repaying is whether the overdraft limit has been reached and the debt has
+	//not yet been paid back.
+	repaying bool
+
+	//empty is the time at which the bucket was, or will have refilled from debt
+	//to be, empty. The bucket holds (t - empty) / d tokens at time t, up to burst.
+	empty time.Time
+}
+
+//NewTokenBucket creates a full TokenBucket that holds burst tokens and refills
+//one token every d.
+func NewTokenBucket(d time.Duration, burst int) *TokenBucket {
+	return &TokenBucket{
+		lock:  &sync.Mutex{},
+		d:     d,
+		burst: burst,
+	}
+}
+
+//NewOverdraftBucket creates a full TokenBucket that holds burst tokens, refills
+//one token every d, and may be overdrawn by up to overdraft tokens.
+func NewOverdraftBucket(d time.Duration, burst, overdraft int) *TokenBucket {
+	b := NewTokenBucket(d, burst)
+	b.overdraft = overdraft
+	return b
+}
+
+//AllowN reports whether n releases may happen at time t, taking n tokens from b
+//if they may.
+func (b *TokenBucket) AllowN(t time.Time, n int) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.delayLocked(t, n) > 0 {
+		return false
+	}
+
+	b.empty = b.emptyLocked(t).Add(time.Duration(n) * b.d)
+	b.repaying = b.overdrawnLocked(t)
+	return true
+}
+
+//DelayN returns how long after t it will be before b holds enough tokens for n
+//releases.
+func (b *TokenBucket) DelayN(t time.Time, n int) time.Duration {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if delay := b.delayLocked(t, n); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+//Tokens returns the number of tokens in b at time t.
+//It is negative if b is in debt.
+func (b *TokenBucket) Tokens(t time.Time) float64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.tokensLocked(t)
+}
+
+//SetDuration changes the time it takes for b to refill one token to d while
+//keeping the number of tokens currently in b.
+func (b *TokenBucket) SetDuration(d time.Duration) {
+	b.setDurationAt(time.Now(), d)
+}
+
+//setDurationAt works just like SetDuration, but keeps the number of tokens in b
+//at time now, so that b can be used with times from a Clock other than
+//SystemClock.
+func (b *TokenBucket) setDurationAt(now time.Time, d time.Duration) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	tokens := b.tokensLocked(now)
+	b.d = d
+	b.empty = now.Add(-time.Duration(tokens * float64(d)))
+}
+
+//Reset refills b.
+func (b *TokenBucket) Reset() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.empty = time.Time{}
+	b.repaying = false
+}
+
+//delayLocked returns how long after t it will be before b holds enough tokens
+//for n releases. It is not positive if b already does.
+func (b *TokenBucket) delayLocked(t time.Time, n int) time.Duration {
+	need := n
+	if !b.repaying {
+		need -= b.overdraft
+	}
+	if need > b.burst {
+		need = b.burst
+	}
+	return b.emptyLocked(t).Add(time.Duration(need) * b.d).Sub(t)
+}
+
+//overdrawnLocked returns whether b is in debt at t by at least its overdraft, so
+//that it may not release another value until the debt is paid back.
+func (b *TokenBucket) overdrawnLocked(t time.Time) bool {
+	return b.overdraft > 0 && b.emptyLocked(t).Sub(t) > time.Duration(b.overdraft-1)*b.d
+}
+
+//emptyLocked returns the time at which b was empty, taking into account that b
+//holds no more than burst tokens at t.
+func (b *TokenBucket) emptyLocked(t time.Time) time.Time {
+	full := t.Add(-time.Duration(b.burst) * b.d)
+	if b.empty.Before(full) {
+		return full
+	}
+	return b.empty
+}
+
+func (b *TokenBucket) tokensLocked(t time.Time) float64 {
+	if b.d <= 0 {
+		return float64(b.burst)
+	}
+	return float64(t.Sub(b.emptyLocked(t))) / float64(b.d)
+}
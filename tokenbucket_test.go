@@ -0,0 +1,174 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket_AllowN_allowsBurstThenOnePerDuration(t *testing.T) {
+	d := time.Duration(1) * time.Second
+	b := NewTokenBucket(d, 3)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if !b.AllowN(now, 1) {
+			t.Fail()
+		}
+	}
+
+	if b.AllowN(now, 1) {
+		t.Fail()
+	}
+
+	if b.AllowN(now.Add(d-1), 1) {
+		t.Fail()
+	}
+
+	if !b.AllowN(now.Add(d), 1) {
+		t.Fail()
+	}
+}
+
+func TestTokenBucket_AllowN_doesNotHoldMoreThanBurst(t *testing.T) {
+	d := time.Duration(1) * time.Second
+	b := NewTokenBucket(d, 2)
+	now := time.Now()
+	b.AllowN(now, 2)
+
+	later := now.Add(10 * d)
+	if !b.AllowN(later, 2) {
+		t.Fail()
+	}
+
+	if b.AllowN(later, 1) {
+		t.Fail()
+	}
+}
+
+func TestTokenBucket_AllowN_moreThanBurstGoesIntoDebt(t *testing.T) {
+	d := time.Duration(1) * time.Second
+	b := NewTokenBucket(d, 2)
+	now := time.Now()
+
+	if !b.AllowN(now, 5) {
+		t.Fail()
+	}
+
+	if tokens := b.Tokens(now); tokens != -3 {
+		t.Fatal(tokens)
+	}
+
+	if b.AllowN(now.Add(3*d), 1) {
+		t.Fail()
+	}
+
+	if !b.AllowN(now.Add(4*d), 1) {
+		t.Fail()
+	}
+}
+
+func TestTokenBucket_DelayN_returnsTimeUntilTokensAreAvailable(t *testing.T) {
+	d := time.Duration(1) * time.Second
+	b := NewTokenBucket(d, 2)
+	now := time.Now()
+
+	if delay := b.DelayN(now, 2); delay != 0 {
+		t.Fail()
+	}
+
+	b.AllowN(now, 2)
+
+	if delay := b.DelayN(now, 1); delay != d {
+		t.Fail()
+	}
+
+	if delay := b.DelayN(now, 2); delay != 2*d {
+		t.Fail()
+	}
+
+	if delay := b.DelayN(now, 3); delay != 2*d {
+		t.Fail()
+	}
+}
+
+func TestTokenBucket_SetDuration_keepsTheTokens(t *testing.T) {
+	b := NewTokenBucket(time.Duration(1)*time.Hour, 2)
+	b.AllowN(time.Now(), 1)
+
+	b.SetDuration(time.Duration(1) * time.Second)
+
+	if tokens := b.Tokens(time.Now()); tokens < 1 || tokens >= 1.5 {
+		t.Fatal(tokens)
+	}
+}
+
+func TestTokenBucket_SetDuration_usesTheClockOfTheLimiter(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	rl := New(time.Duration(1)*time.Hour, WithBurst(2), WithClock(c))
+	rl.Allow()
+
+	rl.SetDuration(time.Duration(1) * time.Second)
+
+	if delay := rl.Delay(); delay != 0 || !rl.Allow() {
+		t.Fatal(delay)
+	}
+	if delay := rl.Delay(); delay <= 0 || delay > time.Second {
+		t.Fatal(delay)
+	}
+}
+
+func TestTokenBucket_Reset_refillsTheBucket(t *testing.T) {
+	b := NewTokenBucket(time.Duration(1)*time.Hour, 2)
+	now := time.Now()
+	b.AllowN(now, 2)
+
+	b.Reset()
+
+	if tokens := b.Tokens(now); tokens != 2 {
+		t.Fail()
+	}
+}
+
+func TestTokenBucket_zeroDurationAlwaysAllows(t *testing.T) {
+	b := NewTokenBucket(0, 1)
+	now := time.Now()
+
+	for i := 0; i < 10; i++ {
+		if !b.AllowN(now, 1) {
+			t.Fail()
+		}
+	}
+}
+
+func TestTokenBucket_overdraftIsPaidBackBeforeFurtherReleases(t *testing.T) {
+	b := NewOverdraftBucket(time.Duration(1)*time.Second, 2, 3)
+	now := time.Now()
+
+	for i := 0; i < 5; i++ {
+		if !b.AllowN(now, 1) {
+			t.Fatal(i)
+		}
+	}
+	if b.AllowN(now, 1) || b.Tokens(now) != -3 {
+		t.Fail()
+	}
+
+	if b.AllowN(now.Add(3*time.Second), 1) || b.DelayN(now, 1) != 4*time.Second {
+		t.Fail()
+	}
+	if !b.AllowN(now.Add(4*time.Second), 1) || !b.AllowN(now.Add(4*time.Second), 1) {
+		t.Fail()
+	}
+}
+
+func TestTokenBucket_overdraftPermitsLargeReleasesWhileNotInDebt(t *testing.T) {
+	b := NewOverdraftBucket(time.Duration(1)*time.Second, 1, 4)
+	now := time.Now()
+
+	if !b.AllowN(now, 5) || b.AllowN(now, 1) {
+		t.Fail()
+	}
+	if b.DelayN(now, 1) != 5*time.Second {
+		t.Fail()
+	}
+}
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+//WithTTL sets the time to live of the values pushed to a Limiter without one of
+//their own from PushTTL.
+//A value that has been queued for longer than its time to live is discarded
+//instead of being released stale. See PushTTL.
+func WithTTL(ttl time.Duration) Option {
+	return func(l *Limiter) {
+		l.ttl = ttl
+	}
+}
+
+//PushTTL places value in l to be popped later, unless it has been queued for
+//longer than ttl by the time it would be released, in which case it is discarded
+//instead.
+//A ttl of zero or less means value never expires, even if WithTTL was used.
+//
+//Expired values are discarded once they reach the front of l, or when a push
+//finds l full, so they are counted by Len until then. They are reported to
+//Observers and Hooks as dropped, counted in Stats.Dropped, and passed to the
+//function set with WithDeadLetter with a Reason of Expired.
+//
+//PushTTL otherwise works just like Push.
+func (l *Limiter) PushTTL(value interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = -1
+	}
+	return l.opError("push", l.push(context.Background(), time.Time{}, item{value: value, ttl: ttl}))
+}
+
+//expired reports whether it has outlived its time to live at now.
+func (it item) expired(now time.Time) bool {
+	return it.ttl > 0 && now.Sub(it.pushed) >= it.ttl
+}
+
+//expireLocked discards the values of l that have expired at now, wherever they
+//are queued.
+func (l *Limiter) expireLocked(now time.Time) {
+	if !l.expiring {
+		return
+	}
+	expired := l.values.removeIf(func(it item) bool { return it.expired(now) })
+	l.dropLocked(Expired, expired...)
+	if len(expired) > 0 {
+		l.broadcastLocked()
+	}
+}
+
+//expireNextLocked discards the values of l that have expired at now as long as
+//they are next to be released.
+func (l *Limiter) expireNextLocked(now time.Time) {
+	if !l.expiring {
+		return
+	}
+	expired := false
+	for l.values.len() > 0 && l.values.peek().expired(now) {
+		l.dropLocked(Expired, l.values.pop())
+		expired = true
+	}
+	if expired {
+		l.broadcastLocked()
+	}
+}
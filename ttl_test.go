@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLimiter_PushTTL_discardsValuesThatExpireBeforeTheirRelease(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	var dropped []DroppedValue
+	l := New(time.Duration(1)*time.Second, WithClock(c), WithCapacity(Unbounded), WithDeadLetter(func(d DroppedValue) {
+		dropped = append(dropped, d)
+	}))
+	l.PushTTL(1, time.Duration(1)*time.Second)
+	l.PushTTL(2, time.Duration(1)*time.Second)
+	l.PushTTL(3, time.Duration(10)*time.Second)
+
+	c.advance(time.Duration(1) * time.Second)
+
+	if v, ok := l.TryPop(); !ok || v != 3 {
+		t.Fatal(v, ok)
+	}
+	if len(dropped) != 2 {
+		t.Fatal(dropped)
+	}
+	for i, d := range dropped {
+		if d.Value != i+1 || d.Reason != Expired || d.Time.Sub(d.Pushed) != time.Duration(1)*time.Second {
+			t.Fatal(i, d)
+		}
+	}
+	if stats := l.Stats(); stats.Popped != 1 || stats.Dropped != 2 {
+		t.Fatal(stats)
+	}
+}
+
+func TestLimiter_PushTTL_expiredValuesDoNotUseTheRate(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	l := New(time.Duration(1)*time.Second, WithClock(c), WithCapacity(Unbounded))
+	l.PushTTL(1, time.Duration(1))
+	l.Push(2)
+
+	c.advance(time.Duration(1))
+
+	if got := l.PopAvailable(); !reflect.DeepEqual(got, []interface{}{2}) {
+		t.Fatal(got)
+	}
+}
+
+func TestLimiter_PushTTL_makesSpaceForPushesByDiscardingExpiredValues(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	l := New(time.Duration(1)*time.Hour, WithClock(c), WithCapacity(3))
+	l.Push(1)
+	l.PushTTL(2, time.Duration(1)*time.Second)
+	l.PushTTL(3, time.Duration(1)*time.Minute)
+
+	c.advance(time.Duration(1) * time.Second)
+
+	if accepted, err := l.TryPush(4); !accepted || err != nil {
+		t.Fatal(accepted, err)
+	}
+	if got := l.Drain(); !reflect.DeepEqual(got, []interface{}{1, 3, 4}) {
+		t.Fatal(got)
+	}
+}
+
+func TestWithTTL_setsTheDefaultTimeToLive(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	l := New(0, WithClock(c), WithCapacity(Unbounded), WithTTL(time.Duration(1)*time.Second))
+	l.Push(1)
+	l.PushTTL(2, 0)
+	l.PushTTL(3, time.Duration(1)*time.Minute)
+
+	c.advance(time.Duration(1) * time.Second)
+
+	if got := l.PopAvailable(); !reflect.DeepEqual(got, []interface{}{2, 3}) {
+		t.Fatal(got)
+	}
+}
+
+func TestLimiter_PushTTL_wakesBlockedPushesWhenAPopDiscardsExpiredValues(t *testing.T) {
+	c := &manualClock{now: time.Unix(0, 0)}
+	l := New(time.Duration(1)*time.Hour, WithClock(c))
+	l.Wait()
+	l.PushTTL(1, time.Duration(1)*time.Second)
+
+	pushed := make(chan error)
+	go func() {
+		pushed <- l.Push(2)
+	}()
+	c.advance(time.Duration(1) * time.Second)
+	l.TryPop()
+
+	if err := <-pushed; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,36 @@
+package ratelimit
+
+//waiters is the line of goroutines blocked popping from a Limiter.
+//Only the goroutine at the front of the line may release a value, so that
+//concurrent callers of Pop receive values in the order they started waiting.
+type waiters struct {
+	next    uint64
+	tickets []uint64
+}
+
+//join adds a goroutine to the back of w and returns its ticket.
+func (w *waiters) join() uint64 {
+	ticket := w.next
+	w.next++
+	w.tickets = append(w.tickets, ticket)
+	return ticket
+}
+
+//first returns whether ticket is at the front of w.
+func (w *waiters) first(ticket uint64) bool {
+	return len(w.tickets) > 0 && w.tickets[0] == ticket
+}
+
+//leave removes ticket from w wherever it is in line.
+func (w *waiters) leave(ticket uint64) {
+	for i, t := range w.tickets {
+		if t == ticket {
+			w.tickets = append(w.tickets[:i], w.tickets[i+1:]...)
+			return
+		}
+	}
+}
+
+func (w *waiters) len() int {
+	return len(w.tickets)
+}
@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+//WarmUp is a Pacer that starts at a fraction of its target rate and ramps up
+//linearly to the full target rate over a warm-up period.
+//This gives a downstream service time to warm its caches before it receives
+//full traffic.
+//
+//The warm-up period begins with the first release permitted by a WarmUp.
+//Releases are permitted one at a time, and each release of n delays the next
+//by n times the time between releases at the current rate.
+type WarmUp struct {
+	lock *sync.Mutex
+
+	d        time.Duration
+	fraction float64
+	period   time.Duration
+
+	start time.Time
+	next  time.Time
+}
+
+//NewWarmUp creates a WarmUp that eventually permits one release every d.
+//It starts by permitting releases at fraction of that rate and reaches the full
+//rate after period has elapsed.
+//NewWarmUp panics if fraction is not greater than 0 and at most 1.
+func NewWarmUp(d time.Duration, fraction float64, period time.Duration) *WarmUp {
+	if !(fraction > 0 && fraction <= 1) {
+		panic("ratelimit: warm-up fraction " + strconv.FormatFloat(fraction, 'g', -1, 64) + " must be greater than 0 and at most 1")
+	}
+	return &WarmUp{
+		lock:     &sync.Mutex{},
+		d:        d,
+		fraction: fraction,
+		period:   period,
+	}
+}
+
+//AllowN reports whether n releases may happen at time t at the rate of w at t,
+//counting them if they may.
+func (w *WarmUp) AllowN(t time.Time, n int) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if t.Before(w.next) {
+		return false
+	}
+
+	if w.start.IsZero() {
+		w.start = t
+	}
+	w.next = t.Add(time.Duration(n) * w.durationLocked(t))
+	return true
+}
+
+//DelayN returns how long after t it will be before n releases may happen at the
+//rate of w at t.
+func (w *WarmUp) DelayN(t time.Time, n int) time.Duration {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if delay := w.next.Sub(t); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+//Rate returns the rate of w at time t in releases per second.
+func (w *WarmUp) Rate(t time.Time) float64 {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return perSecond(w.durationLocked(t))
+}
+
+//SetDuration changes the target time between releases of w without restarting
+//its warm-up period.
+func (w *WarmUp) SetDuration(d time.Duration) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.d = d
+}
+
+//Reset forgets all releases counted by w and restarts its warm-up period with
+//the next release.
+func (w *WarmUp) Reset() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.start = time.Time{}
+	w.next = time.Time{}
+}
+
+//durationLocked returns the time between releases permitted by w at time t.
+func (w *WarmUp) durationLocked(t time.Time) time.Duration {
+	progress := 0.0
+	if !w.start.IsZero() {
+		progress = 1
+		if w.period > 0 {
+			progress = float64(t.Sub(w.start)) / float64(w.period)
+		}
+	}
+	if progress > 1 {
+		progress = 1
+	}
+	if progress < 0 {
+		progress = 0
+	}
+
+	scale := w.fraction + (1-w.fraction)*progress
+	return time.Duration(float64(w.d) / scale)
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWarmUp_Rate_rampsUpLinearly(t *testing.T) {
+	w := NewWarmUp(time.Second/8, 0.5, 10*time.Second)
+	now := time.Now()
+
+	if !approximately(w.Rate(now), 4) {
+		t.Fatal(w.Rate(now))
+	}
+
+	w.AllowN(now, 1)
+
+	if !approximately(w.Rate(now.Add(5*time.Second)), 6) {
+		t.Fail()
+	}
+	if !approximately(w.Rate(now.Add(10*time.Second)), 8) || !approximately(w.Rate(now.Add(time.Hour)), 8) {
+		t.Fail()
+	}
+}
+
+func TestWarmUp_AllowN_pacesAtTheWarmUpRate(t *testing.T) {
+	w := NewWarmUp(time.Second, 0.25, time.Hour)
+	now := time.Now()
+
+	if !w.AllowN(now, 1) {
+		t.Fail()
+	}
+	if w.AllowN(now.Add(time.Second), 1) {
+		t.Fail()
+	}
+	if w.DelayN(now, 1) != 4*time.Second || !w.AllowN(now.Add(4*time.Second), 1) {
+		t.Fail()
+	}
+}
+
+func TestWarmUp_Reset_restartsTheWarmUpPeriod(t *testing.T) {
+	w := NewWarmUp(time.Second, 0.5, time.Second)
+	now := time.Now()
+	w.AllowN(now, 1)
+
+	w.Reset()
+
+	if !approximately(w.Rate(now.Add(time.Hour)), 0.5) {
+		t.Fail()
+	}
+}
+
+func TestWarmUp_DelayN_isNeverNegative(t *testing.T) {
+	w := NewWarmUp(time.Second, 1, 0)
+	now := time.Now()
+	w.AllowN(now, 1)
+
+	if delay := w.DelayN(now.Add(time.Hour), 1); delay != 0 {
+		t.Fatal(delay)
+	}
+}
+
+func TestNewWarmUp_panicsForAnInvalidFraction(t *testing.T) {
+	for _, fraction := range []float64{0, -0.5, 1.5, math.NaN()} {
+		expectPanic(t, func() { NewWarmUp(time.Second, fraction, time.Second) })
+	}
+}
+
+func approximately(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}